@@ -0,0 +1,40 @@
+// Package configtest เปิดฐานข้อมูล sqlite ในหน่วยความจำให้ test ของ controller ที่อ่านผ่าน config.DB()
+package configtest
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/Tawunchai/work-project/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open สร้างฐานข้อมูลใหม่ของ test นี้ (migrate ทุก entity แล้ว) และตั้งเป็น config.DB() จนกว่า test จะจบ
+// ใช้ connection เดียวเหมือน work.db จริง
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_busy_timeout=10000", url.PathEscape(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := config.Migrate(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	prev := config.DB()
+	config.UseDB(db)
+	t.Cleanup(func() {
+		config.UseDB(prev)
+		sqlDB.Close()
+	})
+	return db
+}
@@ -66,9 +66,12 @@ func ConnectionDB() {
 	db.Exec("PRAGMA busy_timeout = 10000;") // 10s
 }
 
-func SetupDatabase() {
-	// ✅ AutoMigrate ทุกครั้ง (อัปสเคม่า) — แต่ยังไม่ seed ถ้าไม่ใช่ DB ใหม่
-	if err := db.AutoMigrate(
+// UseDB ใช้ฐานข้อมูลที่เปิดไว้แล้วแทน work.db (เช่น sqlite ในหน่วยความจำของ test)
+func UseDB(database *gorm.DB) { db = database }
+
+// Migrate สร้าง / อัปเดตตารางของทุก entity
+func Migrate(database *gorm.DB) error {
+	return database.AutoMigrate(
 		&entity.Brand{},
 		&entity.Modal{},
 		&entity.SendEmail{},
@@ -96,7 +99,33 @@ func SetupDatabase() {
 		&entity.Bank{},
 		&entity.Service{},
 		&entity.ChargingSession{},
-	); err != nil {
+		&entity.ChargingTransaction{},
+		&entity.ChargePoint{},
+		&entity.Connector{},
+		&entity.ConnectorStatusHistory{},
+		&entity.MeterValue{},
+		&entity.Tariff{},
+		&entity.TariffBand{},
+		&entity.WalletTransaction{},
+		&entity.WalletEntry{},
+		&entity.IdempotencyKey{},
+		&entity.SlipReference{},
+		&entity.Refund{},
+		&entity.AuditLog{},
+		&entity.Invoice{},
+		&entity.DocumentSequence{},
+		&entity.Coupon{},
+		&entity.CouponRedemption{},
+		&entity.ChargingSessionEvent{},
+		&entity.JobRun{},
+		&entity.SigningKey{},
+		&entity.RevokedToken{},
+	)
+}
+
+func SetupDatabase() {
+	// ✅ AutoMigrate ทุกครั้ง (อัปสเคม่า) — แต่ยังไม่ seed ถ้าไม่ใช่ DB ใหม่
+	if err := Migrate(db); err != nil {
 		log.Fatalf("automigrate failed: %v", err)
 	}
 
@@ -393,11 +422,14 @@ func seedContent(db *gorm.DB) {
 	db.FirstOrCreate(review3, &entity.Review{UserID: &uid3})
 
 	service := &entity.Service{
-		Email:      "support@evstation.example",
-		Phone:      "+66 2 123 4567",
-		Location:   "ชั้น 12 อาคาร EV Station Tower, ถนนสุขุมวิท, กรุงเทพฯ 10110",
-		MapURL:     "https://maps.google.com/?q=EV+Station+Tower",
-		EmployeeID: &emp.ID,
+		Email:       "support@evstation.example",
+		Phone:       "+66 2 123 4567",
+		Location:    "ชั้น 12 อาคาร EV Station Tower, ถนนสุขุมวิท, กรุงเทพฯ 10110",
+		MapURL:      "https://maps.google.com/?q=EV+Station+Tower",
+		CompanyName: "บริษัท อีวี สเตชั่น จำกัด",
+		TaxID:       "0105560000000",
+		Branch:      "สำนักงานใหญ่",
+		EmployeeID:  &emp.ID,
 	}
 
 	db.FirstOrCreate(service, entity.Service{Email: "support@evstation.example"})
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Record บันทึก audit log — ส่ง tx เดียวกับรายการที่ทำ เพื่อให้บันทึกพร้อมกันหรือไม่บันทึกเลย
+func Record(tx *gorm.DB, action, entityType string, entityID uint, actorID *uint, detail interface{}) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&entity.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorID:    actorID,
+		Detail:     string(raw),
+	}).Error
+}
+
+// GET /audit-logs?entity_type=Refund&entity_id=1&action=refund.completed
+func ListAuditLogs(c *gin.Context) {
+	db := config.DB().Order("id DESC")
+	if v := c.Query("entity_type"); v != "" {
+		db = db.Where("entity_type = ?", v)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entity_id"})
+			return
+		}
+		db = db.Where("entity_id = ?", id)
+	}
+	if v := c.Query("action"); v != "" {
+		db = db.Where("action = ?", v)
+	}
+
+	limit := 200
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 && v <= 1000 {
+		limit = v
+	}
+
+	var logs []entity.AuditLog
+	if err := db.Limit(limit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": logs})
+}
@@ -0,0 +1,341 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/tariff"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const measurandEnergyImport = "Energy.Active.Import.Register"
+
+var (
+	ErrTransactionOpen = errors.New("transaction has not stopped yet")
+	ErrNoPayment       = errors.New("transaction is not linked to a payment")
+	ErrNoTariff        = errors.New("cannot find EVcharging for this transaction")
+)
+
+// DeliveredWh คำนวณพลังงานที่จ่ายจริง (Wh) ของ transaction
+// ใช้ meterStop - meterStart ก่อน ถ้าใช้ไม่ได้จึงคิดจาก MeterValues ที่เก็บไว้
+func DeliveredWh(tx entity.ChargingTransaction) float64 {
+	if tx.MeterStop != nil && *tx.MeterStop > tx.MeterStart {
+		return float64(*tx.MeterStop - tx.MeterStart)
+	}
+
+	var samples []entity.MeterValue
+	config.DB().
+		Where("charging_transaction_id = ? AND measurand = ? AND phase = ''", tx.ID, measurandEnergyImport).
+		Order("timestamp").
+		Find(&samples)
+	if len(samples) == 0 {
+		return 0
+	}
+
+	minWh, maxWh := math.Inf(1), math.Inf(-1)
+	for _, s := range samples {
+		wh := s.Value
+		if strings.EqualFold(s.Unit, "kWh") {
+			wh *= 1000
+		}
+		minWh = math.Min(minWh, wh)
+		maxWh = math.Max(maxWh, wh)
+	}
+	// meterStart เป็นจุดเริ่มต้นที่แม่นกว่า sample แรก
+	if float64(tx.MeterStart) > 0 && float64(tx.MeterStart) < minWh {
+		minWh = float64(tx.MeterStart)
+	}
+	return maxWh - minWh
+}
+
+// ✅ ค่ามิเตอร์สะสมของ transaction สำหรับแบ่ง kWh ตามช่วงเวลา TOU
+func energyPoints(transactionRowID uint) []tariff.EnergyPoint {
+	var samples []entity.MeterValue
+	config.DB().
+		Where("charging_transaction_id = ? AND measurand = ? AND phase = ''", transactionRowID, measurandEnergyImport).
+		Order("timestamp").
+		Find(&samples)
+
+	points := make([]tariff.EnergyPoint, 0, len(samples))
+	for _, s := range samples {
+		wh := s.Value
+		if strings.EqualFold(s.Unit, "kWh") {
+			wh *= 1000
+		}
+		points = append(points, tariff.EnergyPoint{At: s.Timestamp, Wh: wh})
+	}
+	return points
+}
+
+// ✅ EVcharging ของหัวชาร์จที่ใช้ (Connector → EVcharging)
+func connectorEVcharging(tx entity.ChargingTransaction) *entity.EVcharging {
+	db := config.DB()
+
+	var connector entity.Connector
+	if err := db.Joins("JOIN charge_points ON charge_points.id = connectors.charge_point_id").
+		Where("charge_points.charger_id = ? AND connectors.connector_no = ?", tx.ChargerID, tx.ConnectorID).
+		First(&connector).Error; err != nil || connector.EVchargingID == nil {
+		return nil
+	}
+
+	var ev entity.EVcharging
+	if err := db.First(&ev, *connector.EVchargingID).Error; err != nil {
+		return nil
+	}
+	return &ev
+}
+
+// ✅ EVcharging ที่ต้องคิดราคาของ transaction และสัดส่วน %
+type share struct {
+	ev      entity.EVcharging
+	percent float64
+}
+
+func transactionShares(tx entity.ChargingTransaction) []share {
+	if ev := connectorEVcharging(tx); ev != nil {
+		return []share{{ev: *ev, percent: 100}}
+	}
+	if tx.PaymentID == nil {
+		return nil
+	}
+
+	// ไม่ได้ผูกหัวชาร์จ → แบ่งตามสัดส่วนที่ลูกค้าเลือกตอนชำระเงิน
+	var quoted []entity.EVChargingPayment
+	config.DB().Preload("EVcharging").
+		Where("payment_id = ? AND charging_transaction_id IS NULL", *tx.PaymentID).
+		Find(&quoted)
+	var total float64
+	for _, q := range quoted {
+		total += q.Percent
+	}
+	// 🔸 EVcharging เดียวกันรวมเป็นรายการเดียว (คิดเงินได้รายการเดียวต่อ transaction)
+	var shares []share
+	index := map[uint]int{}
+	for _, q := range quoted {
+		if total <= 0 {
+			break
+		}
+		if i, ok := index[q.EVchargingID]; ok {
+			shares[i].percent += q.Percent * 100 / total
+			continue
+		}
+		index[q.EVchargingID] = len(shares)
+		shares = append(shares, share{ev: q.EVcharging, percent: q.Percent * 100 / total})
+	}
+	return shares
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// BillTransaction สร้าง EVChargingPayment จากพลังงานที่จ่ายจริงของ transaction
+// ถ้าเคยคิดไปแล้วจะคืนรายการเดิม (เรียกซ้ำได้)
+func BillTransaction(transactionRowID uint) ([]entity.EVChargingPayment, error) {
+	db := config.DB()
+
+	var tx entity.ChargingTransaction
+	if err := db.First(&tx, transactionRowID).Error; err != nil {
+		return nil, err
+	}
+	if tx.StoppedAt == nil {
+		return nil, ErrTransactionOpen
+	}
+	if tx.PaymentID == nil {
+		return nil, ErrNoPayment
+	}
+
+	var existing []entity.EVChargingPayment
+	db.Where("charging_transaction_id = ?", tx.ID).Find(&existing)
+	if len(existing) > 0 {
+		return existing, nil
+	}
+
+	kWh := DeliveredWh(tx) / 1000
+
+	// 🔸 หาว่าต้องคิดราคาจาก EVcharging ตัวไหนบ้าง (และสัดส่วน %)
+	shares := transactionShares(tx)
+	if len(shares) == 0 {
+		return nil, ErrNoTariff
+	}
+
+	var payment entity.Payment
+	db.First(&payment, *tx.PaymentID)
+	points := energyPoints(tx.ID)
+
+	txID := tx.ID
+	items := make([]entity.EVChargingPayment, 0, len(shares))
+	for _, s := range shares {
+		energy := kWh * s.percent / 100
+
+		// 🔸 คิดราคาตาม Tariff (TOU) ถ้าไม่มีใช้ EVcharging.Price เดิม
+		t := tariff.Resolve(payment.EVCabinetID, s.ev, tx.StartedAt)
+		bd := tariff.Calculate(t, tx.StartedAt, *tx.StoppedAt, energy, points).Share(s.percent / 100)
+		breakdown, _ := json.Marshal(bd)
+
+		unitPrice := t.PerKWh
+		if energy > 0 {
+			unitPrice = round2(bd.Total / energy)
+		}
+
+		item := entity.EVChargingPayment{
+			EVchargingID:          s.ev.ID,
+			PaymentID:             *tx.PaymentID,
+			Price:                 bd.Total,
+			Percent:               s.percent,
+			Power:                 energy,
+			UnitPrice:             unitPrice,
+			ChargingTransactionID: &txID,
+			Breakdown:             string(breakdown),
+		}
+		if t.ID != 0 {
+			tariffID := t.ID
+			item.TariffID = &tariffID
+		}
+		items = append(items, item)
+	}
+
+	// 🔸 ตรวจซ้ำแล้วบันทึกใน transaction เดียวกัน — unique index (transaction, EVcharging) กันการคิดเงินซ้ำที่มาพร้อมกัน
+	// (คำนวณราคาไว้ก่อนแล้ว เพราะ helper อ่านผ่าน config.DB() ซึ่งมี connection เดียว)
+	created := true
+	err := db.Transaction(func(dbtx *gorm.DB) error {
+		var billed []entity.EVChargingPayment
+		if err := dbtx.Where("charging_transaction_id = ?", tx.ID).Find(&billed).Error; err != nil {
+			return err
+		}
+		if len(billed) > 0 {
+			items, created = billed, false
+			return nil
+		}
+		return dbtx.Create(&items).Error
+	})
+	if err != nil {
+		var billed []entity.EVChargingPayment
+		if db.Where("charging_transaction_id = ?", tx.ID).Find(&billed); len(billed) > 0 {
+			return billed, nil
+		}
+		return nil, err
+	}
+	if !created {
+		return items, nil
+	}
+
+	fmt.Printf("🧾 Billed transaction #%d: %.3f kWh → %d item(s)\n", tx.TransactionID, kWh, len(items))
+	return items, nil
+}
+
+// Estimate ประเมินค่าใช้จ่ายของ transaction ถึงเวลา at (ใช้กับ transaction ที่ยังชาร์จอยู่)
+// transaction ที่คิดเงินไปแล้วจะคืนยอดจริง
+func Estimate(tx entity.ChargingTransaction, at time.Time) (float64, error) {
+	if tx.PaymentID == nil {
+		return 0, ErrNoPayment
+	}
+	db := config.DB()
+
+	var billed []entity.EVChargingPayment
+	db.Where("charging_transaction_id = ?", tx.ID).Find(&billed)
+	if len(billed) > 0 {
+		var total float64
+		for _, b := range billed {
+			total += b.Price
+		}
+		return round2(total), nil
+	}
+
+	shares := transactionShares(tx)
+	if len(shares) == 0 {
+		return 0, ErrNoTariff
+	}
+	end := at
+	if tx.StoppedAt != nil {
+		end = *tx.StoppedAt
+	}
+
+	var payment entity.Payment
+	db.First(&payment, *tx.PaymentID)
+	kWh := DeliveredWh(tx) / 1000
+	points := energyPoints(tx.ID)
+
+	var total float64
+	for _, s := range shares {
+		t := tariff.Resolve(payment.EVCabinetID, s.ev, tx.StartedAt)
+		total += tariff.Calculate(t, tx.StartedAt, end, kWh*s.percent/100, points).Share(s.percent / 100).Total
+	}
+	return round2(total), nil
+}
+
+// QuoteLine คำนวณยอดที่จองไว้ตอนชำระเงินจากฝั่ง server (ไม่เชื่อราคาจาก client)
+func QuoteLine(paymentID, evchargingID uint, percent float64) (*entity.EVChargingPayment, error) {
+	db := config.DB()
+
+	var payment entity.Payment
+	if err := db.First(&payment, paymentID).Error; err != nil {
+		return nil, fmt.Errorf("payment not found")
+	}
+	var ev entity.EVcharging
+	if err := db.First(&ev, evchargingID).Error; err != nil {
+		return nil, fmt.Errorf("evcharging not found")
+	}
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("percent must be between 0 and 100")
+	}
+
+	// แบ่งจากยอดก่อนส่วนลดคูปอง — ได้พลังงานตามมูลค่าเต็ม ส่วนลดไปหักที่เอกสาร
+	price := round2((payment.Amount + payment.Discount) * percent / 100)
+
+	// 🔸 แปลงเงินเป็นพลังงานตาม Tariff เดียวกับตอนคิดเงินจริง (เริ่มชาร์จตอนนี้)
+	now := time.Now()
+	t := tariff.Resolve(payment.EVCabinetID, ev, now)
+	power, bd := tariff.EnergyFor(t, now, price, percent/100)
+	breakdown, _ := json.Marshal(bd)
+	_, unitPrice, _ := tariff.RateAt(t, now)
+
+	line := &entity.EVChargingPayment{
+		EVchargingID: ev.ID,
+		PaymentID:    payment.ID,
+		Price:        price,
+		Percent:      percent,
+		Power:        power,
+		UnitPrice:    unitPrice,
+		Breakdown:    string(breakdown),
+	}
+	if t.ID != 0 {
+		tariffID := t.ID
+		line.TariffID = &tariffID
+	}
+	return line, nil
+}
+
+// POST /billing/transactions/:id
+// คิดเงินซ้ำด้วยมือ (เช่น ตอน StopTransaction หัวชาร์จยังไม่ได้ผูกกับ EVcharging)
+func BillTransactionByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id"})
+		return
+	}
+
+	items, err := BillTransaction(uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	case errors.Is(err, ErrTransactionOpen), errors.Is(err, ErrNoPayment), errors.Is(err, ErrNoTariff):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
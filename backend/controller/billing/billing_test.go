@@ -0,0 +1,156 @@
+package billing
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tawunchai/work-project/config/configtest"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
+)
+
+// ✅ transaction ที่หยุดแล้ว 12.5 kWh บนหัวที่ผูกกับ EVcharging ราคา 8 บาท/kWh (ไม่มี Tariff)
+func seedTransaction(t *testing.T, db *gorm.DB) entity.ChargingTransaction {
+	t.Helper()
+	ev := entity.EVcharging{Name: "AC 22kW", Price: 8}
+	db.Create(&ev)
+	cp := entity.ChargePoint{ChargerID: "CP-1"}
+	db.Create(&cp)
+	db.Create(&entity.Connector{ChargePointID: cp.ID, ConnectorNo: 1, EVchargingID: &ev.ID})
+	payment := entity.Payment{Amount: 100, Status: entity.PaymentApproved}
+	db.Create(&payment)
+
+	started := time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)
+	stopped := started.Add(time.Hour)
+	stop := 13500
+	tx := entity.ChargingTransaction{
+		TransactionID: 1,
+		ChargerID:     "CP-1",
+		ConnectorID:   1,
+		MeterStart:    1000,
+		MeterStop:     &stop,
+		StartedAt:     started,
+		StoppedAt:     &stopped,
+		PaymentID:     &payment.ID,
+	}
+	if err := db.Create(&tx).Error; err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func billedLines(t *testing.T, db *gorm.DB, txID uint) int64 {
+	t.Helper()
+	var n int64
+	db.Model(&entity.EVChargingPayment{}).Where("charging_transaction_id = ?", txID).Count(&n)
+	return n
+}
+
+func TestBillTransactionOnce(t *testing.T) {
+	db := configtest.Open(t)
+	tx := seedTransaction(t, db)
+
+	first, err := BillTransaction(tx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("got %d lines, want 1", len(first))
+	}
+	if first[0].Power != 12.5 || first[0].Price != 100 || first[0].UnitPrice != 8 {
+		t.Errorf("line = %.3f kWh × %.2f = %.2f, want 12.5 kWh × 8 = 100", first[0].Power, first[0].UnitPrice, first[0].Price)
+	}
+
+	// 🔸 เรียกซ้ำ (เช่น StopTransaction ส่งซ้ำ / admin กดคิดเงินอีกครั้ง) ต้องได้รายการเดิม
+	again, err := BillTransaction(tx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || again[0].ID != first[0].ID {
+		t.Errorf("second call returned %+v, want the line billed first (#%d)", again, first[0].ID)
+	}
+	if n := billedLines(t, db, tx.ID); n != 1 {
+		t.Errorf("%d lines stored, want 1", n)
+	}
+}
+
+func TestBillTransactionConcurrent(t *testing.T) {
+	db := configtest.Open(t)
+	tx := seedTransaction(t, db)
+
+	const callers = 8
+	ids := make([]uint, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			items, err := BillTransaction(tx.ID)
+			errs[i] = err
+			if len(items) == 1 {
+				ids[i] = items[0].ID
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range errs {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if ids[i] == 0 || ids[i] != ids[0] {
+			t.Errorf("caller %d got line #%d, caller 0 got #%d", i, ids[i], ids[0])
+		}
+	}
+	if n := billedLines(t, db, tx.ID); n != 1 {
+		t.Errorf("%d lines stored, want 1", n)
+	}
+}
+
+func TestBillTransactionUniqueIndex(t *testing.T) {
+	db := configtest.Open(t)
+	tx := seedTransaction(t, db)
+	items, err := BillTransaction(tx.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dup := items[0]
+	dup.ID = 0
+	if err := db.Create(&dup).Error; err == nil {
+		t.Error("a second billed line for the same transaction and EVcharging should violate the unique index")
+	}
+
+	// ยอดที่จองไว้ตอนชำระเงิน (transaction = NULL) มีได้หลายรายการ
+	for i := 0; i < 2; i++ {
+		quote := entity.EVChargingPayment{EVchargingID: dup.EVchargingID, PaymentID: dup.PaymentID, Percent: 50}
+		if err := db.Create(&quote).Error; err != nil {
+			t.Fatalf("quote line %d: %v", i, err)
+		}
+	}
+}
+
+func TestBillTransactionNotBillable(t *testing.T) {
+	db := configtest.Open(t)
+	tx := seedTransaction(t, db)
+
+	open := entity.ChargingTransaction{TransactionID: 2, ChargerID: "CP-1", ConnectorID: 1, StartedAt: time.Now(), PaymentID: tx.PaymentID}
+	db.Create(&open)
+	if _, err := BillTransaction(open.ID); !errors.Is(err, ErrTransactionOpen) {
+		t.Errorf("open transaction: err %v, want ErrTransactionOpen", err)
+	}
+
+	now := time.Now()
+	unpaid := entity.ChargingTransaction{TransactionID: 3, ChargerID: "CP-1", ConnectorID: 1, StartedAt: now, StoppedAt: &now}
+	db.Create(&unpaid)
+	if _, err := BillTransaction(unpaid.ID); !errors.Is(err, ErrNoPayment) {
+		t.Errorf("transaction without payment: err %v, want ErrNoPayment", err)
+	}
+
+	if n := billedLines(t, db, open.ID) + billedLines(t, db, unpaid.ID); n != 0 {
+		t.Errorf("%d lines stored for unbillable transactions, want 0", n)
+	}
+}
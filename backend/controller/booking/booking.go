@@ -8,6 +8,7 @@ import (
 	"github.com/Tawunchai/work-project/config"
 	"github.com/Tawunchai/work-project/entity"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ✅ CreateBooking User (1 คนจองได้ 1 ครั้งต่อวัน)
@@ -207,6 +208,8 @@ func UpdateBookingByID(c *gin.Context) {
 	booking.StartDate = input.StartDate
 	booking.EndDate = input.EndDate
 	booking.EVCabinetID = &input.EVCabinetID
+	booking.Status = entity.BookingScheduled // เลื่อนเวลาแล้วต้องตรวจใหม่
+	booking.CheckedAt = nil
 
 	if err := db.Save(&booking).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -217,3 +220,49 @@ func UpdateBookingByID(c *gin.Context) {
 }
 
 
+
+// MarkMissedBookings ตรวจ Booking ที่เลยเวลาสิ้นสุดแล้ว — ไม่มีการชาร์จในช่วงที่จอง = missed
+// (นับทั้ง transaction จาก charger ของตู้นั้น และ Payment ของผู้ใช้ที่ตู้นั้น) คืนจำนวน Booking ที่ตรวจ
+func MarkMissedBookings(db *gorm.DB) (int, error) {
+	var bookings []entity.Booking
+	if err := db.Where("(status = ? OR status = '' OR status IS NULL) AND end_date < ?", entity.BookingScheduled, time.Now()).
+		Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	for i, b := range bookings {
+		status := entity.BookingMissed
+		if chargedDuring(db, b) {
+			status = entity.BookingFulfilled
+		}
+		now := time.Now()
+		if err := db.Model(&b).Updates(map[string]interface{}{"status": status, "checked_at": now}).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(bookings), nil
+}
+
+func chargedDuring(db *gorm.DB, b entity.Booking) bool {
+	if b.UserID == nil || b.EVCabinetID == nil {
+		return false
+	}
+
+	var txCount int64
+	db.Model(&entity.ChargingTransaction{}).
+		Joins("JOIN charge_points ON charge_points.charger_id = charging_transactions.charger_id AND charge_points.deleted_at IS NULL").
+		Joins("JOIN charging_sessions ON charging_sessions.id = charging_transactions.charging_session_id").
+		Where("charge_points.ev_cabinet_id = ? AND charging_sessions.user_id = ?", *b.EVCabinetID, *b.UserID).
+		Where("charging_transactions.started_at BETWEEN ? AND ?", b.StartDate, b.EndDate).
+		Count(&txCount)
+	if txCount > 0 {
+		return true
+	}
+
+	var payCount int64
+	db.Model(&entity.Payment{}).
+		Where("user_id = ? AND ev_cabinet_id = ? AND status = ?", *b.UserID, *b.EVCabinetID, entity.PaymentApproved).
+		Where("created_at BETWEEN ? AND ?", b.StartDate, b.EndDate).
+		Count(&payCount)
+	return payCount > 0
+}
@@ -19,7 +19,7 @@ func ListCabinetEV(c *gin.Context) {
 	var cabinets []entity.EVCabinet
 
 	// ✅ โหลดความสัมพันธ์ด้วย Preload เช่น Employee, EVcharging, Booking
-	results := db.Preload("Employee.User").Find(&cabinets)
+	results := db.Preload("Employee.User").Preload("ChargePoints.Connectors").Find(&cabinets)
 
 	if results.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": results.Error.Error()})
@@ -0,0 +1,154 @@
+package chargepoint
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /charge-points
+func ListChargePoints(c *gin.Context) {
+	var points []entity.ChargePoint
+
+	db := config.DB()
+	if err := db.Preload("EVCabinet").Order("charger_id").Find(&points).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// GET /charge-points/cabinet/:id
+func ListChargePointsByCabinetID(c *gin.Context) {
+	cabinetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid EVCabinet ID"})
+		return
+	}
+
+	var points []entity.ChargePoint
+	if err := config.DB().
+		Where("ev_cabinet_id = ?", uint(cabinetID)).
+		Order("charger_id").
+		Find(&points).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": points})
+}
+
+// PATCH /charge-points/:id/cabinet
+// ผูก (หรือยกเลิกการผูกเมื่อส่ง ev_cabinet_id = null) เครื่องชาร์จเข้ากับตู้
+func UpdateChargePointCabinet(c *gin.Context) {
+	id := c.Param("id")
+	db := config.DB()
+
+	var point entity.ChargePoint
+	if err := db.First(&point, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล ChargePoint"})
+		return
+	}
+
+	var input struct {
+		EVCabinetID *uint `json:"ev_cabinet_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.EVCabinetID != nil {
+		var cabinet entity.EVCabinet
+		if err := db.First(&cabinet, *input.EVCabinetID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบข้อมูลตู้ EV Cabinet"})
+			return
+		}
+	}
+
+	if err := db.Model(&point).Update("ev_cabinet_id", input.EVCabinetID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตข้อมูลไม่สำเร็จ"})
+		return
+	}
+
+	db.Preload("EVCabinet").First(&point, point.ID)
+	c.JSON(http.StatusOK, gin.H{"data": point})
+}
+
+// GET /connectors
+func ListConnectors(c *gin.Context) {
+	var connectors []entity.Connector
+
+	db := config.DB()
+	if err := db.Preload("ChargePoint").Preload("EVcharging").
+		Order("charge_point_id, connector_no").
+		Find(&connectors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, connectors)
+}
+
+// GET /connectors/:id/history
+func ListConnectorStatusHistory(c *gin.Context) {
+	id := c.Param("id")
+	db := config.DB()
+
+	var connector entity.Connector
+	if err := db.First(&connector, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Connector"})
+		return
+	}
+
+	var histories []entity.ConnectorStatusHistory
+	if err := db.Where("connector_id = ?", connector.ID).
+		Order("reported_at DESC").
+		Find(&histories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": histories})
+}
+
+// PATCH /connectors/:id/evcharging
+// ผูกหัวชาร์จเข้ากับ EVcharging (ส่ง evcharging_id = null เพื่อยกเลิก)
+func UpdateConnectorEVcharging(c *gin.Context) {
+	id := c.Param("id")
+	db := config.DB()
+
+	var connector entity.Connector
+	if err := db.First(&connector, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Connector"})
+		return
+	}
+
+	var input struct {
+		EVchargingID *uint `json:"evcharging_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.EVchargingID != nil {
+		var ev entity.EVcharging
+		if err := db.First(&ev, *input.EVchargingID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบข้อมูล EV Charging"})
+			return
+		}
+	}
+
+	if err := db.Model(&connector).Update("e_vcharging_id", input.EVchargingID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตข้อมูลไม่สำเร็จ"})
+		return
+	}
+
+	db.Preload("ChargePoint").Preload("EVcharging").First(&connector, connector.ID)
+	c.JSON(http.StatusOK, gin.H{"data": connector})
+}
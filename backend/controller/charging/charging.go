@@ -25,6 +25,7 @@ func ListEVData(c *gin.Context) {
         Preload("Status").
         Preload("Type").
         Preload("Cabinets"). // ⭐ โหลด cabinets (many-to-many)
+        Preload("Connectors"). // ⭐ สถานะจริงจาก StatusNotification
         Find(&evs)
 
     if result.Error != nil {
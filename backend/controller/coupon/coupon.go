@@ -0,0 +1,490 @@
+package coupon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ เหตุผลที่ใช้คูปองไม่ได้ (ส่งกลับใน field "reason")
+const (
+	ReasonNotFound   = "coupon_not_found"
+	ReasonInactive   = "coupon_inactive"
+	ReasonNotStarted = "coupon_not_started"
+	ReasonExpired    = "coupon_expired"
+	ReasonWrongUse   = "coupon_wrong_use"
+	ReasonCabinet    = "coupon_cabinet"
+	ReasonMinSpend   = "coupon_min_spend"
+	ReasonExhausted  = "coupon_exhausted"
+	ReasonUserLimit  = "coupon_user_limit"
+)
+
+// Error คือคูปองที่ใช้กับรายการนี้ไม่ได้
+type Error struct {
+	Reason  string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func invalid(reason, format string, args ...interface{}) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// IsCouponError บอกว่า err มาจากการตรวจคูปอง
+func IsCouponError(err error) bool {
+	var cerr *Error
+	return errors.As(err, &cerr)
+}
+
+// RespondError ตอบ error ของคูปองให้ client ในรูปแบบเดียวกับการตรวจสลิป
+func RespondError(c *gin.Context, err error) {
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": cerr.Message, "reason": cerr.Reason})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// NormalizeCode ตัดช่องว่างและทำเป็นตัวพิมพ์ใหญ่
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Quote คือผลการใช้คูปองกับยอดหนึ่ง
+type Quote struct {
+	Coupon    *entity.Coupon `json:"coupon"`
+	Amount    float64        `json:"amount"`     // ยอดก่อนส่วนลด / ยอดเติม
+	Discount  float64        `json:"discount"`   // charging
+	Net       float64        `json:"net"`        // ยอดที่ต้องชำระ
+	BonusCoin float64        `json:"bonus_coin"` // topup
+}
+
+// Value คำนวณมูลค่าคูปอง (ส่วนลดหรือโบนัส) ของยอด amount — ไม่ถึงยอดขั้นต่ำได้ 0
+func Value(cp *entity.Coupon, amount float64) float64 {
+	if amount < cp.MinSpend || amount <= 0 {
+		return 0
+	}
+	v := cp.Value
+	if cp.Type == entity.CouponPercent {
+		v = amount * cp.Value / 100
+		if cp.MaxDiscount > 0 {
+			v = math.Min(v, cp.MaxDiscount)
+		}
+	}
+	if cp.AppliesTo == entity.CouponForCharging {
+		v = math.Min(v, amount) // ส่วนลดไม่เกินยอดที่ต้องจ่าย
+	}
+	return round2(math.Max(v, 0))
+}
+
+// Apply ตรวจคูปองกับรายการ (ช่วงเวลา, ประเภท, ตู้, ยอดขั้นต่ำ, สิทธิ์คงเหลือ) แล้วคืนส่วนลด/โบนัส
+// เรียกภายใน transaction เดียวกับ Reserve เพื่อให้การนับสิทธิ์ไม่ชนกัน
+func Apply(tx *gorm.DB, code string, userID uint, appliesTo string, amount float64, cabinetID *uint, now time.Time) (*Quote, error) {
+	code = NormalizeCode(code)
+	var cp entity.Coupon
+	if err := tx.Preload("EVCabinets").Where("code = ?", code).First(&cp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalid(ReasonNotFound, "ไม่พบคูปอง %s", code)
+		}
+		return nil, err
+	}
+
+	switch {
+	case !cp.Active:
+		return nil, invalid(ReasonInactive, "คูปองนี้ถูกปิดใช้งาน")
+	case cp.StartsAt != nil && now.Before(*cp.StartsAt):
+		return nil, invalid(ReasonNotStarted, "คูปองนี้ยังไม่เริ่มใช้งาน")
+	case cp.EndsAt != nil && now.After(*cp.EndsAt):
+		return nil, invalid(ReasonExpired, "คูปองนี้หมดอายุแล้ว")
+	case cp.AppliesTo != appliesTo:
+		if cp.AppliesTo == entity.CouponForTopUp {
+			return nil, invalid(ReasonWrongUse, "คูปองนี้ใช้ได้เฉพาะการเติม Coin")
+		}
+		return nil, invalid(ReasonWrongUse, "คูปองนี้ใช้ได้เฉพาะการชำระค่าชาร์จ")
+	case amount < cp.MinSpend:
+		return nil, invalid(ReasonMinSpend, "ยอดขั้นต่ำสำหรับคูปองนี้คือ %.2f บาท", cp.MinSpend)
+	}
+
+	if len(cp.EVCabinets) > 0 {
+		allowed := false
+		for _, cab := range cp.EVCabinets {
+			if cabinetID != nil && cab.ID == *cabinetID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, invalid(ReasonCabinet, "คูปองนี้ใช้ไม่ได้กับตู้ชาร์จนี้")
+		}
+	}
+
+	// 🔸 สิทธิ์ที่ใช้ไปแล้ว (รวมรายการที่รอตรวจ ไม่นับรายการที่ถูกยกเลิก)
+	used := tx.Model(&entity.CouponRedemption{}).Where("coupon_id = ? AND status <> ?", cp.ID, entity.RedemptionVoid)
+	if cp.MaxUses > 0 {
+		var n int64
+		if err := used.Session(&gorm.Session{}).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n >= int64(cp.MaxUses) {
+			return nil, invalid(ReasonExhausted, "คูปองนี้ถูกใช้ครบจำนวนแล้ว")
+		}
+	}
+	if cp.MaxUsesPerUser > 0 {
+		var n int64
+		if err := used.Session(&gorm.Session{}).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n >= int64(cp.MaxUsesPerUser) {
+			return nil, invalid(ReasonUserLimit, "คุณใช้คูปองนี้ครบจำนวนครั้งแล้ว")
+		}
+	}
+
+	q := &Quote{Coupon: &cp, Amount: round2(amount), Net: round2(amount)}
+	if appliesTo == entity.CouponForCharging {
+		q.Discount = Value(&cp, amount)
+		q.Net = round2(amount - q.Discount)
+	} else {
+		q.BonusCoin = Value(&cp, amount)
+	}
+	return q, nil
+}
+
+// Reserve บันทึกการใช้คูปองของรายการ (status = applied หรือ pending ถ้ารอ admin ตรวจ)
+func Reserve(tx *gorm.DB, q *Quote, userID uint, paymentID, paymentCoinID, cabinetID *uint, status string) error {
+	return tx.Create(&entity.CouponRedemption{
+		CouponID:      q.Coupon.ID,
+		Code:          q.Coupon.Code,
+		UserID:        userID,
+		PaymentID:     paymentID,
+		PaymentCoinID: paymentCoinID,
+		EVCabinetID:   cabinetID,
+		Amount:        q.Amount,
+		Discount:      q.Discount,
+		BonusCoin:     q.BonusCoin,
+		Status:        status,
+	}).Error
+}
+
+// RedemptionStatus แปลงสถานะของรายการเป็นสถานะการใช้คูปอง
+func RedemptionStatus(paymentStatus string) string {
+	if paymentStatus == entity.PaymentApproved {
+		return entity.RedemptionApplied
+	}
+	return entity.RedemptionPending
+}
+
+func redemptionOf(tx *gorm.DB, paymentID, paymentCoinID *uint) *gorm.DB {
+	q := tx.Model(&entity.CouponRedemption{}).Where("status = ?", entity.RedemptionPending)
+	if paymentCoinID != nil {
+		return q.Where("payment_coin_id = ?", *paymentCoinID)
+	}
+	return q.Where("payment_id = ?", *paymentID)
+}
+
+// Confirm ยืนยันการใช้คูปองเมื่อ admin อนุมัติรายการ
+// สำหรับการเติม Coin จะคิดโบนัสใหม่จากยอดที่ admin ยืนยัน (amount) และคืนค่าโบนัส
+func Confirm(tx *gorm.DB, paymentID, paymentCoinID *uint, amount float64) (float64, error) {
+	var r entity.CouponRedemption
+	if err := redemptionOf(tx, paymentID, paymentCoinID).Preload("Coupon").First(&r).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	updates := map[string]interface{}{"status": entity.RedemptionApplied}
+	if paymentCoinID != nil && r.Coupon != nil {
+		r.BonusCoin = Value(r.Coupon, amount)
+		updates["amount"] = round2(amount)
+		updates["bonus_coin"] = r.BonusCoin
+	}
+	if err := tx.Model(&entity.CouponRedemption{}).Where("id = ?", r.ID).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	return r.BonusCoin, nil
+}
+
+// Void คืนสิทธิ์การใช้คูปองของรายการที่ไม่ผ่านการตรวจ
+func Void(tx *gorm.DB, paymentID, paymentCoinID *uint) error {
+	return redemptionOf(tx, paymentID, paymentCoinID).Update("status", entity.RedemptionVoid).Error
+}
+
+// ============================================================================
+// 🔹 Handlers
+// ============================================================================
+
+type couponInput struct {
+	Code           string     `json:"code"`
+	Description    string     `json:"description"`
+	AppliesTo      string     `json:"applies_to"`
+	Type           string     `json:"type"`
+	Value          float64    `json:"value"`
+	MaxDiscount    float64    `json:"max_discount"`
+	MinSpend       float64    `json:"min_spend"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxUses        int        `json:"max_uses"`
+	MaxUsesPerUser int        `json:"max_uses_per_user"`
+	Active         *bool      `json:"active"`
+	EVCabinetIDs   []uint     `json:"ev_cabinet_ids"`
+}
+
+// ✅ ตรวจค่าคูปองก่อนบันทึก
+func (in *couponInput) validate() string {
+	in.Code = NormalizeCode(in.Code)
+	switch {
+	case in.Code == "" || strings.ContainsAny(in.Code, " \t/"):
+		return "รหัสคูปองไม่ถูกต้อง"
+	case in.AppliesTo != entity.CouponForCharging && in.AppliesTo != entity.CouponForTopUp:
+		return "applies_to ต้องเป็น charging หรือ topup"
+	case in.Type != entity.CouponPercent && in.Type != entity.CouponFixed:
+		return "type ต้องเป็น percent หรือ fixed"
+	case in.Value <= 0 || (in.Type == entity.CouponPercent && in.Value > 100):
+		return "มูลค่าคูปองไม่ถูกต้อง"
+	case in.MaxDiscount < 0 || in.MinSpend < 0 || in.MaxUses < 0 || in.MaxUsesPerUser < 0:
+		return "ค่าจำกัดต้องไม่ติดลบ"
+	case in.StartsAt != nil && in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt):
+		return "วันสิ้นสุดต้องอยู่หลังวันเริ่มต้น"
+	case in.AppliesTo == entity.CouponForTopUp && len(in.EVCabinetIDs) > 0:
+		return "คูปองเติม Coin จำกัดตู้ชาร์จไม่ได้"
+	}
+	return ""
+}
+
+func (in *couponInput) apply(cp *entity.Coupon) {
+	cp.Code = in.Code
+	cp.Description = strings.TrimSpace(in.Description)
+	cp.AppliesTo = in.AppliesTo
+	cp.Type = in.Type
+	cp.Value = in.Value
+	cp.MaxDiscount = in.MaxDiscount
+	cp.MinSpend = in.MinSpend
+	cp.StartsAt = in.StartsAt
+	cp.EndsAt = in.EndsAt
+	cp.MaxUses = in.MaxUses
+	cp.MaxUsesPerUser = in.MaxUsesPerUser
+	if in.Active != nil {
+		cp.Active = *in.Active
+	}
+}
+
+func cabinets(tx *gorm.DB, ids []uint) ([]entity.EVCabinet, error) {
+	var list []entity.EVCabinet
+	if len(ids) == 0 {
+		return list, nil
+	}
+	if err := tx.Find(&list, ids).Error; err != nil {
+		return nil, err
+	}
+	if len(list) != len(ids) {
+		return nil, fmt.Errorf("ไม่พบตู้ชาร์จบางรายการ")
+	}
+	return list, nil
+}
+
+// GET /coupons
+func ListCoupons(c *gin.Context) {
+	var coupons []entity.Coupon
+	if err := config.DB().Preload("EVCabinets").Order("id DESC").Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, coupons)
+}
+
+// POST /coupons
+func CreateCoupon(c *gin.Context) {
+	var input couponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	db := config.DB()
+	cp := entity.Coupon{Active: true}
+	input.apply(&cp)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		tx.Model(&entity.Coupon{}).Unscoped().Where("code = ?", cp.Code).Count(&n)
+		if n > 0 {
+			return invalid("coupon_duplicate", "รหัสคูปอง %s มีอยู่แล้ว", cp.Code)
+		}
+		list, err := cabinets(tx, input.EVCabinetIDs)
+		if err != nil {
+			return err
+		}
+		cp.EVCabinets = list
+		return tx.Create(&cp).Error
+	})
+	if err != nil {
+		if IsCouponError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "สร้างคูปองสำเร็จ", "data": cp})
+}
+
+// PATCH /coupons/:id — แก้ไขคูปอง (รหัสเดิมที่ถูกใช้แล้วเปลี่ยนไม่ได้)
+func UpdateCoupon(c *gin.Context) {
+	db := config.DB()
+	var cp entity.Coupon
+	if err := db.Preload("EVCabinets").First(&cp, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคูปอง"})
+		return
+	}
+
+	input := couponInput{
+		Code: cp.Code, Description: cp.Description, AppliesTo: cp.AppliesTo, Type: cp.Type,
+		Value: cp.Value, MaxDiscount: cp.MaxDiscount, MinSpend: cp.MinSpend,
+		StartsAt: cp.StartsAt, EndsAt: cp.EndsAt, MaxUses: cp.MaxUses, MaxUsesPerUser: cp.MaxUsesPerUser,
+	}
+	for _, cab := range cp.EVCabinets {
+		input.EVCabinetIDs = append(input.EVCabinetIDs, cab.ID)
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if input.Code != cp.Code {
+			var n int64
+			tx.Model(&entity.CouponRedemption{}).Where("coupon_id = ?", cp.ID).Count(&n)
+			if n > 0 {
+				return invalid("coupon_in_use", "คูปองที่ถูกใช้แล้วเปลี่ยนรหัสไม่ได้")
+			}
+		}
+		input.apply(&cp)
+		list, err := cabinets(tx, input.EVCabinetIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&cp).Association("EVCabinets").Replace(list); err != nil {
+			return err
+		}
+		cp.EVCabinets = list
+		return tx.Omit("EVCabinets").Save(&cp).Error
+	})
+	if err != nil {
+		if IsCouponError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "แก้ไขคูปองสำเร็จ", "data": cp})
+}
+
+// DELETE /coupons/:id — ปิดใช้งาน (เก็บประวัติการใช้ไว้ทำรายงาน)
+func DeleteCoupon(c *gin.Context) {
+	res := config.DB().Model(&entity.Coupon{}).Where("id = ?", c.Param("id")).Update("active", false)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคูปอง"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ปิดใช้งานคูปองแล้ว"})
+}
+
+// POST /coupons/validate {code, user_id, applies_to, amount, ev_cabinet_id}
+// ตรวจคูปองก่อนชำระเงิน ให้หน้าเว็บแสดงยอดหลังหักส่วนลด/โบนัส (ยังไม่ใช้สิทธิ์)
+func ValidateCoupon(c *gin.Context) {
+	var input struct {
+		Code        string  `json:"code" binding:"required"`
+		UserID      uint    `json:"user_id" binding:"required"`
+		AppliesTo   string  `json:"applies_to"`
+		Amount      float64 `json:"amount"`
+		EVCabinetID *uint   `json:"ev_cabinet_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if input.AppliesTo == "" {
+		input.AppliesTo = entity.CouponForCharging
+	}
+	q, err := Apply(config.DB(), input.Code, input.UserID, input.AppliesTo, input.Amount, input.EVCabinetID, time.Now())
+	if err != nil {
+		RespondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": q})
+}
+
+// redemptionTotal คือยอดรวมการใช้คูปองหนึ่งรหัส
+type redemptionTotal struct {
+	CouponID  uint    `json:"coupon_id"`
+	Code      string  `json:"code"`
+	Uses      int64   `json:"uses"`
+	Users     int64   `json:"users"`
+	Amount    float64 `json:"amount"`
+	Discount  float64 `json:"discount"`
+	BonusCoin float64 `json:"bonus_coin"`
+}
+
+// GET /coupon-redemptions?coupon_id=&user_id=&status=&from=&to=
+// ประวัติการใช้คูปองพร้อมยอดรวม (ไม่นับรายการที่ถูกยกเลิก)
+func ListRedemptions(c *gin.Context) {
+	db := config.DB()
+	query := db.Model(&entity.CouponRedemption{})
+	if v := c.Query("coupon_id"); v != "" {
+		query = query.Where("coupon_id = ?", v)
+	}
+	if v := c.Query("user_id"); v != "" {
+		query = query.Where("user_id = ?", v)
+	}
+	if v := c.Query("status"); v != "" {
+		query = query.Where("status IN ?", strings.Split(v, ","))
+	}
+	if v, err := time.Parse("2006-01-02", c.Query("from")); err == nil {
+		query = query.Where("created_at >= ?", v)
+	}
+	if v, err := time.Parse("2006-01-02", c.Query("to")); err == nil {
+		query = query.Where("created_at < ?", v.AddDate(0, 0, 1))
+	}
+
+	summary := []redemptionTotal{}
+	if err := query.Session(&gorm.Session{}).
+		Where("status <> ?", entity.RedemptionVoid).
+		Select("coupon_id, code, COUNT(*) AS uses, COUNT(DISTINCT user_id) AS users, SUM(amount) AS amount, SUM(discount) AS discount, SUM(bonus_coin) AS bonus_coin").
+		Group("coupon_id, code").Order("coupon_id").
+		Scan(&summary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var redemptions []entity.CouponRedemption
+	if err := query.Session(&gorm.Session{}).Preload("User").Order("id DESC").Find(&redemptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": redemptions, "summary": summary})
+}
@@ -0,0 +1,189 @@
+package coupon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tawunchai/work-project/config/configtest"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func ptr[T any](v T) *T { return &v }
+
+func reason(err error) string {
+	var cerr *Error
+	if errors.As(err, &cerr) {
+		return cerr.Reason
+	}
+	return ""
+}
+
+// ✅ ใช้คูปองแบบเดียวกับ CreateCharge: Apply + Reserve ใน transaction เดียว
+func redeem(t *testing.T, db *gorm.DB, code string, userID, paymentID uint, status string) error {
+	t.Helper()
+	return db.Transaction(func(tx *gorm.DB) error {
+		q, err := Apply(tx, code, userID, entity.CouponForCharging, 200, nil, now)
+		if err != nil {
+			return err
+		}
+		return Reserve(tx, q, userID, &paymentID, nil, nil, status)
+	})
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon entity.Coupon
+		amount float64
+		want   float64
+	}{
+		{"percent", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponPercent, Value: 15}, 200, 30},
+		{"percent capped", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponPercent, Value: 50, MaxDiscount: 40}, 200, 40},
+		{"fixed", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponFixed, Value: 25}, 200, 25},
+		{"fixed over amount", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponFixed, Value: 500}, 200, 200},
+		{"100% off", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponPercent, Value: 100}, 99.99, 99.99},
+		{"below min spend", entity.Coupon{AppliesTo: entity.CouponForCharging, Type: entity.CouponFixed, Value: 25, MinSpend: 300}, 200, 0},
+		{"topup bonus not capped by amount", entity.Coupon{AppliesTo: entity.CouponForTopUp, Type: entity.CouponFixed, Value: 500}, 200, 500},
+		{"rounded", entity.Coupon{AppliesTo: entity.CouponForTopUp, Type: entity.CouponPercent, Value: 7}, 33.33, 2.33},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Value(&tt.coupon, tt.amount); got != tt.want {
+				t.Errorf("Value = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUsageLimits(t *testing.T) {
+	db := configtest.Open(t)
+	db.Create(&entity.Coupon{
+		Code: "EV2", AppliesTo: entity.CouponForCharging, Type: entity.CouponFixed, Value: 20,
+		MaxUses: 2, MaxUsesPerUser: 1, Active: true,
+	})
+
+	if err := redeem(t, db, "ev2", 1, 101, entity.RedemptionApplied); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := redeem(t, db, "EV2", 1, 102, entity.RedemptionApplied); reason(err) != ReasonUserLimit {
+		t.Fatalf("same user again: err %v, want %s", err, ReasonUserLimit)
+	}
+	// 🔸 รายการที่รอ admin ตรวจจองสิทธิ์ไว้แล้ว
+	if err := redeem(t, db, "EV2", 2, 103, entity.RedemptionPending); err != nil {
+		t.Fatalf("second user: %v", err)
+	}
+	if err := redeem(t, db, "EV2", 3, 104, entity.RedemptionApplied); reason(err) != ReasonExhausted {
+		t.Fatalf("third user: err %v, want %s", err, ReasonExhausted)
+	}
+
+	// admin ไม่อนุมัติรายการที่รอตรวจ → คืนสิทธิ์
+	if err := Void(db, ptr(uint(103)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := redeem(t, db, "EV2", 3, 105, entity.RedemptionApplied); err != nil {
+		t.Fatalf("after void: %v", err)
+	}
+
+	var n int64
+	db.Model(&entity.CouponRedemption{}).Where("status <> ?", entity.RedemptionVoid).Count(&n)
+	if n != 2 {
+		t.Errorf("%d redemptions in use, want MaxUses = 2", n)
+	}
+}
+
+func TestApplyRejected(t *testing.T) {
+	db := configtest.Open(t)
+	cabinet := entity.EVCabinet{}
+	db.Create(&cabinet)
+	other := entity.EVCabinet{}
+	db.Create(&other)
+
+	coupons := []entity.Coupon{
+		{Code: "OFF", Active: false},
+		{Code: "SOON", Active: true, StartsAt: ptr(now.Add(time.Hour))},
+		{Code: "OLD", Active: true, EndsAt: ptr(now.Add(-time.Hour))},
+		{Code: "TOPUP", Active: true, AppliesTo: entity.CouponForTopUp},
+		{Code: "BIG", Active: true, MinSpend: 500},
+		{Code: "SITE", Active: true, EVCabinets: []entity.EVCabinet{cabinet}},
+	}
+	for i := range coupons {
+		if coupons[i].AppliesTo == "" {
+			coupons[i].AppliesTo = entity.CouponForCharging
+		}
+		coupons[i].Type, coupons[i].Value = entity.CouponFixed, 10
+		if err := db.Create(&coupons[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Model(&entity.Coupon{}).Where("code = ?", "OFF").Update("active", false)
+
+	tests := []struct {
+		code    string
+		cabinet *uint
+		want    string
+	}{
+		{"NOPE", nil, ReasonNotFound},
+		{"OFF", nil, ReasonInactive},
+		{"SOON", nil, ReasonNotStarted},
+		{"OLD", nil, ReasonExpired},
+		{"TOPUP", nil, ReasonWrongUse},
+		{"BIG", nil, ReasonMinSpend},
+		{"SITE", nil, ReasonCabinet},
+		{"SITE", &other.ID, ReasonCabinet},
+		{"SITE", &cabinet.ID, ""},
+		{" site ", &cabinet.ID, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			q, err := Apply(db, tt.code, 1, entity.CouponForCharging, 200, tt.cabinet, now)
+			if got := reason(err); got != tt.want || (tt.want == "" && err != nil) {
+				t.Fatalf("err %v (reason %q), want reason %q", err, got, tt.want)
+			}
+			if err == nil && (q.Discount != 10 || q.Net != 190) {
+				t.Errorf("quote %+v, want discount 10 net 190", q)
+			}
+		})
+	}
+}
+
+func TestConfirmTopUpBonus(t *testing.T) {
+	db := configtest.Open(t)
+	db.Create(&entity.Coupon{Code: "BONUS10", AppliesTo: entity.CouponForTopUp, Type: entity.CouponPercent, Value: 10, Active: true})
+
+	// ผู้ใช้แจ้งยอด 500 แต่ admin ยืนยันยอดในสลิปเป็น 300 → โบนัสคิดจากยอดที่ยืนยัน
+	err := db.Transaction(func(tx *gorm.DB) error {
+		q, err := Apply(tx, "BONUS10", 1, entity.CouponForTopUp, 500, nil, now)
+		if err != nil {
+			return err
+		}
+		if q.BonusCoin != 50 || q.Net != 500 {
+			t.Errorf("quote %+v, want bonus 50", q)
+		}
+		return Reserve(tx, q, 1, nil, ptr(uint(7)), nil, entity.RedemptionPending)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bonus, err := Confirm(db, nil, ptr(uint(7)), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bonus != 30 {
+		t.Errorf("bonus %v, want 30", bonus)
+	}
+	var r entity.CouponRedemption
+	db.First(&r)
+	if r.Status != entity.RedemptionApplied || r.Amount != 300 || r.BonusCoin != 30 {
+		t.Errorf("redemption %+v, want applied 300 / 30", r)
+	}
+
+	// รายการที่ไม่มีคูปองยืนยันได้โดยไม่มีผล
+	if bonus, err := Confirm(db, nil, ptr(uint(8)), 300); err != nil || bonus != 0 {
+		t.Errorf("Confirm without coupon = %v, %v", bonus, err)
+	}
+}
@@ -0,0 +1,375 @@
+package invoice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// VATRate คืออัตราภาษีมูลค่าเพิ่ม (ราคาค่าชาร์จรวม VAT แล้ว)
+const VATRate = 0.07
+
+const invoiceDir = "uploads/invoices"
+
+// ✅ error ของการออกเอกสาร
+var (
+	ErrNotPaid       = errors.New("ออกเอกสารได้เฉพาะรายการที่อนุมัติการชำระแล้ว")
+	ErrNothingToBill = errors.New("รายการนี้ไม่มียอดที่ต้องออกเอกสาร")
+	ErrNotIssued     = errors.New("ยังไม่ได้ออกใบกำกับภาษีสำหรับรายการนี้")
+)
+
+// Line คือรายการหนึ่งบรรทัดในเอกสาร
+type Line struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"` // kWh (0 = ไม่แสดง)
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"` // รวม VAT
+}
+
+// Buyer คือข้อมูลผู้ซื้อสำหรับใบกำกับภาษีเต็มรูป
+type Buyer struct {
+	Name    string `json:"buyer_name" binding:"required"`
+	TaxID   string `json:"buyer_tax_id" binding:"required"`
+	Branch  string `json:"buyer_branch"`
+	Address string `json:"buyer_address" binding:"required"`
+}
+
+var prefixes = map[string]string{
+	entity.InvoiceReceipt:    "RC",
+	entity.InvoiceTaxInvoice: "INV",
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ValidTaxID ตรวจเลขประจำตัวผู้เสียภาษี 13 หลัก (check digit แบบเดียวกับเลขบัตรประชาชน)
+func ValidTaxID(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return false
+		}
+		if i < 12 {
+			sum += int(id[i]-'0') * (13 - i)
+		}
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+// nextNumber ออกเลขเอกสารถัดไปของชุด (เช่น INV-2026-000001) — เรียกภายใน transaction
+// เดียวกับการบันทึกเอกสาร เพื่อให้เลขไม่ข้ามเมื่อบันทึกไม่สำเร็จ
+func nextNumber(tx *gorm.DB, kind string, issuedAt time.Time) (string, error) {
+	series := fmt.Sprintf("%s-%d", prefixes[kind], issuedAt.In(bangkok).Year())
+	seq := entity.DocumentSequence{Series: series}
+	if err := tx.FirstOrCreate(&seq, entity.DocumentSequence{Series: series}).Error; err != nil {
+		return "", err
+	}
+	res := tx.Model(&entity.DocumentSequence{}).
+		Where("series = ? AND last = ?", series, seq.Last).
+		UpdateColumn("last", seq.Last+1)
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected != 1 {
+		return "", fmt.Errorf("เลขเอกสารชุด %s ถูกใช้พร้อมกัน กรุณาลองใหม่", series)
+	}
+	return fmt.Sprintf("%s-%06d", series, seq.Last+1), nil
+}
+
+// lineItems สร้างรายการจาก EVChargingPayment ของ Payment
+// ใช้ยอดที่จองไว้ตอนชำระ (รวมเท่ากับยอดก่อนส่วนลด) แล้วหักส่วนลดคูปองและยอดที่คืนเงินไปแล้ว
+func lineItems(tx *gorm.DB, payment entity.Payment) ([]Line, error) {
+	var items []entity.EVChargingPayment
+	if err := tx.Preload("EVcharging").
+		Where("payment_id = ? AND charging_transaction_id IS NULL", payment.ID).
+		Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		if err := tx.Preload("EVcharging").Where("payment_id = ?", payment.ID).Order("id").Find(&items).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var lines []Line
+	for _, item := range items {
+		unitPrice := item.UnitPrice
+		if unitPrice == 0 && item.Power > 0 {
+			unitPrice = round2(item.Price / item.Power) // รายการเก่าที่ยังไม่ได้เก็บราคาต่อหน่วย
+		}
+		lines = append(lines, Line{
+			Description: strings.TrimSpace(item.EVcharging.Name),
+			Quantity:    math.Round(item.Power*1000) / 1000,
+			UnitPrice:   unitPrice,
+			Amount:      round2(item.Price),
+		})
+	}
+	if len(lines) == 0 {
+		lines = append(lines, Line{Amount: round2(payment.Amount + payment.Discount)})
+	}
+	if payment.Discount > 0 {
+		lines = append(lines, Line{Description: discountLine, Amount: -round2(payment.Discount)})
+	}
+
+	var refunded float64
+	if err := tx.Model(&entity.Refund{}).
+		Where("payment_id = ? AND status = ?", payment.ID, entity.RefundCompleted).
+		Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+	if refunded > 0 {
+		lines = append(lines, Line{Description: refundLine, Amount: -round2(refunded)})
+	}
+	return lines, nil
+}
+
+// refundLine / discountLine คือคำอธิบายบรรทัดหักคืนเงิน / ส่วนลด (แปลตอนสร้าง PDF)
+const (
+	refundLine   = "#refund"
+	discountLine = "#discount"
+)
+
+// Issue ออกเอกสารให้ Payment — ถ้าเคยออกชนิดเดียวกันแล้วคืนฉบับเดิม (เลขเอกสารไม่เปลี่ยน)
+func Issue(db *gorm.DB, paymentID uint, kind string, buyer *Buyer) (*entity.Invoice, error) {
+	var inv entity.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("payment_id = ? AND kind = ?", paymentID, kind).First(&inv).Error; err == nil {
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var payment entity.Payment
+		if err := tx.Preload("User").First(&payment, paymentID).Error; err != nil {
+			return err
+		}
+		if payment.Status != entity.PaymentApproved {
+			return ErrNotPaid
+		}
+
+		lines, err := lineItems(tx, payment)
+		if err != nil {
+			return err
+		}
+		total := 0.0
+		for _, l := range lines {
+			total += l.Amount
+		}
+		total = round2(total)
+		if total <= 0 {
+			return ErrNothingToBill
+		}
+		vat := round2(total * VATRate / (1 + VATRate))
+
+		var seller entity.Service
+		tx.Order("id").First(&seller)
+
+		now := time.Now()
+		number, err := nextNumber(tx, kind, now)
+		if err != nil {
+			return err
+		}
+		raw, _ := json.Marshal(lines)
+		inv = entity.Invoice{
+			Kind:          kind,
+			Number:        number,
+			IssuedAt:      now,
+			PaymentID:     payment.ID,
+			Subtotal:      round2(total - vat),
+			VATRate:       VATRate,
+			VAT:           vat,
+			Total:         total,
+			SellerName:    seller.CompanyName,
+			SellerTaxID:   seller.TaxID,
+			SellerBranch:  seller.Branch,
+			SellerAddress: seller.Location,
+			Lines:         string(raw),
+			File:          filepath.ToSlash(filepath.Join(invoiceDir, number+".pdf")),
+		}
+		switch {
+		case buyer != nil:
+			inv.BuyerName = buyer.Name
+			inv.BuyerTaxID = buyer.TaxID
+			inv.BuyerBranch = buyer.Branch
+			inv.BuyerAddress = buyer.Address
+		case payment.User != nil:
+			inv.BuyerName = strings.TrimSpace(payment.User.FirstName + " " + payment.User.LastName)
+		}
+		if err := tx.Create(&inv).Error; err != nil {
+			return err
+		}
+
+		// 🔸 สร้างไฟล์ใน transaction — ถ้าเขียนไม่ได้ เลขเอกสารจะไม่ถูกใช้
+		return writeFile(&inv, &payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// writeFile สร้าง PDF จากข้อมูลที่บันทึกไว้ในเอกสาร
+func writeFile(inv *entity.Invoice, payment *entity.Payment) error {
+	data, err := Render(inv, payment)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(inv.File), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(inv.File, data, 0o644)
+}
+
+// ensureFile สร้างไฟล์ซ้ำจาก snapshot ถ้าไฟล์หาย (เช่น ย้ายเครื่อง)
+func ensureFile(db *gorm.DB, inv *entity.Invoice) error {
+	if _, err := os.Stat(inv.File); err == nil {
+		return nil
+	}
+	var payment entity.Payment
+	if err := db.Unscoped().First(&payment, inv.PaymentID).Error; err != nil {
+		return err
+	}
+	return writeFile(inv, &payment)
+}
+
+// ============================================================================
+// 🔹 Handlers
+// ============================================================================
+
+func paymentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการชำระเงิน"})
+	case errors.Is(err, ErrNotIssued):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotPaid), errors.Is(err, ErrNothingToBill):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /payments/:id/receipt — ออกใบเสร็จ (ใบกำกับภาษีอย่างย่อ)
+func IssueReceipt(c *gin.Context) {
+	id, ok := paymentID(c)
+	if !ok {
+		return
+	}
+	inv, err := Issue(config.DB(), id, entity.InvoiceReceipt, nil)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ออกใบเสร็จสำเร็จ", "data": inv})
+}
+
+// POST /payments/:id/tax-invoice {buyer_name, buyer_tax_id, buyer_branch, buyer_address}
+func IssueTaxInvoice(c *gin.Context) {
+	id, ok := paymentID(c)
+	if !ok {
+		return
+	}
+	var buyer Buyer
+	if err := c.ShouldBindJSON(&buyer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุชื่อ ที่อยู่ และเลขประจำตัวผู้เสียภาษีของผู้ซื้อ"})
+		return
+	}
+	buyer.Name = strings.TrimSpace(buyer.Name)
+	buyer.Address = strings.TrimSpace(buyer.Address)
+	buyer.TaxID = strings.NewReplacer("-", "", " ", "").Replace(buyer.TaxID)
+	buyer.Branch = strings.TrimSpace(buyer.Branch)
+	if buyer.Branch == "" {
+		buyer.Branch = "สำนักงานใหญ่"
+	}
+	if !ValidTaxID(buyer.TaxID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เลขประจำตัวผู้เสียภาษีไม่ถูกต้อง"})
+		return
+	}
+
+	inv, err := Issue(config.DB(), id, entity.InvoiceTaxInvoice, &buyer)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ออกใบกำกับภาษีสำเร็จ", "data": inv})
+}
+
+// GET /payments/:id/invoices — เอกสารทั้งหมดของรายการ
+func ListInvoicesByPayment(c *gin.Context) {
+	id, ok := paymentID(c)
+	if !ok {
+		return
+	}
+	var invoices []entity.Invoice
+	if err := config.DB().Where("payment_id = ?", id).Order("id").Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invoices})
+}
+
+// download ส่งไฟล์ PDF ให้ดาวน์โหลด
+func download(c *gin.Context, inv *entity.Invoice) {
+	if err := ensureFile(config.DB(), inv); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.FileAttachment(inv.File, inv.Number+".pdf")
+}
+
+// GET /payments/:id/receipt — ดาวน์โหลดใบเสร็จ (ออกให้อัตโนมัติถ้ายังไม่มี)
+func DownloadReceipt(c *gin.Context) {
+	id, ok := paymentID(c)
+	if !ok {
+		return
+	}
+	inv, err := Issue(config.DB(), id, entity.InvoiceReceipt, nil)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	download(c, inv)
+}
+
+// GET /payments/:id/tax-invoice — ดาวน์โหลดใบกำกับภาษีที่ออกแล้ว
+func DownloadTaxInvoice(c *gin.Context) {
+	id, ok := paymentID(c)
+	if !ok {
+		return
+	}
+	var inv entity.Invoice
+	if err := config.DB().Where("payment_id = ? AND kind = ?", id, entity.InvoiceTaxInvoice).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = ErrNotIssued
+		}
+		respondError(c, err)
+		return
+	}
+	download(c, &inv)
+}
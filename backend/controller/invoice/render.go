@@ -0,0 +1,326 @@
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/pdf"
+)
+
+var bangkok = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Bangkok"); err == nil {
+		return loc
+	}
+	return time.FixedZone("ICT", 7*60*60)
+}()
+
+// ✅ ฟอนต์ภาษาไทย — ตั้ง INVOICE_FONT / INVOICE_FONT_BOLD เป็นไฟล์ .ttf
+// ถ้าไม่ตั้งจะหาจาก uploads/fonts และฟอนต์ของระบบ ถ้าไม่พบใช้ Helvetica (ป้ายกำกับเป็นภาษาอังกฤษ)
+var fontCandidates = [][2]string{
+	{"uploads/fonts/THSarabunNew.ttf", "uploads/fonts/THSarabunNew Bold.ttf"},
+	{"/usr/share/fonts/truetype/tlwg/Garuda.ttf", "/usr/share/fonts/truetype/tlwg/Garuda-Bold.ttf"},
+	{"/usr/share/fonts/truetype/noto/NotoSansThai-Regular.ttf", "/usr/share/fonts/truetype/noto/NotoSansThai-Bold.ttf"},
+	{"/usr/share/fonts/truetype/tlwg/Loma.ttf", "/usr/share/fonts/truetype/tlwg/Loma-Bold.ttf"},
+}
+
+var (
+	fontOnce    sync.Once
+	regularFace *pdf.TrueType
+	boldFace    *pdf.TrueType
+)
+
+func loadFace(path string) *pdf.TrueType {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	face, err := pdf.ParseTrueType(data)
+	if err != nil {
+		log.Printf("⚠️ invoice font %s: %v", path, err)
+		return nil
+	}
+	return face
+}
+
+func loadFonts() {
+	candidates := fontCandidates
+	if env := os.Getenv("INVOICE_FONT"); env != "" {
+		candidates = append([][2]string{{env, os.Getenv("INVOICE_FONT_BOLD")}}, candidates...)
+	}
+	for _, c := range candidates {
+		if regularFace = loadFace(c[0]); regularFace != nil {
+			boldFace = loadFace(c[1])
+			return
+		}
+	}
+	log.Println("⚠️ ไม่พบฟอนต์ภาษาไทยสำหรับใบเสร็จ (ตั้ง INVOICE_FONT) — ใช้ Helvetica แทน")
+}
+
+// renderer เก็บฟอนต์ของเอกสารหนึ่งฉบับ
+type renderer struct {
+	page      *pdf.Page
+	regular   pdf.Font
+	bold      pdf.Font
+	thai      bool
+	sizeScale float64
+}
+
+func newRenderer(doc *pdf.Document) *renderer {
+	fontOnce.Do(loadFonts)
+	r := &renderer{page: doc.AddPage(), regular: pdf.Helvetica, bold: pdf.HelveticaBold, sizeScale: 1}
+	if regularFace != nil {
+		r.regular = regularFace.Font()
+		r.bold = r.regular
+		if boldFace != nil {
+			r.bold = boldFace.Font()
+		}
+		r.thai = r.regular.Supports('ก')
+		// ฟอนต์ตระกูล Sarabun ตัวเล็กกว่าฟอนต์ทั่วไปที่ขนาดเดียวกัน
+		if r.regular.Width("0", 10) < 4.5 {
+			r.sizeScale = 1.35
+		}
+	}
+	return r
+}
+
+// t เลือกข้อความตามภาษาที่ฟอนต์รองรับ
+func (r *renderer) t(th, en string) string {
+	if r.thai {
+		return th
+	}
+	return en
+}
+
+func (r *renderer) text(bold bool, size, x, y float64, s string) {
+	f := r.regular
+	if bold {
+		f = r.bold
+	}
+	r.page.Text(f, size*r.sizeScale, x, y, s)
+}
+
+func (r *renderer) right(bold bool, size, x, y float64, s string) {
+	f := r.regular
+	if bold {
+		f = r.bold
+	}
+	r.page.TextRight(f, size*r.sizeScale, x, y, s)
+}
+
+// wrap เขียนหลายบรรทัด คืน y ของบรรทัดถัดไป
+func (r *renderer) wrap(size, x, y, width float64, s string) float64 {
+	for _, line := range pdf.WrapText(r.regular, size*r.sizeScale, width, s) {
+		r.page.Text(r.regular, size*r.sizeScale, x, y, line)
+		y += size * 1.45
+	}
+	return y
+}
+
+// money จัดรูปแบบ 1,234.50
+func money(v float64) string {
+	neg := v < 0
+	s := fmt.Sprintf("%.2f", math.Abs(v))
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	out := b.String() + frac
+	if neg {
+		return "-" + out
+	}
+	return out
+}
+
+// ✅ จำนวนเงินเป็นตัวอักษรภาษาไทย เช่น 121.50 → หนึ่งร้อยยี่สิบเอ็ดบาทห้าสิบสตางค์
+var (
+	thaiDigits = []string{"", "หนึ่ง", "สอง", "สาม", "สี่", "ห้า", "หก", "เจ็ด", "แปด", "เก้า"}
+	thaiPlaces = []string{"", "สิบ", "ร้อย", "พัน", "หมื่น", "แสน"}
+)
+
+// thaiNumber อ่านตัวเลข (higher = มีหลักที่สูงกว่านำหน้า ใช้ตัดสิน "เอ็ด")
+func thaiNumber(n int64, higher bool) string {
+	if n == 0 {
+		return ""
+	}
+	if n >= 1000000 {
+		return thaiNumber(n/1000000, higher) + "ล้าน" + thaiNumber(n%1000000, true)
+	}
+	var b strings.Builder
+	s := fmt.Sprint(n)
+	for i, c := range s {
+		d := int(c - '0')
+		place := len(s) - 1 - i
+		switch {
+		case d == 0:
+			continue
+		case place == 0 && d == 1 && (len(s) > 1 || higher):
+			b.WriteString("เอ็ด")
+		case place == 1 && d == 1:
+			b.WriteString("สิบ")
+		case place == 1 && d == 2:
+			b.WriteString("ยี่สิบ")
+		default:
+			b.WriteString(thaiDigits[d] + thaiPlaces[place])
+		}
+	}
+	return b.String()
+}
+
+// BahtText แปลงจำนวนเงินเป็นตัวอักษร สำหรับพิมพ์ใต้ยอดรวม
+func BahtText(v float64) string {
+	satang := int64(math.Round(math.Abs(v) * 100))
+	baht, st := satang/100, satang%100
+	words := thaiNumber(baht, false)
+	if words == "" {
+		words = "ศูนย์"
+	}
+	words += "บาท"
+	if st == 0 {
+		return words + "ถ้วน"
+	}
+	return words + thaiNumber(st, false) + "สตางค์"
+}
+
+// Render สร้าง PDF ของเอกสารจากข้อมูลที่บันทึกไว้
+func Render(inv *entity.Invoice, payment *entity.Payment) ([]byte, error) {
+	var lines []Line
+	if err := json.Unmarshal([]byte(inv.Lines), &lines); err != nil {
+		return nil, err
+	}
+
+	doc := pdf.New()
+	doc.Title = inv.Number
+	r := newRenderer(doc)
+	const left, rightEdge = 40.0, 555.0
+
+	// 🔹 หัวเอกสาร: ผู้ขาย (ซ้าย) + ชื่อเอกสาร (ขวา)
+	title := r.t("ใบเสร็จรับเงิน/ใบกำกับภาษีอย่างย่อ", "RECEIPT / ABBREVIATED TAX INVOICE")
+	if inv.Kind == entity.InvoiceTaxInvoice {
+		title = r.t("ใบกำกับภาษี/ใบเสร็จรับเงิน", "TAX INVOICE / RECEIPT")
+	}
+	r.right(true, 13, rightEdge, 58, title)
+	r.right(false, 8, rightEdge, 72, r.t("ต้นฉบับ", "ORIGINAL"))
+
+	y := 58.0
+	r.text(true, 13, left, y, inv.SellerName)
+	y = r.wrap(9, left, y+16, 280, inv.SellerAddress)
+	if inv.SellerTaxID != "" {
+		r.text(false, 9, left, y, fmt.Sprintf("%s %s  %s", r.t("เลขประจำตัวผู้เสียภาษี", "Tax ID"), inv.SellerTaxID, inv.SellerBranch))
+		y += 13
+	}
+
+	// 🔹 กล่องเลขที่/วันที่
+	boxY := 86.0
+	r.page.Rect(360, boxY, rightEdge-360, 62, 0.5, -1)
+	info := [][2]string{
+		{r.t("เลขที่", "No."), inv.Number},
+		{r.t("วันที่", "Date"), inv.IssuedAt.In(bangkok).Format("02/01/2006 15:04")},
+		{r.t("อ้างอิงการชำระ", "Payment ref."), strings.TrimSpace(fmt.Sprintf("#%d %s", inv.PaymentID, payment.ReferenceNumber))},
+	}
+	for i, row := range info {
+		ry := boxY + 16 + float64(i)*16
+		r.text(true, 9, 368, ry, row[0])
+		r.right(false, 9, rightEdge-8, ry, row[1])
+	}
+
+	// 🔹 ผู้ซื้อ
+	y = math.Max(y, boxY+62) + 14
+	r.text(true, 10, left, y, r.t("ลูกค้า", "Customer"))
+	y += 14
+	if inv.BuyerName != "" {
+		r.text(false, 9, left, y, inv.BuyerName)
+		y += 13
+	}
+	if inv.BuyerTaxID != "" {
+		r.text(false, 9, left, y, fmt.Sprintf("%s %s  %s", r.t("เลขประจำตัวผู้เสียภาษี", "Tax ID"), inv.BuyerTaxID, inv.BuyerBranch))
+		y += 13
+	}
+	if inv.BuyerAddress != "" {
+		y = r.wrap(9, left, y, 400, inv.BuyerAddress)
+	}
+
+	// 🔹 ตารางรายการ
+	y += 10
+	cols := []float64{left, 70, 410, 480, rightEdge}
+	r.page.Rect(left, y, rightEdge-left, 20, 0.5, 0.9)
+	hy := y + 13
+	r.text(true, 9, cols[0]+6, hy, "#")
+	r.text(true, 9, cols[1], hy, r.t("รายการ", "Description"))
+	r.right(true, 9, cols[2]-6, hy, r.t("จำนวน (kWh)", "Qty (kWh)"))
+	r.right(true, 9, cols[3]-6, hy, r.t("ราคา/หน่วย", "Unit price"))
+	r.right(true, 9, cols[4]-6, hy, r.t("จำนวนเงิน", "Amount"))
+	y += 20
+
+	for i, l := range lines {
+		desc := l.Description
+		switch {
+		case desc == refundLine:
+			desc = r.t("หักคืนเงิน", "Less: refund")
+		case desc == discountLine:
+			desc = r.t("ส่วนลดคูปอง", "Less: coupon discount")
+		case desc == "":
+			desc = r.t("ค่าบริการชาร์จรถยนต์ไฟฟ้า", "EV charging service")
+		default:
+			desc = r.t("ค่าบริการชาร์จรถยนต์ไฟฟ้า - ", "EV charging - ") + desc
+		}
+		rowLines := pdf.WrapText(r.regular, 9*r.sizeScale, cols[2]-cols[1]-60, desc)
+		rowH := 8 + float64(len(rowLines))*13
+		if y+rowH > 700 {
+			r.page = doc.AddPage()
+			y = 50
+		}
+		ry := y + 14
+		r.text(false, 9, cols[0]+6, ry, fmt.Sprint(i+1))
+		for j, dl := range rowLines {
+			r.text(false, 9, cols[1], ry+float64(j)*13, dl)
+		}
+		if l.Quantity > 0 {
+			r.right(false, 9, cols[2]-6, ry, fmt.Sprintf("%.3f", l.Quantity))
+			r.right(false, 9, cols[3]-6, ry, money(l.UnitPrice))
+		}
+		r.right(false, 9, cols[4]-6, ry, money(l.Amount))
+		y += rowH
+		r.page.Line(left, y, rightEdge, y, 0.25)
+	}
+
+	// 🔹 สรุปยอด (ราคารวม VAT แล้ว แยกภาษีออกมาแสดง)
+	y += 18
+	totals := [][2]string{
+		{r.t("มูลค่าก่อนภาษีมูลค่าเพิ่ม", "Amount before VAT"), money(inv.Subtotal)},
+		{fmt.Sprintf("%s %g%%", r.t("ภาษีมูลค่าเพิ่ม", "VAT"), math.Round(inv.VATRate*10000)/100), money(inv.VAT)},
+		{r.t("จำนวนเงินรวมทั้งสิ้น", "Grand total"), money(inv.Total)},
+	}
+	for i, row := range totals {
+		bold := i == len(totals)-1
+		r.text(bold, 10, 360, y, row[0])
+		r.right(bold, 10, rightEdge-6, y, row[1])
+		y += 16
+	}
+	r.page.Line(360, y-11, rightEdge, y-11, 0.5)
+	if r.thai {
+		r.text(false, 9, left, y-16, "("+BahtText(inv.Total)+")")
+	}
+
+	// 🔹 ท้ายเอกสาร
+	y += 10
+	r.text(false, 8, left, y, r.t("ราคาค่าบริการรวมภาษีมูลค่าเพิ่มแล้ว", "Prices include VAT."))
+	r.text(false, 8, left, y+12, r.t("เอกสารนี้ออกโดยระบบคอมพิวเตอร์", "This document was generated electronically."))
+	r.page.Line(400, 770, rightEdge, 770, 0.5)
+	r.right(false, 9, rightEdge-35, 784, r.t("ผู้รับเงิน", "Collector"))
+
+	return doc.Bytes()
+}
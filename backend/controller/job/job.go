@@ -0,0 +1,249 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/audit"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// ✅ ที่มาของการรัน
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrUnknownJob = errors.New("ไม่พบ job")
+	ErrRunning    = errors.New("job กำลังทำงานอยู่")
+)
+
+// Func คืนจำนวนรายการที่จัดการในรอบนั้น
+type Func func(db *gorm.DB) (int, error)
+
+// Job คือ background job ที่รันตาม Schedule (รูปแบบ cron เช่น "0 7 * * *" หรือ "@every 5m")
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string
+	Run         Func
+}
+
+type registered struct {
+	Job
+	entry   cron.EntryID
+	running bool
+}
+
+var (
+	mu        sync.Mutex
+	jobs      = map[string]*registered{}
+	scheduler = cron.New()
+)
+
+// Register เพิ่ม job เข้าตารางเวลา — เรียกตอนเริ่มระบบก่อน Start
+func Register(j Job) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := jobs[j.Name]; ok {
+		panic("job: duplicate job " + j.Name)
+	}
+	r := &registered{Job: j}
+	entry, err := scheduler.AddFunc(j.Schedule, func() {
+		if _, err := Run(j.Name, TriggerSchedule, nil); err != nil && !errors.Is(err, ErrRunning) {
+			fmt.Printf("❌ Job %s failed: %v\n", j.Name, err)
+		}
+	})
+	if err != nil {
+		panic("job: invalid schedule for " + j.Name + ": " + err.Error())
+	}
+	r.entry = entry
+	jobs[j.Name] = r
+}
+
+// Start เริ่มตารางเวลา — รอบที่ค้าง running จากการปิดระบบครั้งก่อนจะถูกปิดเป็น failed
+func Start() {
+	now := time.Now()
+	config.DB().Model(&entity.JobRun{}).Where("status = ?", entity.JobRunning).
+		Updates(map[string]interface{}{"status": entity.JobFailed, "ended_at": now, "error": "interrupted"})
+	scheduler.Start()
+}
+
+// Run รัน job ทันทีและบันทึกประวัติ — job เดียวกันรันซ้อนกันไม่ได้
+func Run(name, trigger string, actorID *uint) (*entity.JobRun, error) {
+	mu.Lock()
+	r, ok := jobs[name]
+	if !ok {
+		mu.Unlock()
+		return nil, ErrUnknownJob
+	}
+	if r.running {
+		mu.Unlock()
+		return nil, ErrRunning
+	}
+	r.running = true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		r.running = false
+		mu.Unlock()
+	}()
+
+	db := config.DB()
+	run := entity.JobRun{
+		Job:         name,
+		Trigger:     trigger,
+		TriggeredBy: actorID,
+		Status:      entity.JobRunning,
+		StartedAt:   time.Now(),
+	}
+	if err := db.Create(&run).Error; err != nil {
+		return nil, err
+	}
+
+	items, err := safeRun(r.Run, db)
+
+	ended := time.Now()
+	run.EndedAt = &ended
+	run.DurationMs = ended.Sub(run.StartedAt).Milliseconds()
+	run.Items = items
+	run.Status = entity.JobSucceeded
+	if err != nil {
+		run.Status = entity.JobFailed
+		run.Error = err.Error()
+	}
+	if serr := db.Save(&run).Error; serr != nil {
+		return &run, serr
+	}
+	return &run, err
+}
+
+// RunRetention คืออายุของประวัติ JobRun ก่อนถูกลบ (expire-sessions รันทุกนาที ประวัติจึงโตเร็ว)
+const RunRetention = 14 * 24 * time.Hour
+
+// PurgeRuns ลบประวัติ JobRun ที่จบไปนานกว่า RunRetention (รอบที่ยัง running ไม่ลบ)
+func PurgeRuns(db *gorm.DB) (int, error) {
+	res := db.Where("status <> ? AND started_at < ?", entity.JobRunning, time.Now().Add(-RunRetention)).
+		Delete(&entity.JobRun{})
+	return int(res.RowsAffected), res.Error
+}
+
+// ✅ panic ใน job ไม่ทำให้ server ล่ม — บันทึกเป็น error ของรอบนั้น
+func safeRun(fn Func, db *gorm.DB) (items int, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn(db)
+}
+
+// ============================================================================
+// 🔹 Handlers สำหรับ admin
+// ============================================================================
+
+type jobInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Schedule    string         `json:"schedule"`
+	Running     bool           `json:"running"`
+	NextRun     *time.Time     `json:"next_run"`
+	LastRun     *entity.JobRun `json:"last_run"`
+}
+
+// GET /jobs
+func ListJobs(c *gin.Context) {
+	db := config.DB()
+
+	mu.Lock()
+	list := make([]jobInfo, 0, len(jobs))
+	for _, r := range jobs {
+		info := jobInfo{Name: r.Name, Description: r.Description, Schedule: r.Schedule, Running: r.running}
+		if next := scheduler.Entry(r.entry).Next; !next.IsZero() {
+			info.NextRun = &next
+		}
+		list = append(list, info)
+	}
+	mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	for i := range list {
+		var last entity.JobRun
+		if err := db.Where("job = ?", list[i].Name).Order("id DESC").First(&last).Error; err == nil {
+			list[i].LastRun = &last
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GET /jobs/:name/runs?limit=50
+func ListJobRuns(c *gin.Context) {
+	name := c.Param("name")
+	mu.Lock()
+	_, ok := jobs[name]
+	mu.Unlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrUnknownJob.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	var runs []entity.JobRun
+	if err := config.DB().Where("job = ?", name).Order("id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// POST /jobs/:name/run — admin สั่งรันทันที (รอจนเสร็จ) ผู้สั่งมาจาก JWT
+func TriggerJob(c *gin.Context) {
+	var actorID *uint
+	if v, ok := c.Get("UserID"); ok {
+		if id, ok := v.(int); ok && id > 0 {
+			uid := uint(id)
+			actorID = &uid
+		}
+	}
+	if actorID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+		return
+	}
+
+	run, err := Run(c.Param("name"), TriggerManual, actorID)
+	switch {
+	case errors.Is(err, ErrUnknownJob):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case run == nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	audit.Record(config.DB(), "job.triggered", "JobRun", run.ID, actorID, gin.H{
+		"job":    run.Job,
+		"status": run.Status,
+		"items":  run.Items,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "data": run})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
@@ -1,6 +1,7 @@
 package notify
 
 import (
+	"errors"
 	"fmt"
 	"net/smtp"
 	"time"
@@ -8,20 +9,42 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/Tawunchai/work-project/config"
 	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
 )
 
+// ErrNoSender เมื่อยังไม่ได้ตั้งค่าอีเมลผู้ส่ง
+var ErrNoSender = errors.New("ไม่พบข้อมูล Email สำหรับส่งแจ้งเตือน")
+
 // ✅ ฟังก์ชันส่งอีเมลแจ้งเตือน
 func SendBookingReminder(c *gin.Context) {
-	db := config.DB()
-
-	var sender entity.SendEmail
-	if err := db.First(&sender).Error; err != nil {
+	sent, err := SendTodayReminders(config.DB())
+	switch {
+	case err != nil:
+		if c != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+		} else {
+			fmt.Println("❌", err)
+		}
+	case sent == 0:
 		if c != nil {
-			c.JSON(500, gin.H{"error": "ไม่พบข้อมูล Email สำหรับส่งแจ้งเตือน"})
+			c.JSON(200, gin.H{"message": "ไม่มีการจองในวันนี้"})
+		} else {
+			fmt.Println("ℹ️ ไม่มีการจองในวันนี้")
+		}
+	default:
+		if c != nil {
+			c.JSON(200, gin.H{"message": "✅ ส่งอีเมลแจ้งเตือนสำเร็จ", "sent": sent})
 		} else {
-			fmt.Println("❌ ไม่พบข้อมูล Email สำหรับส่งแจ้งเตือน")
+			fmt.Println("✅ ส่งอีเมลแจ้งเตือนสำเร็จ (จาก Cron Job)")
 		}
-		return
+	}
+}
+
+// SendTodayReminders ส่งอีเมลแจ้งเตือน Booking ของวันนี้ที่ยังไม่ได้ส่ง — คืนจำนวนที่ส่งสำเร็จ
+func SendTodayReminders(db *gorm.DB) (int, error) {
+	var sender entity.SendEmail
+	if err := db.First(&sender).Error; err != nil {
+		return 0, ErrNoSender
 	}
 
 	today := time.Now().Format("2006-01-02")
@@ -29,23 +52,10 @@ func SendBookingReminder(c *gin.Context) {
 	if err := db.Preload("User").Preload("EVCabinet").
 		Where("DATE(start_date) = ? AND is_email_sent = ?", today, false).
 		Find(&bookings).Error; err != nil {
-		if c != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
-		} else {
-			fmt.Println("❌ Database error:", err)
-		}
-		return
-	}
-
-	if len(bookings) == 0 {
-		if c != nil {
-			c.JSON(200, gin.H{"message": "ไม่มีการจองในวันนี้"})
-		} else {
-			fmt.Println("ℹ️ ไม่มีการจองในวันนี้")
-		}
-		return
+		return 0, err
 	}
 
+	sent := 0
 	for _, b := range bookings {
 		if b.User.Email == "" {
 			continue
@@ -70,17 +80,13 @@ func SendBookingReminder(c *gin.Context) {
 		err := sendEmailWithAppPassword(sender.Email, sender.PassApp, b.User.Email, subject, body)
 		if err == nil {
 			db.Model(&b).Update("is_email_sent", true)
+			sent++
 			fmt.Printf("✅ ส่งอีเมลถึง %s สำเร็จ → IsEmailSent = true\n", b.User.Email)
 		} else {
 			fmt.Printf("❌ ส่งอีเมลถึง %s ไม่สำเร็จ: %v\n", b.User.Email, err)
 		}
 	}
-
-	if c != nil {
-		c.JSON(200, gin.H{"message": "✅ ส่งอีเมลแจ้งเตือนสำเร็จ"})
-	} else {
-		fmt.Println("✅ ส่งอีเมลแจ้งเตือนสำเร็จ (จาก Cron Job)")
-	}
+	return sent, nil
 }
 
 // ✅ ฟังก์ชันส่งอีเมลด้วย Gmail App Password
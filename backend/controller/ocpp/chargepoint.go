@@ -0,0 +1,96 @@
+package ocpp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm/clause"
+)
+
+type bootNotificationRequest struct {
+	ChargePointVendor       string `json:"chargePointVendor"`
+	ChargePointModel        string `json:"chargePointModel"`
+	ChargePointSerialNumber string `json:"chargePointSerialNumber"`
+	ChargeBoxSerialNumber   string `json:"chargeBoxSerialNumber"`
+	FirmwareVersion         string `json:"firmwareVersion"`
+	MeterType               string `json:"meterType"`
+}
+
+// ResetChargePointsOnline ตั้งทุก ChargePoint เป็น offline ตอนเปิด server
+// (connection เดิมหายไปหมดแล้วหลัง restart)
+func ResetChargePointsOnline() {
+	config.DB().Model(&entity.ChargePoint{}).
+		Where("online = ?", true).
+		Update("online", false)
+}
+
+// ✅ charger ต่อเข้ามา → สร้างหรืออัปเดต ChargePoint เป็น online
+func markChargePointOnline(chargerID string) {
+	now := time.Now()
+	cp := entity.ChargePoint{ChargerID: chargerID, Online: true, LastSeenAt: &now}
+	if err := config.DB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "charger_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"online": true, "last_seen_at": now, "deleted_at": nil}),
+	}).Create(&cp).Error; err != nil {
+		fmt.Println("❌ Cannot save charge point:", err)
+		return
+	}
+	syncCabinetStatusOfCharger(chargerID)
+}
+
+func markChargePointOffline(chargerID string) {
+	// 🔸 ถ้า charger ต่อใหม่ด้วย ID เดิมแล้ว ไม่ต้องตั้ง offline
+	if IsConnected(chargerID) {
+		return
+	}
+	config.DB().Model(&entity.ChargePoint{}).
+		Where("charger_id = ?", chargerID).
+		Updates(map[string]interface{}{"online": false, "last_seen_at": time.Now()})
+	syncCabinetStatusOfCharger(chargerID)
+}
+
+func touchChargePoint(chargerID string) {
+	config.DB().Model(&entity.ChargePoint{}).
+		Where("charger_id = ?", chargerID).
+		Update("last_seen_at", time.Now())
+}
+
+// ✅ บันทึกข้อมูล hardware จาก BootNotification
+func saveBootNotification(chargerID string, req bootNotificationRequest) {
+	serial := req.ChargePointSerialNumber
+	if serial == "" {
+		serial = req.ChargeBoxSerialNumber
+	}
+	now := time.Now()
+	if err := config.DB().Model(&entity.ChargePoint{}).
+		Where("charger_id = ?", chargerID).
+		Updates(map[string]interface{}{
+			"vendor":           req.ChargePointVendor,
+			"charger_model":    req.ChargePointModel,
+			"serial_number":    serial,
+			"firmware_version": req.FirmwareVersion,
+			"meter_type":       req.MeterType,
+			"last_boot_at":     now,
+			"online":           true,
+			"last_seen_at":     now,
+		}).Error; err != nil {
+		fmt.Println("❌ Cannot save BootNotification:", err)
+	}
+}
+
+// ChargerIDsForCabinet คืน chargerID ที่ผูกกับตู้นี้ (ตัวที่ online มาก่อน)
+func ChargerIDsForCabinet(cabinetID uint) []string {
+	var points []entity.ChargePoint
+	config.DB().
+		Where("ev_cabinet_id = ?", cabinetID).
+		Order("online DESC, last_seen_at DESC").
+		Find(&points)
+
+	ids := make([]string, 0, len(points))
+	for _, p := range points {
+		ids = append(ids, p.ChargerID)
+	}
+	return ids
+}
@@ -0,0 +1,223 @@
+package ocpp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
+)
+
+// ✅ ChargePointStatus ตาม OCPP 1.6
+const (
+	StatusAvailable     = "Available"
+	StatusPreparing     = "Preparing"
+	StatusCharging      = "Charging"
+	StatusSuspendedEVSE = "SuspendedEVSE"
+	StatusSuspendedEV   = "SuspendedEV"
+	StatusFinishing     = "Finishing"
+	StatusReserved      = "Reserved"
+	StatusUnavailable   = "Unavailable"
+	StatusFaulted       = "Faulted"
+)
+
+// ✅ ตาราง transition ที่อนุญาต (OCPP 1.6 ข้อ 4.9)
+var allowedTransitions = map[string][]string{
+	StatusAvailable:     {StatusPreparing, StatusCharging, StatusSuspendedEV, StatusSuspendedEVSE, StatusReserved, StatusUnavailable, StatusFaulted},
+	StatusPreparing:     {StatusAvailable, StatusCharging, StatusSuspendedEV, StatusSuspendedEVSE, StatusFinishing, StatusFaulted},
+	StatusCharging:      {StatusAvailable, StatusSuspendedEV, StatusSuspendedEVSE, StatusFinishing, StatusUnavailable, StatusFaulted},
+	StatusSuspendedEV:   {StatusAvailable, StatusCharging, StatusSuspendedEVSE, StatusFinishing, StatusUnavailable, StatusFaulted},
+	StatusSuspendedEVSE: {StatusAvailable, StatusCharging, StatusSuspendedEV, StatusFinishing, StatusUnavailable, StatusFaulted},
+	StatusFinishing:     {StatusAvailable, StatusPreparing, StatusUnavailable, StatusFaulted},
+	StatusReserved:      {StatusAvailable, StatusPreparing, StatusUnavailable, StatusFaulted},
+	StatusUnavailable:   {StatusAvailable, StatusPreparing, StatusCharging, StatusSuspendedEV, StatusSuspendedEVSE, StatusFaulted},
+	StatusFaulted:       {StatusAvailable, StatusPreparing, StatusCharging, StatusSuspendedEV, StatusSuspendedEVSE, StatusFinishing, StatusReserved, StatusUnavailable},
+}
+
+// IsValidConnectorStatus ตรวจว่าเป็นสถานะที่ OCPP 1.6 รู้จัก
+func IsValidConnectorStatus(status string) bool {
+	_, ok := allowedTransitions[status]
+	return ok
+}
+
+// IsValidTransition ตรวจว่าเปลี่ยนจาก from → to ได้ตาม OCPP 1.6
+// from ว่าง (ยังไม่เคยรายงาน) หรือสถานะเดิมซ้ำ (เช่น errorCode เปลี่ยน) ถือว่าผ่าน
+func IsValidTransition(from, to string) bool {
+	if !IsValidConnectorStatus(to) {
+		return false
+	}
+	if from == "" || from == to {
+		return true
+	}
+	for _, s := range allowedTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 🔹 StatusNotification → อัปเดต Connector + บันทึกประวัติ + sync สถานะตู้
+// ============================================================================
+func applyStatusNotification(chargerID string, req statusNotificationRequest) error {
+	if !IsValidConnectorStatus(req.Status) {
+		return &CallError{Code: ErrFormationViolation, Description: "unknown status " + req.Status}
+	}
+
+	reportedAt := time.Now()
+	if req.Timestamp != nil && !req.Timestamp.IsZero() {
+		reportedAt = *req.Timestamp
+	}
+
+	db := config.DB()
+	var point entity.ChargePoint
+	if err := db.Where("charger_id = ?", chargerID).First(&point).Error; err != nil {
+		return &CallError{Code: ErrInternalError, Description: "unknown charge point"}
+	}
+
+	var connector entity.Connector
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("charge_point_id = ? AND connector_no = ?", point.ID, req.ConnectorID).
+			Attrs(entity.Connector{ChargePointID: point.ID, ConnectorNo: req.ConnectorID}).
+			FirstOrCreate(&connector).Error; err != nil {
+			return err
+		}
+
+		from := connector.Status
+		valid := IsValidTransition(from, req.Status)
+		if !valid {
+			// hardware คือความจริง → ยังบันทึกสถานะใหม่ แต่ flag ไว้ใน history
+			fmt.Printf("⚠️ Invalid transition %s connector %d: %s → %s\n", chargerID, req.ConnectorID, from, req.Status)
+		}
+
+		history := entity.ConnectorStatusHistory{
+			ConnectorID: connector.ID,
+			FromStatus:  from,
+			ToStatus:    req.Status,
+			ErrorCode:   req.ErrorCode,
+			Info:        req.Info,
+			Valid:       valid,
+			ReportedAt:  reportedAt,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		connector.Status = req.Status
+		connector.ErrorCode = req.ErrorCode
+		connector.Info = req.Info
+		connector.StatusUpdatedAt = &reportedAt
+		return tx.Save(&connector).Error
+	})
+	if err != nil {
+		return &CallError{Code: ErrInternalError, Description: "cannot save connector status"}
+	}
+
+	syncEVchargingStatus(connector)
+	syncCabinetStatus(point.EVCabinetID)
+	syncSessionStatus(chargerID, req)
+	return nil
+}
+
+// ✅ หยุดจ่ายไฟชั่วคราว / กลับมาจ่ายไฟ → อัปเดตสถานะ session ของ transaction ที่เปิดอยู่
+func syncSessionStatus(chargerID string, req statusNotificationRequest) {
+	var to string
+	switch req.Status {
+	case StatusSuspendedEV, StatusSuspendedEVSE:
+		to = entity.SessionSuspended
+	case StatusCharging:
+		to = entity.SessionCharging
+	default:
+		return
+	}
+
+	db := config.DB()
+	var tx entity.ChargingTransaction
+	if err := db.Where("charger_id = ? AND connector_id = ? AND stopped_at IS NULL AND charging_session_id IS NOT NULL",
+		chargerID, req.ConnectorID).Order("id DESC").First(&tx).Error; err != nil {
+		return
+	}
+	reason := fmt.Sprintf("StatusNotification %s connector %d", req.Status, req.ConnectorID)
+	if _, err := session.MoveByID(db, *tx.ChargingSessionID, to, reason, session.SourceOCPP); err != nil {
+		fmt.Println("⚠️ Cannot update session status:", err)
+	}
+}
+
+// ✅ EVcharging.StatusID ตามหัวชาร์จที่ผูกไว้ (Available เท่านั้นที่ถือว่าว่าง)
+func syncEVchargingStatus(connector entity.Connector) {
+	if connector.EVchargingID == nil {
+		return
+	}
+
+	name := "Unavailable"
+	if connector.Status == StatusAvailable {
+		name = "Available"
+	}
+
+	db := config.DB()
+	var status entity.Status
+	if err := db.Where("status = ?", name).First(&status).Error; err != nil {
+		return
+	}
+	db.Model(&entity.EVcharging{}).
+		Where("id = ?", *connector.EVchargingID).
+		Update("status_id", status.ID)
+}
+
+// ✅ EVCabinet.Status = Active ถ้ามีหัวชาร์จที่ใช้งานได้อย่างน้อยหนึ่งหัว
+// ตู้ที่ admin ตั้งเป็น Maintenance จะไม่ถูกเปลี่ยน
+func syncCabinetStatus(cabinetID *uint) {
+	if cabinetID == nil {
+		return
+	}
+
+	db := config.DB()
+	var cabinet entity.EVCabinet
+	if err := db.Preload("ChargePoints.Connectors").First(&cabinet, *cabinetID).Error; err != nil {
+		return
+	}
+	if cabinet.Status == "Maintenance" || len(cabinet.ChargePoints) == 0 {
+		return
+	}
+
+	usable := func(s string) bool { return s != StatusFaulted && s != StatusUnavailable }
+
+	status := "Inactive"
+	for _, point := range cabinet.ChargePoints {
+		if !point.Online {
+			continue
+		}
+		// connectorId 0 คือสถานะของทั้งเครื่อง
+		wholeUnit := true
+		for _, conn := range point.Connectors {
+			if conn.ConnectorNo == 0 && !usable(conn.Status) {
+				wholeUnit = false
+			}
+		}
+		if !wholeUnit {
+			continue
+		}
+		for _, conn := range point.Connectors {
+			if conn.ConnectorNo != 0 && usable(conn.Status) {
+				status = "Active"
+			}
+		}
+	}
+
+	if cabinet.Status != status {
+		db.Model(&cabinet).Update("status", status)
+	}
+}
+
+// ✅ sync สถานะตู้เมื่อ charger online/offline
+func syncCabinetStatusOfCharger(chargerID string) {
+	var point entity.ChargePoint
+	if err := config.DB().Where("charger_id = ?", chargerID).First(&point).Error; err != nil {
+		return
+	}
+	syncCabinetStatus(point.EVCabinetID)
+}
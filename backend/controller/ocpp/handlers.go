@@ -0,0 +1,348 @@
+package ocpp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/billing"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/controller/tokensign"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/chargetoken"
+	"gorm.io/gorm"
+)
+
+// ✅ idTag คือ token การชาร์จ (ใช้เริ่มชาร์จได้) — log แค่ hash สั้น ๆ ไว้เทียบกันเท่านั้น
+func idTagRef(idTag string) string {
+	sum := sha256.Sum256([]byte(idTag))
+	return "#" + hex.EncodeToString(sum[:4])
+}
+
+// ✅ OCPP-J message types
+const (
+	callMessage       = 2
+	callResultMessage = 3
+	callErrorMessage  = 4
+)
+
+// ✅ OCPP 1.6 error codes ที่ใช้ใน CALLERROR
+const (
+	ErrNotImplemented     = "NotImplemented"
+	ErrFormationViolation = "FormationViolation"
+	ErrInternalError      = "InternalError"
+)
+
+// CallError คือ error ที่จะถูกส่งกลับเป็น CALLERROR
+type CallError struct {
+	Code        string
+	Description string
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// ✅ idTagInfo.status ตาม OCPP 1.6
+const (
+	AuthAccepted     = "Accepted"
+	AuthBlocked      = "Blocked"
+	AuthExpired      = "Expired"
+	AuthInvalid      = "Invalid"
+	AuthConcurrentTx = "ConcurrentTx"
+)
+
+type idTagInfo struct {
+	Status     string     `json:"status"`
+	ExpiryDate *time.Time `json:"expiryDate,omitempty"`
+}
+
+type authorizeRequest struct {
+	IdTag string `json:"idTag"`
+}
+
+type startTransactionRequest struct {
+	ConnectorID int       `json:"connectorId"`
+	IdTag       string    `json:"idTag"`
+	MeterStart  int       `json:"meterStart"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type stopTransactionRequest struct {
+	TransactionID int       `json:"transactionId"`
+	IdTag         string    `json:"idTag"`
+	MeterStop     int       `json:"meterStop"`
+	Timestamp     time.Time `json:"timestamp"`
+	Reason        string    `json:"reason"`
+
+	TransactionData []meterValue `json:"transactionData"`
+}
+
+type statusNotificationRequest struct {
+	ConnectorID int        `json:"connectorId"`
+	ErrorCode   string     `json:"errorCode"`
+	Status      string     `json:"status"`
+	Timestamp   *time.Time `json:"timestamp"`
+	Info        string     `json:"info"`
+}
+
+// ============================================================================
+// 🔹 แยกประมวลผล CALL ตาม action
+// ============================================================================
+func handleCall(chargerID, action string, payload json.RawMessage) (interface{}, error) {
+	switch action {
+	case "BootNotification":
+		var req bootNotificationRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		saveBootNotification(chargerID, req)
+		fmt.Println("✅ BootNotification Accepted:", req.ChargePointVendor, req.ChargePointModel)
+		return map[string]interface{}{
+			"status":      "Accepted",
+			"currentTime": time.Now().UTC().Format(time.RFC3339),
+			"interval":    30,
+		}, nil
+
+	case "Heartbeat":
+		return map[string]interface{}{
+			"currentTime": time.Now().UTC().Format(time.RFC3339),
+		}, nil
+
+	case "Authorize":
+		var req authorizeRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		info, cs := authorizeIdTag(req.IdTag)
+		if info.Status == AuthAccepted && cs != nil {
+			if _, err := session.Move(config.DB(), cs, entity.SessionAuthorized, "Authorize "+chargerID, session.SourceOCPP); err != nil {
+				fmt.Println("⚠️ Cannot update session status:", err)
+			}
+		}
+		fmt.Println("🔑 Authorize", idTagRef(req.IdTag), "→", info.Status)
+		return map[string]interface{}{"idTagInfo": info}, nil
+
+	case "StartTransaction":
+		var req startTransactionRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		return handleStartTransaction(chargerID, req)
+
+	case "StopTransaction":
+		var req stopTransactionRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		return handleStopTransaction(chargerID, req)
+
+	case "StatusNotification":
+		var req statusNotificationRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		fmt.Printf("🔌 StatusNotification %s connector %d → %s (%s)\n", chargerID, req.ConnectorID, req.Status, req.ErrorCode)
+		if err := applyStatusNotification(chargerID, req); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{}, nil
+
+	case "MeterValues":
+		var req meterValuesRequest
+		if err := decodePayload(payload, &req); err != nil {
+			return nil, err
+		}
+		if err := handleMeterValues(chargerID, req); err != nil {
+			return nil, err
+		}
+		fmt.Println("📊 MeterValues Received and Stored")
+		return map[string]interface{}{}, nil
+
+	default:
+		fmt.Println("ℹ️ Unknown OCPP Action:", action)
+		return nil, &CallError{Code: ErrNotImplemented, Description: "action " + action + " is not supported"}
+	}
+}
+
+func decodePayload(payload json.RawMessage, v interface{}) error {
+	if len(payload) == 0 {
+		return &CallError{Code: ErrFormationViolation, Description: "missing payload"}
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return &CallError{Code: ErrFormationViolation, Description: err.Error()}
+	}
+	return nil
+}
+
+// ✅ idTag = ChargingSession.Token ที่ออกให้หลังชำระเงิน
+func authorizeIdTag(idTag string) (idTagInfo, *entity.ChargingSession) {
+	db := config.DB()
+
+	// 🔸 token แบบลงชื่อ → ตรวจลายเซ็นและ denylist ก่อนค้นฐานข้อมูล
+	var revoked bool
+	if chargetoken.IsSigned(idTag) {
+		_, err := tokensign.Verify(db, idTag)
+		switch {
+		case errors.Is(err, tokensign.ErrRevoked):
+			revoked = true
+		case err != nil && !errors.Is(err, chargetoken.ErrExpired):
+			return idTagInfo{Status: AuthInvalid}, nil
+		}
+	}
+
+	var cs entity.ChargingSession
+	if err := db.Where("token = ?", idTag).First(&cs).Error; err != nil {
+		return idTagInfo{Status: AuthInvalid}, nil
+	}
+	if err := session.ExpireIfDue(db, &cs); err != nil {
+		return idTagInfo{Status: AuthExpired}, &cs
+	}
+	if revoked || !entity.IsSessionActive(cs.Status) {
+		return idTagInfo{Status: AuthBlocked}, &cs
+	}
+	expiry := cs.ExpiresAt
+	return idTagInfo{Status: AuthAccepted, ExpiryDate: &expiry}, &cs
+}
+
+// ============================================================================
+// 🔹 StartTransaction — สร้าง ChargingTransaction ผูกกับ Session และ Payment
+// ============================================================================
+func handleStartTransaction(chargerID string, req startTransactionRequest) (interface{}, error) {
+	db := config.DB()
+	info, cs := authorizeIdTag(req.IdTag)
+
+	// ❌ idTag เดียวกันมี transaction ที่ยังไม่จบอยู่แล้ว
+	if info.Status == AuthAccepted {
+		var open int64
+		db.Model(&entity.ChargingTransaction{}).
+			Where("id_tag = ? AND stopped_at IS NULL", req.IdTag).
+			Count(&open)
+		if open > 0 {
+			info = idTagInfo{Status: AuthConcurrentTx}
+		}
+	}
+
+	startedAt := req.Timestamp
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
+	tx := entity.ChargingTransaction{
+		ChargerID:   chargerID,
+		ConnectorID: req.ConnectorID,
+		IdTag:       req.IdTag,
+		MeterStart:  req.MeterStart,
+		StartedAt:   startedAt,
+	}
+	if cs != nil {
+		sessionID := cs.ID
+		paymentID := cs.PaymentID
+		tx.ChargingSessionID = &sessionID
+		tx.PaymentID = &paymentID
+	}
+
+	// 🔸 บันทึกทุกครั้ง (แม้ไม่ Accepted) เพราะ charger ถือ transactionId นี้ไปแล้ว
+	err := db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Create(&tx).Error; err != nil {
+			return err
+		}
+		tx.TransactionID = int(tx.ID)
+		return dbTx.Model(&tx).Update("transaction_id", tx.TransactionID).Error
+	})
+	if err != nil {
+		return nil, &CallError{Code: ErrInternalError, Description: "cannot create transaction"}
+	}
+
+	if info.Status != AuthAccepted {
+		now := time.Now()
+		db.Model(&tx).Updates(map[string]interface{}{
+			"stopped_at":  now,
+			"meter_stop":  req.MeterStart,
+			"stop_reason": "DeAuthorized",
+		})
+	} else if cs != nil {
+		reason := fmt.Sprintf("StartTransaction %s #%d", chargerID, tx.TransactionID)
+		if _, err := session.Move(db, cs, entity.SessionCharging, reason, session.SourceOCPP); err != nil {
+			fmt.Println("⚠️ Cannot update session status:", err)
+		}
+	}
+
+	fmt.Printf("⚡ StartTransaction %s #%d idTag=%s → %s\n", chargerID, tx.TransactionID, idTagRef(req.IdTag), info.Status)
+
+	return map[string]interface{}{
+		"transactionId": tx.TransactionID,
+		"idTagInfo":     info,
+	}, nil
+}
+
+// ============================================================================
+// 🔹 StopTransaction — ปิด transaction และ session
+// ============================================================================
+func handleStopTransaction(chargerID string, req stopTransactionRequest) (interface{}, error) {
+	db := config.DB()
+
+	var tx entity.ChargingTransaction
+	if err := db.Where("transaction_id = ? AND charger_id = ?", req.TransactionID, chargerID).First(&tx).Error; err != nil {
+		// ตาม OCPP ต้องตอบรับเสมอ ไม่งั้น charger จะส่งซ้ำไปเรื่อยๆ
+		fmt.Println("⚠️ StopTransaction for unknown transaction:", req.TransactionID)
+		return map[string]interface{}{}, nil
+	}
+
+	stoppedAt := req.Timestamp
+	if stoppedAt.IsZero() {
+		stoppedAt = time.Now()
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "Local"
+	}
+
+	meterStop := req.MeterStop
+	tx.MeterStop = &meterStop
+	tx.StoppedAt = &stoppedAt
+	tx.StopReason = reason
+
+	err := db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Save(&tx).Error; err != nil {
+			return err
+		}
+		if tx.ChargingSessionID != nil {
+			_, err := session.MoveByID(dbTx, *tx.ChargingSessionID, entity.SessionCompleted,
+				"StopTransaction: "+reason, session.SourceOCPP)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &CallError{Code: ErrInternalError, Description: "cannot stop transaction"}
+	}
+
+	if len(req.TransactionData) > 0 {
+		if err := saveMeterValues(chargerID, tx.ConnectorID, &tx.ID, req.TransactionData); err != nil {
+			fmt.Println("❌ Cannot save transactionData:", err)
+		}
+	}
+
+	// 🔸 คิดเงินตามพลังงานที่จ่ายจริง → สร้าง EVChargingPayment
+	if tx.PaymentID != nil {
+		if _, err := billing.BillTransaction(tx.ID); err != nil {
+			fmt.Println("⚠️ Cannot bill transaction:", tx.TransactionID, err)
+		}
+	}
+	// 🔸 แจ้ง stream อีกครั้งหลังคิดเงินแล้ว (ยอดสุดท้ายเป็นยอดจริง)
+	if tx.ChargingSessionID != nil {
+		session.Notify(*tx.ChargingSessionID)
+	}
+
+	fmt.Printf("🛑 StopTransaction %s #%d meter %d→%d Wh (%s)\n", chargerID, tx.TransactionID, tx.MeterStart, meterStop, reason)
+
+	if req.IdTag == "" {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{"idTagInfo": idTagInfo{Status: AuthAccepted}}, nil
+}
@@ -0,0 +1,286 @@
+package ocpp
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ ค่า default ของ sampledValue ตาม OCPP 1.6
+const (
+	MeasurandEnergyImport = "Energy.Active.Import.Register"
+	MeasurandPowerImport  = "Power.Active.Import"
+	MeasurandSoC          = "SoC"
+)
+
+type sampledValue struct {
+	Value     string `json:"value"`
+	Context   string `json:"context"`
+	Format    string `json:"format"`
+	Measurand string `json:"measurand"`
+	Phase     string `json:"phase"`
+	Location  string `json:"location"`
+	Unit      string `json:"unit"`
+}
+
+type meterValue struct {
+	Timestamp    time.Time      `json:"timestamp"`
+	SampledValue []sampledValue `json:"sampledValue"`
+}
+
+type meterValuesRequest struct {
+	ConnectorID   int          `json:"connectorId"`
+	TransactionID *int         `json:"transactionId"`
+	MeterValue    []meterValue `json:"meterValue"`
+}
+
+// ============================================================================
+// 🔹 บันทึก MeterValues ลงฐานข้อมูล (ผูกกับ ChargingTransaction ถ้าหาเจอ)
+// ============================================================================
+func saveMeterValues(chargerID string, connectorID int, txID *uint, values []meterValue) error {
+	rows := make([]entity.MeterValue, 0)
+	for _, mv := range values {
+		ts := mv.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		for _, sv := range mv.SampledValue {
+			// SignedData เป็นข้อมูลเข้ารหัส ไม่ใช่ตัวเลข
+			if sv.Format == "SignedData" {
+				continue
+			}
+			row := entity.MeterValue{
+				ChargerID:             chargerID,
+				ConnectorID:           connectorID,
+				Timestamp:             ts,
+				Measurand:             defaultString(sv.Measurand, MeasurandEnergyImport),
+				Phase:                 sv.Phase,
+				Unit:                  sv.Unit,
+				Context:               defaultString(sv.Context, "Sample.Periodic"),
+				Location:              defaultString(sv.Location, "Outlet"),
+				RawValue:              sv.Value,
+				ChargingTransactionID: txID,
+			}
+			if row.Unit == "" && row.Measurand == MeasurandEnergyImport {
+				row.Unit = "Wh"
+			}
+			if v, err := strconv.ParseFloat(sv.Value, 64); err == nil {
+				row.Value = v
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return config.DB().CreateInBatches(&rows, 100).Error
+}
+
+func defaultString(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// ✅ หา ChargingTransaction ที่ MeterValues นี้เป็นของ
+func findTransactionForMeterValues(chargerID string, req meterValuesRequest) *uint {
+	db := config.DB()
+	var tx entity.ChargingTransaction
+
+	if req.TransactionID != nil {
+		if err := db.Where("transaction_id = ? AND charger_id = ?", *req.TransactionID, chargerID).First(&tx).Error; err == nil {
+			return &tx.ID
+		}
+		return nil
+	}
+	if req.ConnectorID == 0 {
+		return nil
+	}
+	if err := db.Where("charger_id = ? AND connector_id = ? AND stopped_at IS NULL", chargerID, req.ConnectorID).
+		Order("started_at DESC").
+		First(&tx).Error; err == nil {
+		return &tx.ID
+	}
+	return nil
+}
+
+func handleMeterValues(chargerID string, req meterValuesRequest) error {
+	txID := findTransactionForMeterValues(chargerID, req)
+	if err := saveMeterValues(chargerID, req.ConnectorID, txID, req.MeterValue); err != nil {
+		fmt.Println("❌ Cannot save MeterValues:", err)
+		return &CallError{Code: ErrInternalError, Description: "cannot save meter values"}
+	}
+	// 🔸 แจ้ง stream ความคืบหน้าของ session ที่กำลังชาร์จ
+	if txID != nil {
+		var tx entity.ChargingTransaction
+		if err := config.DB().Select("charging_session_id").First(&tx, *txID).Error; err == nil && tx.ChargingSessionID != nil {
+			session.Notify(*tx.ChargingSessionID)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// 🔹 Query API — downsample เป็นช่วงเวลาเพื่อวาดกราฟ
+// ============================================================================
+
+// MeterBucket คือค่าสรุปของ sample ในช่วงเวลาหนึ่ง
+type MeterBucket struct {
+	Start time.Time `json:"start"`
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Last  float64   `json:"last"`
+	Count int       `json:"count"`
+}
+
+// ✅ แปลงเป็น kWh / kW ก่อนรวมเป็นช่วง (charger บางรุ่นส่ง Wh บางรุ่นส่ง kWh)
+func normalizeUnit(v *entity.MeterValue) {
+	switch {
+	case strings.EqualFold(v.Unit, "Wh"):
+		v.Value /= 1000
+		v.Unit = "kWh"
+	case strings.EqualFold(v.Unit, "W") || (v.Unit == "" && v.Measurand == MeasurandPowerImport):
+		v.Value /= 1000
+		v.Unit = "kW"
+	}
+}
+
+// Downsample รวม sample (เรียงตามเวลาแล้ว) เป็นช่วงละ interval
+func Downsample(values []entity.MeterValue, interval time.Duration) []MeterBucket {
+	buckets := make([]MeterBucket, 0)
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	var cur *MeterBucket
+	var sum float64
+	for _, v := range values {
+		start := v.Timestamp.Truncate(interval)
+		if cur == nil || !cur.Start.Equal(start) {
+			if cur != nil {
+				cur.Avg = sum / float64(cur.Count)
+				buckets = append(buckets, *cur)
+			}
+			cur = &MeterBucket{Start: start, Min: math.Inf(1), Max: math.Inf(-1)}
+			sum = 0
+		}
+		sum += v.Value
+		cur.Count++
+		cur.Min = math.Min(cur.Min, v.Value)
+		cur.Max = math.Max(cur.Max, v.Value)
+		cur.Last = v.Value
+	}
+	if cur != nil {
+		cur.Avg = sum / float64(cur.Count)
+		buckets = append(buckets, *cur)
+	}
+	return buckets
+}
+
+// ✅ อ่าน query ?measurand=&phase=&from=&to=&interval= แล้วคืนกราฟ
+func queryMeterSeries(c *gin.Context, scope func(*gorm.DB) *gorm.DB) {
+	measurand := c.DefaultQuery("measurand", MeasurandEnergyImport)
+
+	interval := time.Minute
+	if v := c.Query("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval ต้องเป็น duration เช่น 30s, 5m"})
+			return
+		}
+		interval = d
+	}
+
+	query := scope(config.DB().Model(&entity.MeterValue{})).Where("measurand = ?", measurand)
+	// 🔸 ไม่ระบุ phase → เฉพาะค่ารวม (ไม่งั้นค่าแต่ละ phase ปนกับค่ารวมในช่วงเดียวกัน)
+	query = query.Where("phase = ?", c.Query("phase"))
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from ต้องเป็น RFC3339"})
+			return
+		}
+		query = query.Where("timestamp >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to ต้องเป็น RFC3339"})
+			return
+		}
+		query = query.Where("timestamp <= ?", to)
+	}
+
+	var values []entity.MeterValue
+	if err := query.Order("timestamp").Find(&values).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	unit := ""
+	for i := range values {
+		normalizeUnit(&values[i])
+	}
+	if len(values) > 0 {
+		unit = values[0].Unit
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"measurand": measurand,
+		"unit":      unit,
+		"interval":  interval.String(),
+		"samples":   len(values),
+		"data":      Downsample(values, interval),
+	})
+}
+
+// GET /meter-values/charger/:chargerID
+func ListMeterValuesByCharger(c *gin.Context) {
+	chargerID := c.Param("chargerID")
+	queryMeterSeries(c, func(db *gorm.DB) *gorm.DB {
+		if connector := c.Query("connector_id"); connector != "" {
+			db = db.Where("connector_id = ?", connector)
+		}
+		return db.Where("charger_id = ?", chargerID)
+	})
+}
+
+// GET /meter-values/session/:session_id
+func ListMeterValuesBySession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("session_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		return
+	}
+	queryMeterSeries(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("charging_transaction_id IN (?)",
+			config.DB().Model(&entity.ChargingTransaction{}).
+				Select("id").
+				Where("charging_session_id = ?", uint(sessionID)))
+	})
+}
+
+// GET /meter-values/transaction/:transaction_id
+func ListMeterValuesByTransaction(c *gin.Context) {
+	transactionID, err := strconv.Atoi(c.Param("transaction_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction_id"})
+		return
+	}
+	queryMeterSeries(c, func(db *gorm.DB) *gorm.DB {
+		return db.Where("charging_transaction_id IN (?)",
+			config.DB().Model(&entity.ChargingTransaction{}).
+				Select("id").
+				Where("transaction_id = ?", transactionID))
+	})
+}
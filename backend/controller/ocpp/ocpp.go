@@ -64,6 +64,13 @@ func HandleOCPP(c *gin.Context) {
 	chargerID := c.Param("chargerID")
 	fmt.Println("🚗 Charger connected:", chargerID)
 
+	cp := registerChargePoint(chargerID, conn)
+	markChargePointOnline(chargerID)
+	defer func() {
+		unregisterChargePoint(cp)
+		markChargePointOffline(chargerID)
+	}()
+
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
@@ -71,13 +78,15 @@ func HandleOCPP(c *gin.Context) {
 			break
 		}
 
+		touchChargePoint(chargerID)
+
 		// ✅ ตอบกลับข้อความ "ready" ทุกครั้งที่มีการส่งข้อมูลเข้ามา
-		if err := conn.WriteMessage(websocket.TextMessage, []byte("ready")); err != nil {
+		if err := cp.write([]byte("ready")); err != nil {
 			fmt.Println("❌ Failed to send ready response:", err)
 		}
 
 		// ✅ แปลง JSON frame สำหรับ OCPP message
-		var frame []interface{}
+		var frame []json.RawMessage
 		if err := json.Unmarshal(msg, &frame); err != nil {
 			fmt.Println("❌ JSON parse error:", err)
 			continue
@@ -87,49 +96,102 @@ func HandleOCPP(c *gin.Context) {
 			continue
 		}
 
-		messageType, ok := frame[0].(float64)
-		if !ok {
+		var messageType int
+		if err := json.Unmarshal(frame[0], &messageType); err != nil {
 			continue
 		}
-		messageID, _ := frame[1].(string)
-		action, _ := frame[2].(string)
-
-		if int(messageType) == 2 {
-			switch action {
-			case "BootNotification":
-				// 🔸 ตอบกลับ BootNotification
-				response := []interface{}{
-					3,
-					messageID,
-					map[string]interface{}{
-						"status":      "Accepted",
-						"currentTime": "2025-11-12T12:00:00Z",
-						"interval":    30,
-					},
-				}
-				respJSON, _ := json.Marshal(response)
-				conn.WriteMessage(websocket.TextMessage, respJSON)
-				fmt.Println("✅ BootNotification Accepted")
-
-			case "MeterValues":
-				// 🔸 ตอบกลับ MeterValues
-				response := []interface{}{
-					3,
-					messageID,
-					map[string]interface{}{},
-				}
-				respJSON, _ := json.Marshal(response)
-				conn.WriteMessage(websocket.TextMessage, respJSON)
-				fmt.Println("📊 MeterValues Received and Acknowledged")
-
-			default:
-				fmt.Println("ℹ️ Unknown OCPP Action:", action)
+		var messageID string
+		json.Unmarshal(frame[1], &messageID)
+
+		if messageType == callMessage {
+			var action string
+			json.Unmarshal(frame[2], &action)
+
+			var payload json.RawMessage
+			if len(frame) > 3 {
+				payload = frame[3]
 			}
+
+			// 🔸 ประมวลผล CALL แล้วตอบกลับเป็น CALLRESULT หรือ CALLERROR
+			result, err := handleCall(chargerID, action, payload)
+			if err != nil {
+				sendCallError(cp, messageID, err)
+			} else {
+				sendCallResult(cp, messageID, result)
+			}
+		}
+
+		// 🔸 คำตอบของ CALL ที่ server ส่งออกไป (ดู Send ใน registry.go)
+		if messageType == callResultMessage {
+			cp.resolve(messageID, callResponse{payload: frame[2]})
 		}
 
-		// ✅ Broadcast ไปยัง frontend ทุกตัว
-		broadcastToFrontend(msg)
+		if messageType == callErrorMessage {
+			callErr := &CallError{}
+			json.Unmarshal(frame[2], &callErr.Code)
+			if len(frame) > 3 {
+				json.Unmarshal(frame[3], &callErr.Description)
+			}
+			cp.resolve(messageID, callResponse{err: callErr})
+		}
+
+		// ✅ Broadcast ไปยัง frontend ทุกตัว (idTag ถูกแทนด้วย hash — /frontend ไม่ต้อง login)
+		broadcastToFrontend(redactIdTag(msg, frame))
+	}
+}
+
+// ============================================================================
+// 🔸 ส่ง CALLRESULT / CALLERROR กลับไปยัง charger
+// ============================================================================
+func sendCallResult(cp *chargePoint, messageID string, payload interface{}) {
+	respJSON, _ := json.Marshal([]interface{}{callResultMessage, messageID, payload})
+	if err := cp.write(respJSON); err != nil {
+		fmt.Println("❌ Failed to send CALLRESULT:", err)
+	}
+}
+
+func sendCallError(cp *chargePoint, messageID string, err error) {
+	callErr, ok := err.(*CallError)
+	if !ok {
+		callErr = &CallError{Code: ErrInternalError, Description: err.Error()}
+	}
+	respJSON, _ := json.Marshal([]interface{}{
+		callErrorMessage, messageID, callErr.Code, callErr.Description, map[string]interface{}{},
+	})
+	if err := cp.write(respJSON); err != nil {
+		fmt.Println("❌ Failed to send CALLERROR:", err)
+	}
+}
+
+// ✅ CALL ที่มี idTag (Authorize / StartTransaction / StopTransaction) — idTag คือ token การชาร์จ
+// ส่งต่อได้แค่ idTagRef เท่านั้น ข้อความอื่นส่งตามเดิม
+func redactIdTag(msg []byte, frame []json.RawMessage) []byte {
+	if len(frame) < 4 {
+		return msg
+	}
+	var messageType int
+	if err := json.Unmarshal(frame[0], &messageType); err != nil || messageType != callMessage {
+		return msg
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(frame[3], &payload); err != nil {
+		return msg
+	}
+	raw, ok := payload["idTag"]
+	if !ok {
+		return msg
+	}
+	var idTag string
+	json.Unmarshal(raw, &idTag)
+	payload["idTag"], _ = json.Marshal(idTagRef(idTag))
+
+	redacted := append([]json.RawMessage{}, frame...)
+	redacted[3], _ = json.Marshal(payload)
+	out, err := json.Marshal(redacted)
+	if err != nil {
+		return msg
 	}
+	return out
 }
 
 // ============================================================================
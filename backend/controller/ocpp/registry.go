@@ -0,0 +1,210 @@
+package ocpp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// DefaultCallTimeout ใช้เมื่อ ctx ที่ส่งเข้า Send ไม่มี deadline
+const DefaultCallTimeout = 30 * time.Second
+
+var (
+	ErrChargerNotConnected = errors.New("charger is not connected")
+	ErrChargerDisconnected = errors.New("charger disconnected before responding")
+	ErrCallTimeout         = errors.New("timed out waiting for charger response")
+)
+
+// ✅ ผลลัพธ์ของ CALL ที่เราส่งออกไป (CALLRESULT หรือ CALLERROR)
+type callResponse struct {
+	payload json.RawMessage
+	err     error
+}
+
+// chargePoint คือ connection ของ charger หนึ่งตัว พร้อมคิว CALL ที่รอคำตอบ
+type chargePoint struct {
+	id   string
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   map[string]chan callResponse
+}
+
+var (
+	chargePoints   = make(map[string]*chargePoint)
+	chargePointsMu sync.RWMutex
+)
+
+// ============================================================================
+// 🔹 ลงทะเบียน / ยกเลิก connection ของ charger
+// ============================================================================
+func registerChargePoint(chargerID string, conn *websocket.Conn) *chargePoint {
+	cp := &chargePoint{
+		id:      chargerID,
+		conn:    conn,
+		pending: make(map[string]chan callResponse),
+	}
+
+	chargePointsMu.Lock()
+	old := chargePoints[chargerID]
+	chargePoints[chargerID] = cp
+	chargePointsMu.Unlock()
+
+	// 🔸 charger ต่อเข้ามาใหม่ด้วย ID เดิม → ปิด connection เก่าทิ้ง
+	if old != nil {
+		old.conn.Close()
+	}
+	return cp
+}
+
+func unregisterChargePoint(cp *chargePoint) {
+	chargePointsMu.Lock()
+	if chargePoints[cp.id] == cp {
+		delete(chargePoints, cp.id)
+	}
+	chargePointsMu.Unlock()
+
+	// 🔸 CALL ที่ยังรอคำตอบอยู่ให้จบด้วย error ทันที
+	cp.pendingMu.Lock()
+	for messageID, ch := range cp.pending {
+		ch <- callResponse{err: ErrChargerDisconnected}
+		delete(cp.pending, messageID)
+	}
+	cp.pendingMu.Unlock()
+}
+
+func getChargePoint(chargerID string) *chargePoint {
+	chargePointsMu.RLock()
+	defer chargePointsMu.RUnlock()
+	return chargePoints[chargerID]
+}
+
+// IsConnected บอกว่า charger นี้มี WebSocket เปิดอยู่หรือไม่
+func IsConnected(chargerID string) bool {
+	return getChargePoint(chargerID) != nil
+}
+
+// ConnectedChargers คืนรายชื่อ charger ที่เชื่อมต่ออยู่ในขณะนี้
+func ConnectedChargers() []string {
+	chargePointsMu.RLock()
+	defer chargePointsMu.RUnlock()
+
+	ids := make([]string, 0, len(chargePoints))
+	for id := range chargePoints {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ✅ เขียนลง WebSocket ทีละ goroutine เท่านั้น (gorilla ไม่รองรับ concurrent write)
+func (cp *chargePoint) write(msg []byte) error {
+	cp.writeMu.Lock()
+	defer cp.writeMu.Unlock()
+	return cp.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// ✅ ส่งคำตอบที่ได้จาก charger ให้ผู้เรียก Send ที่รออยู่
+func (cp *chargePoint) resolve(messageID string, resp callResponse) bool {
+	cp.pendingMu.Lock()
+	ch, ok := cp.pending[messageID]
+	if ok {
+		delete(cp.pending, messageID)
+	}
+	cp.pendingMu.Unlock()
+
+	if !ok {
+		fmt.Println("⚠️ Response for unknown messageID:", messageID)
+		return false
+	}
+	ch <- resp
+	return true
+}
+
+func (cp *chargePoint) forget(messageID string) {
+	cp.pendingMu.Lock()
+	delete(cp.pending, messageID)
+	cp.pendingMu.Unlock()
+}
+
+// ============================================================================
+// 🔹 Send — ส่ง CALL ไปยัง charger แล้วรอ CALLRESULT / CALLERROR
+// ============================================================================
+//
+// คืน payload ของ CALLRESULT หรือ *CallError เมื่อ charger ตอบ CALLERROR
+// ห้ามเรียกจาก goroutine ที่อ่าน connection ของ charger ตัวเดียวกัน (จะ deadlock)
+func Send(ctx context.Context, chargerID, action string, payload interface{}) (json.RawMessage, error) {
+	cp := getChargePoint(chargerID)
+	if cp == nil {
+		return nil, ErrChargerNotConnected
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	messageID := uuid.New().String()
+	msg, err := json.Marshal([]interface{}{callMessage, messageID, action, payload})
+	if err != nil {
+		return nil, err
+	}
+
+	// buffer 1 เพื่อไม่ให้ resolve ค้างถ้าผู้เรียก timeout ไปแล้ว
+	ch := make(chan callResponse, 1)
+	cp.pendingMu.Lock()
+	cp.pending[messageID] = ch
+	cp.pendingMu.Unlock()
+
+	if err := cp.write(msg); err != nil {
+		cp.forget(messageID)
+		return nil, err
+	}
+	fmt.Printf("📤 CALL %s → %s (%s)\n", action, chargerID, messageID)
+
+	select {
+	case resp := <-ch:
+		return resp.payload, resp.err
+	case <-ctx.Done():
+		cp.forget(messageID)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrCallTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// SendAndDecode เหมือน Send แต่ decode CALLRESULT ลงใน response ให้เลย
+func SendAndDecode(ctx context.Context, chargerID, action string, payload, response interface{}) error {
+	raw, err := Send(ctx, chargerID, action, payload)
+	if err != nil {
+		return err
+	}
+	if response == nil || len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, response); err != nil {
+		return &CallError{Code: ErrFormationViolation, Description: "invalid " + action + " response: " + err.Error()}
+	}
+	return nil
+}
+
+// GET /charge-points/connected
+func ListConnectedChargers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": ConnectedChargers()})
+}
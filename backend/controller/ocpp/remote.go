@@ -0,0 +1,49 @@
+package ocpp
+
+import (
+	"context"
+	"fmt"
+)
+
+// ✅ สถานะตอบกลับของ RemoteStart/RemoteStopTransaction ตาม OCPP 1.6
+const (
+	RemoteAccepted = "Accepted"
+	RemoteRejected = "Rejected"
+)
+
+type remoteStartTransactionRequest struct {
+	ConnectorID int    `json:"connectorId,omitempty"`
+	IdTag       string `json:"idTag"`
+}
+
+type remoteStopTransactionRequest struct {
+	TransactionID int `json:"transactionId"`
+}
+
+// RemoteResponse คือ payload ของ CALLRESULT สำหรับ RemoteStart/RemoteStop
+type RemoteResponse struct {
+	Status string `json:"status"`
+}
+
+// RemoteStartTransaction สั่ง charger ให้เริ่มชาร์จด้วย idTag ที่กำหนด
+// connectorID = 0 หมายถึงให้ charger เลือกหัวชาร์จเอง
+func RemoteStartTransaction(ctx context.Context, chargerID string, connectorID int, idTag string) (*RemoteResponse, error) {
+	var resp RemoteResponse
+	req := remoteStartTransactionRequest{ConnectorID: connectorID, IdTag: idTag}
+	if err := SendAndDecode(ctx, chargerID, "RemoteStartTransaction", req, &resp); err != nil {
+		return nil, err
+	}
+	fmt.Printf("▶️ RemoteStartTransaction %s connector %d → %s\n", chargerID, connectorID, resp.Status)
+	return &resp, nil
+}
+
+// RemoteStopTransaction สั่ง charger ให้หยุด transaction ที่กำลังชาร์จอยู่
+func RemoteStopTransaction(ctx context.Context, chargerID string, transactionID int) (*RemoteResponse, error) {
+	var resp RemoteResponse
+	req := remoteStopTransactionRequest{TransactionID: transactionID}
+	if err := SendAndDecode(ctx, chargerID, "RemoteStopTransaction", req, &resp); err != nil {
+		return nil, err
+	}
+	fmt.Printf("⏹️ RemoteStopTransaction %s #%d → %s\n", chargerID, transactionID, resp.Status)
+	return &resp, nil
+}
@@ -0,0 +1,61 @@
+package ocpp
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// ✅ idTag / token ของ session คือสิทธิ์เริ่ม-หยุดชาร์จและดู progress ของเจ้าของ session
+// endpoint อ่านข้อมูลส่งแค่ idTagRef (เทียบกันได้ แต่นำไปใช้แทน token ไม่ได้)
+func redactTransactions(txs []entity.ChargingTransaction) {
+	for i := range txs {
+		txs[i].IdTag = idTagRef(txs[i].IdTag)
+		if txs[i].ChargingSession != nil {
+			txs[i].ChargingSession.Token = ""
+		}
+	}
+}
+
+// GET /charging-transactions
+func ListChargingTransactions(c *gin.Context) {
+	var txs []entity.ChargingTransaction
+
+	db := config.DB()
+	query := db.Preload("ChargingSession").Preload("Payment").Order("started_at DESC")
+	if chargerID := c.Query("charger_id"); chargerID != "" {
+		query = query.Where("charger_id = ?", chargerID)
+	}
+
+	if err := query.Find(&txs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	redactTransactions(txs)
+
+	c.JSON(http.StatusOK, txs)
+}
+
+// GET /charging-transactions/payment/:payment_id
+func ListChargingTransactionsByPaymentID(c *gin.Context) {
+	paymentID, err := strconv.ParseUint(c.Param("payment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment_id"})
+		return
+	}
+
+	var txs []entity.ChargingTransaction
+	if err := config.DB().
+		Where("payment_id = ?", uint(paymentID)).
+		Order("started_at DESC").
+		Find(&txs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	redactTransactions(txs)
+
+	c.JSON(http.StatusOK, gin.H{"data": txs})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/Tawunchai/work-project/config"
 	"github.com/Tawunchai/work-project/entity"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // POST /send-otp
@@ -97,3 +98,9 @@ func VerifyOTP(c *gin.Context) {
 	db.Save(&otp)
 	c.JSON(http.StatusOK, gin.H{"message": "OTP verified successfully"})
 }
+
+// PurgeExpiredOTPs ลบ OTP ที่หมดอายุแล้วออกจากฐานข้อมูล
+func PurgeExpiredOTPs(db *gorm.DB) (int, error) {
+	res := db.Unscoped().Where("expires_at < ?", time.Now().Unix()).Delete(&entity.OTP{})
+	return int(res.RowsAffected), res.Error
+}
@@ -1,16 +1,23 @@
 package payment
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/billing"
+	"github.com/Tawunchai/work-project/controller/coupon"
+	"github.com/Tawunchai/work-project/controller/slip"
+	"github.com/Tawunchai/work-project/controller/wallet"
 	"github.com/Tawunchai/work-project/entity"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func ListEVChargingPayment(c *gin.Context) {
@@ -119,44 +126,6 @@ func ListPaymentByUserID(c *gin.Context) {
 }
 
 func CreatePayment(c *gin.Context) {
-	var filePath string
-
-	// ==========================
-	// 📌 ตรวจสอบรูปภาพ ถ้ามี
-	// ==========================
-	file, err := c.FormFile("picture")
-	if err == nil && file != nil {
-		validTypes := []string{"image/jpeg", "image/png", "image/gif"}
-		isValid := false
-		for _, t := range validTypes {
-			if file.Header.Get("Content-Type") == t {
-				isValid = true
-				break
-			}
-		}
-		if !isValid {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "รูปภาพต้องเป็นไฟล์ .jpg, .png, .gif เท่านั้น"})
-			return
-		}
-
-		uploadDir := "uploads/payment"
-		if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างโฟลเดอร์เก็บไฟล์ได้"})
-			return
-		}
-
-		ext := filepath.Ext(file.Filename)
-		newFileName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-		filePath = filepath.Join(uploadDir, newFileName)
-
-		if err := c.SaveUploadedFile(file, filePath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		filePath = ""
-	}
-
 	// ==========================
 	// 📌 รับข้อมูลจาก Form
 	// ==========================
@@ -166,6 +135,7 @@ func CreatePayment(c *gin.Context) {
 	methodIDStr := c.PostForm("method_id")
 	referenceNumber := c.PostForm("reference_number")
 	cabinetIDStr := c.PostForm("ev_cabinet_id") // ⭐⭐ เพิ่มมาใหม่
+	couponCode := coupon.NormalizeCode(c.PostForm("coupon_code"))
 
 	// ==========================
 	// 📌 แปลงข้อมูล
@@ -206,31 +176,233 @@ func CreatePayment(c *gin.Context) {
 		}
 	}
 
+	payment := CreateCharge(c, ChargeInput{
+		Date:            date,
+		Amount:          amount,
+		UserID:          userID,
+		MethodID:        methodID,
+		CabinetID:       cabinetID,
+		CouponCode:      couponCode,
+		ReferenceNumber: referenceNumber,
+	})
+	if payment == nil {
+		return
+	}
+
+	if payment.Status != entity.PaymentApproved {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "ได้รับสลิปแล้ว รอผู้ดูแลตรวจสอบ",
+			"data":    payment,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "สร้างข้อมูล Payment สำเร็จ",
+		"data":    payment,
+	})
+}
+
+// ✅ บัญชีรับเงินของระบบ (Bank แถวแรก) — ไม่มี / อ่านไม่ได้ ตอบ error ให้ client แล้วคืน false
+func receivingBank(c *gin.Context) (entity.Bank, bool) {
+	var bank entity.Bank
+	err := config.DB().Order("id").First(&bank).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "ยังไม่ได้ตั้งค่าบัญชีรับเงิน", "reason": slip.ReasonNoBank})
+		return bank, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอ่านข้อมูลบัญชีรับเงินได้: " + err.Error()})
+		return bank, false
+	}
+	return bank, true
+}
+
+// ChargeInput คือข้อมูลสร้าง Payment ค่าชาร์จ (Amount = ยอดก่อนหักคูปอง)
+type ChargeInput struct {
+	Date            time.Time
+	Amount          float64
+	UserID          uint
+	MethodID        uint
+	CabinetID       *uint
+	CouponCode      string
+	ReferenceNumber string
+}
+
+// CreateCharge สร้าง Payment ค่าชาร์จ: คูปอง, ตรวจสลิป (ไฟล์ "picture" ใน form) หรือหัก Coin
+// ถ้าไม่สำเร็จจะตอบ error ให้ client แล้วคืน nil
+func CreateCharge(c *gin.Context, in ChargeInput) *entity.Payment {
+	var filePath string
+	date, amount, userID, methodID := in.Date, in.Amount, in.UserID, in.MethodID
+	cabinetID, couponCode, referenceNumber := in.CabinetID, coupon.NormalizeCode(in.CouponCode), in.ReferenceNumber
+
+	db := config.DB()
+	var method entity.Method
+	if err := db.First(&method, methodID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบ Method การชำระเงิน"})
+		return nil
+	}
+	isCoin := strings.Contains(strings.ToLower(method.Medthod), "coin")
+
+	// ==========================
+	// 📌 คูปองส่วนลด → amount คือยอดก่อนหัก, ยอดที่ต้องชำระจริงคือ net
+	// ==========================
+	// ตรวจรอบแรกเพื่อรู้ยอดสลิปที่ต้องจ่าย แล้วตรวจซ้ำใน transaction ตอนจองสิทธิ์
+	net, discount := amount, 0.0
+	if couponCode != "" {
+		quote, err := coupon.Apply(db, couponCode, userID, entity.CouponForCharging, amount, cabinetID, time.Now())
+		if err != nil {
+			coupon.RespondError(c, err)
+			return nil
+		}
+		net, discount = quote.Net, quote.Discount
+	}
+
+	// ==========================
+	// 📌 ชำระผ่าน QR → ตรวจสลิปฝั่ง server (ผู้รับ / ยอด / วันที่ / ซ้ำ)
+	// ==========================
+	// ตรวจอัตโนมัติไม่ได้ (เช่น provider ล่ม) → บันทึกเป็น manual-review ให้ admin ตรวจ
+	var review paymentReview
+	var imageRef string
+	if net <= 0 {
+		review = couponPaid()
+	} else if isCoin {
+		review = coinPaid()
+	} else {
+		bank, ok := receivingBank(c)
+		if !ok {
+			return nil
+		}
+		slipResult, err := slip.VerifyUpload(c, "picture", bank, net)
+		if err != nil && !slip.NeedsReview(err) {
+			slip.RespondError(c, err)
+			return nil
+		}
+		review = reviewOf(err)
+		// เลขอ้างอิงต้องมาจากสลิปที่ตรวจแล้ว ไม่ใช้ค่าที่ client ส่งมา
+		referenceNumber = ""
+		if slipResult != nil {
+			referenceNumber = slipResult.TransRef
+		}
+		// อ่านเลขอ้างอิงไม่ได้ → จองรูปนี้ด้วย hash แทน (กันส่งรูปเดิมซ้ำเป็นรายการรอตรวจ)
+		if referenceNumber == "" {
+			imageRef = slip.UploadReference(c, "picture")
+		}
+	}
+
+	// ==========================
+	// 📌 ตรวจสอบรูปภาพ ถ้ามี
+	// ==========================
+	file, err := c.FormFile("picture")
+	if err == nil && file != nil {
+		validTypes := []string{"image/jpeg", "image/png", "image/gif"}
+		isValid := false
+		for _, t := range validTypes {
+			if file.Header.Get("Content-Type") == t {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "รูปภาพต้องเป็นไฟล์ .jpg, .png, .gif เท่านั้น"})
+			return nil
+		}
+
+		uploadDir := "uploads/payment"
+		if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างโฟลเดอร์เก็บไฟล์ได้"})
+			return nil
+		}
+
+		ext := filepath.Ext(file.Filename)
+		newFileName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
+		filePath = filepath.Join(uploadDir, newFileName)
+
+		if err := c.SaveUploadedFile(file, filePath); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil
+		}
+	} else {
+		filePath = ""
+	}
+
 	// ==========================
 	// 📌 Create Payment
 	// ==========================
 	payment := entity.Payment{
 		Date:            date,
-		Amount:          amount,
+		Amount:          net, // ยอดที่ชำระจริง (หลังหักส่วนลด)
+		Discount:        discount,
+		CouponCode:      couponCode,
 		UserID:          &userID,
 		MethodID:        &methodID,
 		EVCabinetID:     cabinetID, // ⭐⭐ บันทึกตู้ชาร์จ
 		ReferenceNumber: referenceNumber,
 		Picture:         filePath,
+		Status:          review.Status,
+		StatusReason:    review.Reason,
+		VerifiedAt:      review.VerifiedAt,
+		ReviewedAt:      review.ReviewedAt,
 	}
 
-	if err := config.DB().Create(&payment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกข้อมูลได้: " + err.Error()})
-		return
+	// ✅ จ่ายด้วย Coin → หัก coin ผ่าน ledger พร้อมสร้าง Payment ใน transaction เดียวกัน
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := slip.RegisterReference(tx, payment.ReferenceNumber, &payment.ID, nil); err != nil {
+			return err
+		}
+		if err := slip.RegisterReference(tx, imageRef, &payment.ID, nil); err != nil {
+			return err
+		}
+		if couponCode != "" {
+			quote, err := coupon.Apply(tx, couponCode, userID, entity.CouponForCharging, amount, cabinetID, time.Now())
+			if err != nil {
+				return err
+			}
+			if quote.Net != net {
+				return &coupon.Error{Reason: coupon.ReasonExhausted, Message: "เงื่อนไขคูปองเปลี่ยนไป กรุณาลองใหม่"}
+			}
+			status := coupon.RedemptionStatus(payment.Status)
+			if err := coupon.Reserve(tx, quote, userID, &payment.ID, nil, cabinetID, status); err != nil {
+				return err
+			}
+		}
+		if !isCoin || net <= 0 {
+			return nil
+		}
+		_, err := wallet.Post(tx, wallet.Posting{
+			UserID:    userID,
+			Kind:      wallet.KindCharge,
+			Amount:    -net,
+			Note:      "ชำระค่าชาร์จด้วย Coin",
+			PaymentID: &payment.ID,
+		})
+		return err
+	})
+	if err != nil {
+		if filePath != "" {
+			os.Remove(filePath) // ไม่เก็บรูปของรายการที่บันทึกไม่สำเร็จ
+		}
+		var verr *slip.ValidationError
+		if errors.As(err, &verr) || slip.IsDuplicate(err) {
+			slip.RespondError(c, err)
+			return nil
+		}
+		if coupon.IsCouponError(err) {
+			coupon.RespondError(c, err)
+			return nil
+		}
+		c.JSON(wallet.ErrorStatus(err), gin.H{"error": "ไม่สามารถบันทึกข้อมูลได้: " + err.Error()})
+		return nil
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "สร้างข้อมูล Payment สำเร็จ",
-		"data":    payment,
-	})
+	return &payment
 }
 
 // ✅ Struct สำหรับรับ JSON จาก frontend
+// price / power จาก client ไม่ถูกใช้แล้ว — server คำนวณเองจาก Payment.Amount และ EVcharging.Price
 type CreateEVChargingPaymentInput struct {
 	EVchargingID uint    `json:"evcharging_id" binding:"required"`
 	PaymentID    uint    `json:"payment_id" binding:"required"`
@@ -253,17 +425,17 @@ func CreateEVChargingPayment(c *gin.Context) {
 
 	db := config.DB()
 
-	// ✅ สร้างข้อมูลใหม่ตาม struct entity.EVChargingPayment
-	evPayment := entity.EVChargingPayment{
-		EVchargingID: input.EVchargingID,
-		PaymentID:    input.PaymentID,
-		Price:        input.Price,
-		Percent:      input.Percent, // ✅ ใช้ Percent แทน Quantity
-		Power:        input.Power,   // ✅ เพิ่ม Power
+	// ✅ คำนวณยอดจากฝั่ง server ตามสัดส่วน Percent ของ Payment
+	evPayment, err := billing.QuoteLine(input.PaymentID, input.EVchargingID, input.Percent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "ข้อมูลไม่ถูกต้อง: " + err.Error(),
+		})
+		return
 	}
 
 	// ✅ บันทึกลงฐานข้อมูล
-	if err := db.Create(&evPayment).Error; err != nil {
+	if err := db.Create(evPayment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "ไม่สามารถบันทึกข้อมูลได้: " + err.Error(),
 		})
@@ -320,6 +492,20 @@ func ListPaymentCoinsByUserID(c *gin.Context) {
 func CreatePaymentCoin(c *gin.Context) {
     var filePath string
 
+    // 0. ตรวจสลิปฝั่ง server — ยอดต้องไม่น้อยกว่า Bank.Minimum และเติม Coin ตามยอดในสลิป
+    db := config.DB()
+    bank, ok := receivingBank(c)
+    if !ok {
+        return
+    }
+    // ตรวจอัตโนมัติไม่ได้ → บันทึกเป็น manual-review และยังไม่เติม Coin จนกว่า admin อนุมัติ
+    slipResult, err := slip.VerifyUpload(c, "Picture", bank, float64(bank.Minimum))
+    if err != nil && !slip.NeedsReview(err) {
+        slip.RespondError(c, err)
+        return
+    }
+    review := reviewOf(err)
+
     // 1. จัดการรูปภาพ
     file, err := c.FormFile("Picture")
     if err == nil && file != nil {
@@ -356,30 +542,12 @@ func CreatePaymentCoin(c *gin.Context) {
     }
 
     // 2. รับข้อมูลอื่นจาก form
-    dateStr := c.PostForm("Date")                    // ตัว D ใหญ่ตรงกับ key ที่ส่งมาจาก frontend
-    amountStr := c.PostForm("Amount")
-    referenceNumber := c.PostForm("ReferenceNumber")
+    // Date / Amount / ReferenceNumber ใช้ค่าจากสลิปที่ตรวจแล้ว ไม่ใช้ค่าที่ client ส่งมา
+    // (ยกเว้นรายการที่รอ admin ตรวจ — ใช้ยอดที่ client แจ้งเป็นยอดตั้งต้นให้ admin ยืนยัน)
     userIDStr := c.PostForm("UserID")
+    couponCode := coupon.NormalizeCode(c.PostForm("CouponCode"))
 
     // 3. แปลงค่าที่จำเป็น
-    // กรณี Date ใน react เป็น ISO string ใช้ time.Parse(time.RFC3339, ...)
-    var date time.Time
-    if dateStr != "" {
-        date, err = time.Parse(time.RFC3339, dateStr)
-        if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "รูปแบบวันที่ไม่ถูกต้อง (ต้องเป็น ISO 8601)"})
-            return
-        }
-    } else {
-        date = time.Now()
-    }
-
-    amount, err := strconv.ParseFloat(amountStr, 64)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "จำนวนเงินไม่ถูกต้อง"})
-        return
-    }
-
     userID64, err := strconv.ParseUint(userIDStr, 10, 32)
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "UserID ไม่ถูกต้อง"})
@@ -387,8 +555,30 @@ func CreatePaymentCoin(c *gin.Context) {
     }
     userID := uint(userID64)
 
+    var amount float64
+    var referenceNumber, imageRef string
+    date := time.Now()
+    if slipResult != nil {
+        referenceNumber = slipResult.TransRef
+    }
+    // อ่านเลขอ้างอิงไม่ได้ → จองรูปนี้ด้วย hash แทน (กันส่งรูปเดิมซ้ำเป็นรายการรอตรวจ)
+    if referenceNumber == "" {
+        imageRef = slip.UploadReference(c, "Picture")
+    }
+    if review.Status == entity.PaymentManualReview {
+        amount, _ = strconv.ParseFloat(c.PostForm("Amount"), 64)
+        if slipResult != nil && slipResult.Amount > 0 {
+            amount = slipResult.Amount
+        }
+        if slipResult != nil && !slipResult.Date.IsZero() {
+            date = slipResult.Date
+        }
+    } else {
+        amount = slipResult.Amount
+        date = slipResult.Date
+    }
+
     // 4. ตรวจสอบ user
-    db := config.DB()
     var user entity.User
     if err := db.First(&user, userID).Error; err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
@@ -402,15 +592,69 @@ func CreatePaymentCoin(c *gin.Context) {
         ReferenceNumber: referenceNumber,
         Picture:         filePath, // string (อาจเป็น path ว่าง)
         UserID:          userID,
+        CouponCode:      couponCode,
+        Status:          review.Status,
+        StatusReason:    review.Reason,
+        VerifiedAt:      review.VerifiedAt,
+        ReviewedAt:      review.ReviewedAt,
     }
 
-    if err := db.Create(&paymentCoin).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    // 6. บันทึก PaymentCoin และเพิ่ม Coin ผ่าน ledger พร้อมกัน (เฉพาะรายการที่อนุมัติแล้ว)
+    err = db.Transaction(func(tx *gorm.DB) error {
+        // คูปองเติม Coin → ได้โบนัสเพิ่ม (รายการที่รอตรวจ จะคิดโบนัสใหม่จากยอดที่ admin ยืนยัน)
+        var quote *coupon.Quote
+        if couponCode != "" {
+            var err error
+            quote, err = coupon.Apply(tx, couponCode, userID, entity.CouponForTopUp, paymentCoin.Amount, nil, time.Now())
+            if err != nil {
+                return err
+            }
+            paymentCoin.BonusCoin = quote.BonusCoin
+        }
+        if err := tx.Create(&paymentCoin).Error; err != nil {
+            return err
+        }
+        if err := slip.RegisterReference(tx, paymentCoin.ReferenceNumber, nil, &paymentCoin.ID); err != nil {
+            return err
+        }
+        if err := slip.RegisterReference(tx, imageRef, nil, &paymentCoin.ID); err != nil {
+            return err
+        }
+        if quote != nil {
+            status := coupon.RedemptionStatus(paymentCoin.Status)
+            if err := coupon.Reserve(tx, quote, userID, nil, &paymentCoin.ID, nil, status); err != nil {
+                return err
+            }
+        }
+        if paymentCoin.Status != entity.PaymentApproved {
+            return nil
+        }
+        return creditTopUp(tx, &paymentCoin, nil)
+    })
+    if err != nil {
+        if filePath != "" {
+            os.Remove(filePath) // ไม่เก็บรูปของรายการที่บันทึกไม่สำเร็จ
+        }
+        var verr *slip.ValidationError
+        if errors.As(err, &verr) || slip.IsDuplicate(err) {
+            slip.RespondError(c, err)
+            return
+        }
+        if coupon.IsCouponError(err) {
+            coupon.RespondError(c, err)
+            return
+        }
+        c.JSON(wallet.ErrorStatus(err), gin.H{"error": err.Error()})
         return
     }
 
     db.Preload("User").First(&paymentCoin, paymentCoin.ID)
 
+    if paymentCoin.Status != entity.PaymentApproved {
+        c.JSON(http.StatusAccepted, paymentCoin)
+        return
+    }
+
     c.JSON(http.StatusCreated, paymentCoin)
 }
 
@@ -433,16 +677,10 @@ func DeletePaymentCoins(c *gin.Context) {
 		return
 	}
 
-	// ลบรูปภาพทั้งหมด
-	for _, paymentCoin := range paymentCoins {
-		if paymentCoin.Picture != "" && filepath.HasPrefix(paymentCoin.Picture, "uploads/paymentcoin") {
-			if _, err := os.Stat(paymentCoin.Picture); err == nil {
-				if removeErr := os.Remove(paymentCoin.Picture); removeErr != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถลบรูปภาพได้: " + removeErr.Error()})
-					return
-				}
-			}
-		}
+	// 🔸 ไม่ลบรูปสลิป — เก็บไว้เป็นหลักฐานสำหรับการตรวจสอบและคืนเงิน (ลบเป็น soft delete)
+	if len(paymentCoins) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลที่ต้องการลบ"})
+		return
 	}
 
 	// ลบข้อมูลในฐานข้อมูลทั้งหมด
@@ -452,7 +690,7 @@ func DeletePaymentCoins(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "ลบ PaymentCoin ทั้งหมดสำเร็จพร้อมลบรูปภาพ",
+		"message": "ลบ PaymentCoin ทั้งหมดสำเร็จ (เก็บรูปสลิปไว้เป็นหลักฐาน)",
 		"deleted": ids,
 	})
 }
@@ -480,18 +718,10 @@ func DeletePayment(c *gin.Context) {
 		return
 	}
 
-	// ลบรูปภาพทั้งหมด (ถ้ามี)
-	for _, payment := range payments {
-		if payment.Picture != "" && filepath.HasPrefix(payment.Picture, "uploads/payment") {
-			if _, err := os.Stat(payment.Picture); err == nil {
-				if removeErr := os.Remove(payment.Picture); removeErr != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error": "ไม่สามารถลบรูปภาพได้: " + removeErr.Error(),
-					})
-					return
-				}
-			}
-		}
+	// 🔸 ไม่ลบรูปสลิป — เก็บไว้เป็นหลักฐานสำหรับการตรวจสอบและคืนเงิน (ลบเป็น soft delete)
+	if len(payments) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลที่ต้องการลบ"})
+		return
 	}
 
 	// ลบข้อมูลในฐานข้อมูลทั้งหมด
@@ -503,38 +733,39 @@ func DeletePayment(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "ลบ Payment ทั้งหมดสำเร็จพร้อมลบรูปภาพ",
+		"message": "ลบ Payment ทั้งหมดสำเร็จ (เก็บรูปสลิปไว้เป็นหลักฐาน)",
 		"deleted": ids,
 	})
 }
 
-// ✅ GetDataPaymentByRef: ตรวจสอบว่ามี ref นี้ใน Payment หรือ PaymentCoin หรือไม่
+// ✅ GetDataPaymentByRef: ตรวจสอบว่ามี ref นี้ในทะเบียนสลิป (Payment หรือ PaymentCoin) หรือไม่
 func GetDataPaymentByRef(c *gin.Context) {
 	ref := c.Param("ref")
 	db := config.DB()
 
-	// ค้นใน Payment ก่อน
-	var payment entity.Payment
-	if err := db.Where("reference_number = ?", ref).First(&payment).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"found":   true,
-			"type":    "Payment",
-			"message": "พบข้อมูลใน Payment",
-			"data":    payment,
-		})
-		return
-	}
-
-	// ถ้าไม่พบใน Payment ให้ค้นใน PaymentCoin
-	var paymentCoin entity.PaymentCoin
-	if err := db.Where("reference_number = ?", ref).First(&paymentCoin).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"found":   true,
-			"type":    "PaymentCoin",
-			"message": "พบข้อมูลใน PaymentCoin",
-			"data":    paymentCoin,
-		})
-		return
+	if holder, err := slip.FindReference(db, ref); err == nil {
+		if holder.PaymentID != nil {
+			var payment entity.Payment
+			db.Unscoped().First(&payment, *holder.PaymentID)
+			c.JSON(http.StatusOK, gin.H{
+				"found":   true,
+				"type":    "Payment",
+				"message": "พบข้อมูลใน Payment",
+				"data":    payment,
+			})
+			return
+		}
+		if holder.PaymentCoinID != nil {
+			var paymentCoin entity.PaymentCoin
+			db.Unscoped().First(&paymentCoin, *holder.PaymentCoinID)
+			c.JSON(http.StatusOK, gin.H{
+				"found":   true,
+				"type":    "PaymentCoin",
+				"message": "พบข้อมูลใน PaymentCoin",
+				"data":    paymentCoin,
+			})
+			return
+		}
 	}
 
 	// ไม่พบข้อมูล
@@ -543,4 +774,4 @@ func GetDataPaymentByRef(c *gin.Context) {
 		"ref":     ref,
 		"message": "ไม่พบข้อมูลใน Payment หรือ PaymentCoin",
 	})
-}
\ No newline at end of file
+}
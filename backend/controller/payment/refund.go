@@ -0,0 +1,462 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/audit"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/controller/wallet"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ error ของการคืนเงิน
+var (
+	ErrRefundNotApproved = errors.New("คืนเงินได้เฉพาะรายการที่อนุมัติแล้ว")
+	ErrRefundMethod      = errors.New("วิธีคืนเงินไม่ถูกต้อง")
+	ErrRefundNoUser      = errors.New("รายการนี้ไม่มีผู้ใช้ให้คืนเงินเข้ากระเป๋า")
+	ErrRefundDecided     = errors.New("คำขอคืนเงินนี้ถูกตัดสินไปแล้ว")
+	ErrBankReference     = errors.New("กรุณาระบุเลขอ้างอิงการโอนคืน")
+)
+
+// RefundExceedsError เมื่อยอดคืนรวมเกินยอดที่จ่าย
+type RefundExceedsError struct {
+	Paid, Refunded, Requested float64
+}
+
+func (e *RefundExceedsError) Error() string {
+	return fmt.Sprintf("ยอดคืนเกินยอดที่ชำระ (ชำระ %.2f, คืน/ขอคืนแล้ว %.2f, ขอคืนเพิ่ม %.2f)", e.Paid, e.Refunded, e.Requested)
+}
+
+// refundTarget คือรายการต้นทางของการคืนเงิน (Payment หรือ PaymentCoin อย่างใดอย่างหนึ่ง)
+type refundTarget struct {
+	PaymentID     *uint
+	PaymentCoinID *uint
+	UserID        uint
+	Amount        float64
+	Status        string
+}
+
+func (t refundTarget) entityType() string {
+	if t.PaymentCoinID != nil {
+		return "PaymentCoin"
+	}
+	return "Payment"
+}
+
+func (t refundTarget) id() uint {
+	if t.PaymentCoinID != nil {
+		return *t.PaymentCoinID
+	}
+	return *t.PaymentID
+}
+
+func (t refundTarget) model() interface{} {
+	if t.PaymentCoinID != nil {
+		return &entity.PaymentCoin{}
+	}
+	return &entity.Payment{}
+}
+
+// ✅ โหลดรายการต้นทางจาก refund (หรือจาก id ตอนสร้างคำขอ)
+func loadRefundTarget(tx *gorm.DB, paymentID, paymentCoinID *uint) (refundTarget, error) {
+	if paymentCoinID != nil {
+		var pc entity.PaymentCoin
+		if err := tx.First(&pc, *paymentCoinID).Error; err != nil {
+			return refundTarget{}, err
+		}
+		return refundTarget{PaymentCoinID: &pc.ID, UserID: pc.UserID, Amount: pc.Amount, Status: pc.Status}, nil
+	}
+	var p entity.Payment
+	if err := tx.First(&p, *paymentID).Error; err != nil {
+		return refundTarget{}, err
+	}
+	t := refundTarget{PaymentID: &p.ID, Amount: p.Amount, Status: p.Status}
+	if p.UserID != nil {
+		t.UserID = *p.UserID
+	}
+	return t, nil
+}
+
+// refundedTotal รวมยอดคืนของรายการตามสถานะที่ระบุ
+func refundedTotal(tx *gorm.DB, t refundTarget, statuses ...string) (float64, error) {
+	var total float64
+	query := tx.Model(&entity.Refund{}).Where("status IN ?", statuses)
+	if t.PaymentCoinID != nil {
+		query = query.Where("payment_coin_id = ?", *t.PaymentCoinID)
+	} else {
+		query = query.Where("payment_id = ?", *t.PaymentID)
+	}
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return math.Round(total*100) / 100, nil
+}
+
+// ✅ สรุปยอดคืนของรายการ — ใช้ตอบ client และบันทึกใน audit log
+func refundSummary(tx *gorm.DB, t refundTarget) gin.H {
+	completed, _ := refundedTotal(tx, t, entity.RefundCompleted)
+	pending, _ := refundedTotal(tx, t, entity.RefundRequested)
+	return gin.H{
+		"paid":       t.Amount,
+		"refunded":   completed,
+		"pending":    pending,
+		"refundable": math.Max(0, math.Round((t.Amount-completed-pending)*100)/100),
+	}
+}
+
+// ============================================================================
+// 🔹 คำขอคืนเงิน
+// ============================================================================
+
+type refundInput struct {
+	Amount        float64 `json:"amount"` // ไม่ระบุ = คืนยอดที่เหลือทั้งหมด
+	Reason        string  `json:"reason"`
+	Method        string  `json:"method"`
+	BankReference string  `json:"bank_reference"`
+}
+
+// ✅ อ่าน body และหาผู้ทำรายการจาก JwtAuth (route อยู่หลัง RequireRoles(Admin) เหมือน bindReview)
+func bindRefund(c *gin.Context) (refundInput, *uint, error) {
+	var input refundInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		return input, nil, err
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	input.Method = strings.TrimSpace(input.Method)
+	input.BankReference = strings.TrimSpace(input.BankReference)
+
+	if v, ok := c.Get("UserID"); ok {
+		if id, ok := v.(int); ok && id > 0 {
+			uid := uint(id)
+			return input, &uid, nil
+		}
+	}
+	return input, nil, errMissingReviewer
+}
+
+// requestRefund สร้างคำขอคืนเงิน (status = requested) หลังตรวจยอดคงเหลือที่คืนได้
+func requestRefund(c *gin.Context, paymentID, paymentCoinID *uint) {
+	input, actor, err := bindRefund(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil || input.Reason == "" || input.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลและยอดคืนให้ถูกต้อง"})
+		return
+	}
+
+	db := config.DB()
+	var refund entity.Refund
+	var summary gin.H
+	err = db.Transaction(func(tx *gorm.DB) error {
+		target, err := loadRefundTarget(tx, paymentID, paymentCoinID)
+		if err != nil {
+			return err
+		}
+		if target.Status != entity.PaymentApproved {
+			return ErrRefundNotApproved
+		}
+
+		// 🔸 PaymentCoin คือ coin ที่อยู่ในกระเป๋าแล้ว → คืนได้เฉพาะโอนคืน (หัก coin ออก)
+		method := input.Method
+		if method == "" {
+			method = entity.RefundToWallet
+			if target.PaymentCoinID != nil {
+				method = entity.RefundBankTransfer
+			}
+		}
+		switch {
+		case method != entity.RefundToWallet && method != entity.RefundBankTransfer:
+			return ErrRefundMethod
+		case method == entity.RefundToWallet && target.PaymentCoinID != nil:
+			return ErrRefundMethod
+		case target.UserID == 0 && (method == entity.RefundToWallet || target.PaymentCoinID != nil):
+			return ErrRefundNoUser
+		}
+
+		// 🔸 ยอดคืนรวม (คืนแล้ว + รอคืน) ต้องไม่เกินยอดที่จ่าย
+		used, err := refundedTotal(tx, target, entity.RefundRequested, entity.RefundCompleted)
+		if err != nil {
+			return err
+		}
+		amount := math.Round(input.Amount*100) / 100
+		if amount == 0 {
+			amount = math.Round((target.Amount-used)*100) / 100
+		}
+		if amount <= 0 || math.Round((used+amount)*100) > math.Round(target.Amount*100) {
+			return &RefundExceedsError{Paid: target.Amount, Refunded: used, Requested: amount}
+		}
+
+		var userID *uint
+		if target.UserID != 0 {
+			userID = &target.UserID
+		}
+		refund = entity.Refund{
+			Amount:        amount,
+			Reason:        input.Reason,
+			Method:        method,
+			Status:        entity.RefundRequested,
+			PaymentID:     target.PaymentID,
+			PaymentCoinID: target.PaymentCoinID,
+			UserID:        userID,
+			RequestedByID: actor,
+		}
+		if err := tx.Create(&refund).Error; err != nil {
+			return err
+		}
+		summary = refundSummary(tx, target)
+		return audit.Record(tx, "refund.requested", "Refund", refund.ID, actor, gin.H{
+			"target":    target.entityType(),
+			"target_id": target.id(),
+			"amount":    amount,
+			"method":    method,
+			"reason":    input.Reason,
+			"summary":   summary,
+		})
+	})
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "สร้างคำขอคืนเงินสำเร็จ", "data": refund, "summary": summary})
+}
+
+// POST /payments/:id/refunds {amount, reason, method}
+func CreatePaymentRefund(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	requestRefund(c, &id, nil)
+}
+
+// POST /payment-coins/:id/refunds {amount, reason}
+func CreatePaymentCoinRefund(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	requestRefund(c, nil, &id)
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscan(c.Param("id"), &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// ============================================================================
+// 🔹 อนุมัติ / ไม่อนุมัติคำขอคืนเงิน
+// ============================================================================
+
+// POST /refunds/:id/approve {bank_reference} — คืนเงินจริงแล้วบันทึกผู้อนุมัติ
+func ApproveRefund(c *gin.Context) {
+	input, approver, err := bindRefund(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	db := config.DB()
+	var refund entity.Refund
+	var summary gin.H
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&refund, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if refund.Status != entity.RefundRequested {
+			return ErrRefundDecided
+		}
+		if refund.Method == entity.RefundBankTransfer && input.BankReference == "" {
+			return ErrBankReference
+		}
+
+		target, err := loadRefundTarget(tx, refund.PaymentID, refund.PaymentCoinID)
+		if err != nil {
+			return err
+		}
+		if target.Status != entity.PaymentApproved {
+			return ErrRefundNotApproved
+		}
+		completed, err := refundedTotal(tx, target, entity.RefundCompleted)
+		if err != nil {
+			return err
+		}
+		if math.Round((completed+refund.Amount)*100) > math.Round(target.Amount*100) {
+			return &RefundExceedsError{Paid: target.Amount, Refunded: completed, Requested: refund.Amount}
+		}
+
+		// 🔸 ledger: คืนเข้ากระเป๋า = +coin, โอนคืนค่าเติม coin = หัก coin ที่เติมไป
+		var posting *wallet.Posting
+		switch {
+		case refund.Method == entity.RefundToWallet:
+			posting = &wallet.Posting{Amount: refund.Amount, Note: "คืนเงินเข้ากระเป๋า: " + refund.Reason}
+		case target.PaymentCoinID != nil:
+			posting = &wallet.Posting{Amount: -refund.Amount, Note: "โอนคืนค่าเติม Coin: " + refund.Reason}
+		}
+		updates := map[string]interface{}{
+			"status":         entity.RefundCompleted,
+			"approved_by_id": approver,
+			"decided_at":     time.Now(),
+			"decision_note":  input.Reason,
+			"bank_reference": input.BankReference,
+		}
+		if posting != nil {
+			posting.UserID = target.UserID
+			posting.Kind = wallet.KindRefund
+			posting.PaymentID = target.PaymentID
+			posting.PaymentCoinID = target.PaymentCoinID
+			posting.CreatedByID = approver
+			wt, err := wallet.Post(tx, *posting)
+			if err != nil {
+				return err
+			}
+			updates["wallet_transaction_id"] = wt.ID
+		}
+
+		res := tx.Model(&entity.Refund{}).Where("id = ? AND status = ?", refund.ID, entity.RefundRequested).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefundDecided
+		}
+
+		// 🔸 คืนครบยอด → รายการต้นทางเป็น refunded และ token ของ Payment นั้นใช้ต่อไม่ได้
+		fully := math.Round((completed+refund.Amount)*100) == math.Round(target.Amount*100)
+		if fully {
+			if err := transition(tx, target.model(), target.id(), target.Status, entity.PaymentRefunded, map[string]interface{}{
+				"status_reason": "คืนเงินครบยอด",
+			}); err != nil {
+				return err
+			}
+			if target.PaymentID != nil {
+				if _, err := session.CancelByPayment(tx, *target.PaymentID, "คืนเงินครบยอด", session.SourceAPI); err != nil {
+					return err
+				}
+			}
+			target.Status = entity.PaymentRefunded
+		}
+
+		if err := tx.First(&refund, refund.ID).Error; err != nil {
+			return err
+		}
+		summary = refundSummary(tx, target)
+		return audit.Record(tx, "refund.completed", "Refund", refund.ID, approver, gin.H{
+			"target":                target.entityType(),
+			"target_id":             target.id(),
+			"amount":                refund.Amount,
+			"method":                refund.Method,
+			"bank_reference":        refund.BankReference,
+			"wallet_transaction_id": refund.WalletTransactionID,
+			"fully_refunded":        fully,
+			"summary":               summary,
+		})
+	})
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "คืนเงินสำเร็จ", "data": refund, "summary": summary})
+}
+
+// POST /refunds/:id/reject {reason} — ต้องระบุเหตุผล
+func RejectRefund(c *gin.Context) {
+	input, approver, err := bindRefund(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil || input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ไม่อนุมัติ"})
+		return
+	}
+
+	db := config.DB()
+	var refund entity.Refund
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&refund, c.Param("id")).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&entity.Refund{}).Where("id = ? AND status = ?", refund.ID, entity.RefundRequested).Updates(map[string]interface{}{
+			"status":         entity.RefundRejected,
+			"approved_by_id": approver,
+			"decided_at":     time.Now(),
+			"decision_note":  input.Reason,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefundDecided
+		}
+		if err := tx.First(&refund, refund.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, "refund.rejected", "Refund", refund.ID, approver, gin.H{
+			"amount": refund.Amount,
+			"method": refund.Method,
+			"reason": input.Reason,
+		})
+	})
+	if err != nil {
+		respondRefundError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ไม่อนุมัติคำขอคืนเงิน", "data": refund})
+}
+
+// GET /refunds?status=requested&payment_id=&payment_coin_id=&user_id=
+func ListRefunds(c *gin.Context) {
+	db := config.DB().Preload("User").Preload("ApprovedBy").Order("id DESC")
+	if v := c.Query("status"); v != "" {
+		db = db.Where("status IN ?", strings.Split(v, ","))
+	}
+	for _, key := range []string{"payment_id", "payment_coin_id", "user_id"} {
+		if v := c.Query(key); v != "" {
+			db = db.Where(key+" = ?", v)
+		}
+	}
+
+	var refunds []entity.Refund
+	if err := db.Find(&refunds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": refunds})
+}
+
+// ✅ แปลง error จากการคืนเงินเป็น HTTP response
+func respondRefundError(c *gin.Context, err error) {
+	var exceeds *RefundExceedsError
+	switch {
+	case errors.As(err, &exceeds):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": exceeds.Error(), "paid": exceeds.Paid, "refunded": exceeds.Refunded})
+	case errors.Is(err, ErrRefundMethod), errors.Is(err, ErrBankReference), errors.Is(err, ErrRefundNoUser):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRefundNotApproved), errors.Is(err, ErrRefundDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondReviewError(c, err)
+	}
+}
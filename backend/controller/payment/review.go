@@ -0,0 +1,386 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/coupon"
+	"github.com/Tawunchai/work-project/controller/slip"
+	tokening "github.com/Tawunchai/work-project/controller/token"
+	"github.com/Tawunchai/work-project/controller/wallet"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TransitionError เมื่อเปลี่ยนสถานะไม่ได้ (สถานะไม่อนุญาต หรือมีคนตัดสินรายการนี้ไปก่อนแล้ว)
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("ไม่สามารถเปลี่ยนสถานะจาก %s เป็น %s ได้", e.From, e.To)
+}
+
+// paymentReview คือสถานะเริ่มต้นของรายการหลังตรวจสลิป
+type paymentReview struct {
+	Status     string
+	Reason     string
+	VerifiedAt *time.Time
+	ReviewedAt *time.Time
+}
+
+// defaultAutoApprove ใช้เมื่อไม่ได้ตั้ง PAYMENT_AUTO_APPROVE (หรืออ่านค่าไม่ได้)
+// ค่าเริ่มต้นคือไม่อนุมัติเอง — สลิปที่ตรวจผ่านเป็น auto-verified รอ admin ยืนยันก่อนเริ่มชาร์จได้
+const defaultAutoApprove = false
+
+// ✅ อนุมัติรายการที่ตรวจสลิปผ่านทันทีหรือไม่ — ตั้ง PAYMENT_AUTO_APPROVE=true เพื่อข้ามการยืนยันของ admin
+func autoApprove() bool {
+	v, err := strconv.ParseBool(os.Getenv("PAYMENT_AUTO_APPROVE"))
+	if err != nil {
+		return defaultAutoApprove
+	}
+	return v
+}
+
+// reviewOf แปลงผลตรวจสลิปเป็นสถานะเริ่มต้น (err ต้องเป็น nil หรือกรณี slip.NeedsReview)
+func reviewOf(err error) paymentReview {
+	if err != nil {
+		return paymentReview{Status: entity.PaymentManualReview, Reason: err.Error()}
+	}
+	now := time.Now()
+	r := paymentReview{Status: entity.PaymentAutoVerified, VerifiedAt: &now}
+	if autoApprove() {
+		r.Status = entity.PaymentApproved
+		r.Reason = "ตรวจสลิปผ่านอัตโนมัติ"
+		r.ReviewedAt = &now
+	}
+	return r
+}
+
+// ✅ จ่ายด้วย Coin ไม่มีสลิปให้ตรวจ — อนุมัติทันทีพร้อมหัก coin
+func coinPaid() paymentReview {
+	now := time.Now()
+	return paymentReview{Status: entity.PaymentApproved, Reason: "ชำระด้วย Coin", ReviewedAt: &now}
+}
+
+// ✅ ส่วนลดคูปองครอบคลุมทั้งยอด ไม่มีเงินให้โอน — อนุมัติทันที
+func couponPaid() paymentReview {
+	now := time.Now()
+	return paymentReview{Status: entity.PaymentApproved, Reason: "ชำระด้วยคูปองเต็มจำนวน", ReviewedAt: &now}
+}
+
+// creditTopUp เติม coin ของ PaymentCoin ที่อนุมัติแล้วผ่าน ledger (รวมโบนัสคูปองถ้ามี)
+func creditTopUp(tx *gorm.DB, pc *entity.PaymentCoin, reviewerID *uint) error {
+	_, err := wallet.Post(tx, wallet.Posting{
+		UserID:        pc.UserID,
+		Kind:          wallet.KindTopUp,
+		Amount:        pc.Amount,
+		Note:          "เติม Coin " + pc.ReferenceNumber,
+		PaymentCoinID: &pc.ID,
+		CreatedByID:   reviewerID,
+	})
+	if err != nil || pc.BonusCoin <= 0 {
+		return err
+	}
+	// 🔸 โบนัสจากคูปอง แยกรายการเพื่อให้รายงานโปรโมชันแยกจากยอดเติมจริง
+	_, err = wallet.Post(tx, wallet.Posting{
+		UserID:        pc.UserID,
+		Kind:          wallet.KindPromotion,
+		Amount:        pc.BonusCoin,
+		Note:          "โบนัสคูปอง " + pc.CouponCode,
+		PaymentCoinID: &pc.ID,
+		CreatedByID:   reviewerID,
+	})
+	return err
+}
+
+// transition เปลี่ยนสถานะแบบมีเงื่อนไข (WHERE status = เดิม) กันการตัดสินซ้ำจาก request ที่มาพร้อมกัน
+func transition(tx *gorm.DB, model interface{}, id uint, from, to string, updates map[string]interface{}) error {
+	if !entity.CanTransitionPayment(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	updates["status"] = to
+	res := tx.Model(model).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// EnsurePaymentStatuses ให้รายการเก่าที่ยังไม่มีสถานะเป็น approved (เคยเติม coin / ออก token ไปแล้ว)
+func EnsurePaymentStatuses() {
+	db := config.DB()
+	db.Model(&entity.Payment{}).Where("status IS NULL OR status = ''").UpdateColumn("status", entity.PaymentApproved)
+	db.Model(&entity.PaymentCoin{}).Where("status IS NULL OR status = ''").UpdateColumn("status", entity.PaymentApproved)
+}
+
+// ============================================================================
+// 🔹 Review queue สำหรับ admin
+// ============================================================================
+
+// errMissingReviewer เมื่อไม่รู้ว่าใครเป็นผู้ตรวจ (ไม่ได้ผ่าน JwtAuth)
+var errMissingReviewer = errors.New("missing authorization")
+
+type reviewInput struct {
+	Reason          string  `json:"reason"`
+	ReferenceNumber string  `json:"reference_number"` // เลขอ้างอิงที่ admin อ่านจากสลิป (ถ้าระบบอ่านไม่ได้)
+	Amount          float64 `json:"amount"`           // PaymentCoin: ยอดที่ admin ยืนยัน (ไม่ระบุ = ใช้ยอดเดิม)
+}
+
+// ✅ อ่าน body (ไม่บังคับ) และหาผู้ตรวจจาก JwtAuth (route อยู่หลัง RequireRoles(Admin))
+func bindReview(c *gin.Context) (reviewInput, *uint, error) {
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		return input, nil, err
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	input.ReferenceNumber = strings.TrimSpace(input.ReferenceNumber)
+
+	if v, ok := c.Get("UserID"); ok {
+		if id, ok := v.(int); ok && id > 0 {
+			uid := uint(id)
+			return input, &uid, nil
+		}
+	}
+	return input, nil, errMissingReviewer
+}
+
+func decision(reviewerID *uint, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"reviewer_id":   reviewerID,
+		"status_reason": reason,
+		"reviewed_at":   time.Now(),
+	}
+}
+
+// ✅ แปลง error จากการตัดสินเป็น HTTP response
+func respondReviewError(c *gin.Context, err error) {
+	var terr *TransitionError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการ"})
+	case errors.As(err, &terr):
+		c.JSON(http.StatusConflict, gin.H{"error": terr.Error(), "status": terr.From})
+	case slip.IsDuplicate(err):
+		slip.RespondError(c, err)
+	default:
+		c.JSON(wallet.ErrorStatus(err), gin.H{"error": err.Error()})
+	}
+}
+
+// GET /payment-reviews?status=manual-review,auto-verified
+// รายการที่รอตรวจ (ค่าเริ่มต้น: pending, auto-verified, manual-review)
+func ListPaymentReviews(c *gin.Context) {
+	statuses := entity.ReviewQueueStatuses
+	if q := c.Query("status"); q != "" {
+		statuses = strings.Split(q, ",")
+	}
+
+	db := config.DB()
+	var payments []entity.Payment
+	if err := db.Preload("User").Preload("Method").Preload("Reviewer").
+		Where("status IN ?", statuses).Order("created_at").Find(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var coins []entity.PaymentCoin
+	if err := db.Preload("User").Preload("Reviewer").
+		Where("status IN ?", statuses).Order("created_at").Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payments": payments, "payment_coins": coins})
+}
+
+// POST /payments/:id/approve — อนุมัติแล้วออก token สำหรับการชาร์จ
+func ApprovePayment(c *gin.Context) {
+	input, reviewerID, err := bindReview(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	db := config.DB()
+	var payment entity.Payment
+	var session *entity.ChargingSession
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&payment, c.Param("id")).Error; err != nil {
+			return err
+		}
+		reason := input.Reason
+		if reason == "" {
+			reason = "อนุมัติโดยผู้ดูแล"
+		}
+		updates := decision(reviewerID, reason)
+		if payment.ReferenceNumber == "" && input.ReferenceNumber != "" {
+			if err := slip.RegisterReference(tx, input.ReferenceNumber, &payment.ID, nil); err != nil {
+				return err
+			}
+			updates["reference_number"] = input.ReferenceNumber
+		}
+		if err := transition(tx, &entity.Payment{}, payment.ID, payment.Status, entity.PaymentApproved, updates); err != nil {
+			return err
+		}
+		if _, err := coupon.Confirm(tx, &payment.ID, nil, payment.Amount); err != nil {
+			return err
+		}
+		if payment.UserID == nil {
+			return nil
+		}
+		var err error
+		session, err = tokening.IssueSession(tx, *payment.UserID, payment.ID)
+		return err
+	})
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	db.Preload("User").Preload("Method").First(&payment, payment.ID)
+	resp := gin.H{"message": "อนุมัติรายการสำเร็จ", "data": payment}
+	if session != nil {
+		resp["charging_token"] = session.Token
+		resp["expires_at"] = session.ExpiresAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /payments/:id/reject — ต้องระบุเหตุผล
+func RejectPayment(c *gin.Context) {
+	input, reviewerID, err := bindReview(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil || input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ไม่อนุมัติ"})
+		return
+	}
+
+	db := config.DB()
+	var payment entity.Payment
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&payment, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if err := transition(tx, &entity.Payment{}, payment.ID, payment.Status, entity.PaymentRejected, decision(reviewerID, input.Reason)); err != nil {
+			return err
+		}
+		return coupon.Void(tx, &payment.ID, nil)
+	})
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	db.Preload("User").Preload("Method").First(&payment, payment.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "ไม่อนุมัติรายการ", "data": payment})
+}
+
+// POST /payment-coins/:id/approve — อนุมัติแล้วเติม Coin ผ่าน ledger
+func ApprovePaymentCoin(c *gin.Context) {
+	input, reviewerID, err := bindReview(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil || input.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	db := config.DB()
+	var paymentCoin entity.PaymentCoin
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&paymentCoin, c.Param("id")).Error; err != nil {
+			return err
+		}
+		reason := input.Reason
+		if reason == "" {
+			reason = "อนุมัติโดยผู้ดูแล"
+		}
+		updates := decision(reviewerID, reason)
+		if paymentCoin.ReferenceNumber == "" && input.ReferenceNumber != "" {
+			if err := slip.RegisterReference(tx, input.ReferenceNumber, nil, &paymentCoin.ID); err != nil {
+				return err
+			}
+			updates["reference_number"] = input.ReferenceNumber
+			paymentCoin.ReferenceNumber = input.ReferenceNumber
+		}
+		if input.Amount > 0 {
+			updates["amount"] = input.Amount
+			paymentCoin.Amount = input.Amount
+		}
+		if paymentCoin.Amount <= 0 {
+			return wallet.ErrInvalidAmount
+		}
+		// 🔸 คิดโบนัสคูปองใหม่จากยอดที่ยืนยัน
+		bonus, err := coupon.Confirm(tx, nil, &paymentCoin.ID, paymentCoin.Amount)
+		if err != nil {
+			return err
+		}
+		if paymentCoin.CouponCode != "" {
+			updates["bonus_coin"] = bonus
+			paymentCoin.BonusCoin = bonus
+		}
+		if err := transition(tx, &entity.PaymentCoin{}, paymentCoin.ID, paymentCoin.Status, entity.PaymentApproved, updates); err != nil {
+			return err
+		}
+		return creditTopUp(tx, &paymentCoin, reviewerID)
+	})
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	db.Preload("User").First(&paymentCoin, paymentCoin.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "อนุมัติการเติม Coin สำเร็จ", "data": paymentCoin})
+}
+
+// POST /payment-coins/:id/reject — ต้องระบุเหตุผล
+func RejectPaymentCoin(c *gin.Context) {
+	input, reviewerID, err := bindReview(c)
+	if errors.Is(err, errMissingReviewer) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil || input.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ไม่อนุมัติ"})
+		return
+	}
+
+	db := config.DB()
+	var paymentCoin entity.PaymentCoin
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&paymentCoin, c.Param("id")).Error; err != nil {
+			return err
+		}
+		if err := transition(tx, &entity.PaymentCoin{}, paymentCoin.ID, paymentCoin.Status, entity.PaymentRejected, decision(reviewerID, input.Reason)); err != nil {
+			return err
+		}
+		return coupon.Void(tx, nil, &paymentCoin.ID)
+	})
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	db.Preload("User").First(&paymentCoin, paymentCoin.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "ไม่อนุมัติการเติม Coin", "data": paymentCoin})
+}
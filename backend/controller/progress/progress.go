@@ -0,0 +1,175 @@
+package progress
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/controller/billing"
+	"github.com/Tawunchai/work-project/controller/ocpp"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
+)
+
+// ✅ ค่า power ที่เก่ากว่านี้ถือว่าไม่ใช่ค่าปัจจุบัน (charger หยุดส่ง MeterValues)
+const powerStaleAfter = 5 * time.Minute
+
+// ✅ เหตุผลของเวลาที่คาดว่าจะชาร์จเสร็จ
+const (
+	FinishEnergyLimit = "energy_limit" // ครบพลังงานที่ซื้อไว้
+	FinishFullSoC     = "full_soc"     // แบตเต็ม 100%
+)
+
+// Progress คือความคืบหน้าของ session ที่แปลงจาก OCPP แล้ว (frontend ไม่ต้องแปลง frame เอง)
+type Progress struct {
+	SessionID    uint       `json:"session_id"`
+	Status       string     `json:"status"`
+	StatusReason string     `json:"status_reason"`
+	StartedAt    *time.Time `json:"started_at"`
+	EndedAt      *time.Time `json:"ended_at"`
+
+	ChargerID     string `json:"charger_id,omitempty"`
+	ConnectorID   int    `json:"connector_id,omitempty"`
+	TransactionID int    `json:"transaction_id,omitempty"`
+	Online        bool   `json:"online"`
+
+	ElapsedSeconds int64      `json:"elapsed_seconds"`
+	EnergyKWh      float64    `json:"energy_kwh"`
+	PowerKW        *float64   `json:"power_kw"` // nil = ไม่มีค่าล่าสุด
+	SoC            *float64   `json:"soc"`      // % (nil = charger ไม่ส่ง SoC)
+	MeterAt        *time.Time `json:"meter_at"` // เวลาของ sample ล่าสุด
+
+	CostSoFar float64 `json:"cost_so_far"`
+	Final     bool    `json:"final"` // true = คิดเงินแล้ว CostSoFar คือยอดจริง
+
+	LimitKWh        float64    `json:"limit_kwh"`    // พลังงานที่ซื้อไว้ตอนชำระเงิน
+	LimitAmount     float64    `json:"limit_amount"` // ยอดที่ชำระ (ก่อนหักส่วนลด)
+	RemainingKWh    float64    `json:"remaining_kwh"`
+	EstimatedFinish *time.Time `json:"estimated_finish"`
+	FinishBy        string     `json:"finish_by,omitempty"`
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// ✅ sample ล่าสุด / แรกสุดของ measurand (เฉพาะค่ารวมทุก phase)
+func sample(db *gorm.DB, txID uint, measurand, order string) *entity.MeterValue {
+	var v entity.MeterValue
+	if err := db.Where("charging_transaction_id = ? AND measurand = ? AND phase = ''", txID, measurand).
+		Order(order).First(&v).Error; err != nil {
+		return nil
+	}
+	return &v
+}
+
+// Build อ่านความคืบหน้าล่าสุดของ session จากฐานข้อมูล
+func Build(db *gorm.DB, cs entity.ChargingSession, now time.Time) Progress {
+	p := Progress{
+		SessionID:    cs.ID,
+		Status:       cs.Status,
+		StatusReason: cs.StatusReason,
+		StartedAt:    cs.StartedAt,
+		EndedAt:      cs.EndedAt,
+		Final:        !entity.IsSessionActive(cs.Status),
+	}
+
+	// 🔸 วงเงินที่ซื้อไว้ (เหมือน max_kwh / max_amount ใน token แบบลงชื่อ)
+	var payment entity.Payment
+	if err := db.First(&payment, cs.PaymentID).Error; err == nil {
+		p.LimitAmount = round2(payment.Amount + payment.Discount)
+	}
+	db.Model(&entity.EVChargingPayment{}).
+		Where("payment_id = ? AND charging_transaction_id IS NULL", cs.PaymentID).
+		Select("COALESCE(SUM(power), 0)").Scan(&p.LimitKWh)
+	p.LimitKWh = round3(p.LimitKWh)
+	p.RemainingKWh = p.LimitKWh
+
+	var tx entity.ChargingTransaction
+	if err := db.Where("charging_session_id = ?", cs.ID).Order("started_at DESC").First(&tx).Error; err != nil {
+		return p // ยังไม่ได้ StartTransaction
+	}
+	p.ChargerID = tx.ChargerID
+	p.ConnectorID = tx.ConnectorID
+	p.TransactionID = tx.TransactionID
+	p.Online = ocpp.IsConnected(tx.ChargerID)
+
+	end := now
+	if tx.StoppedAt != nil {
+		end = *tx.StoppedAt
+	} else if cs.EndedAt != nil {
+		end = *cs.EndedAt
+	}
+	if end.After(tx.StartedAt) {
+		p.ElapsedSeconds = int64(end.Sub(tx.StartedAt).Seconds())
+	}
+
+	p.EnergyKWh = round3(billing.DeliveredWh(tx) / 1000)
+	p.RemainingKWh = round3(math.Max(p.LimitKWh-p.EnergyKWh, 0))
+	if cost, err := billing.Estimate(tx, now); err == nil {
+		p.CostSoFar = cost
+	}
+	var billed int64
+	db.Model(&entity.EVChargingPayment{}).Where("charging_transaction_id = ?", tx.ID).Count(&billed)
+	p.Final = billed > 0
+
+	var last entity.MeterValue
+	if err := db.Where("charging_transaction_id = ?", tx.ID).Order("timestamp DESC").First(&last).Error; err == nil {
+		p.MeterAt = &last.Timestamp
+	}
+
+	// 🔸 power เฉพาะตอนยังชาร์จอยู่ (หยุดชั่วคราว = 0)
+	if tx.StoppedAt == nil {
+		if v := sample(db, tx.ID, ocpp.MeasurandPowerImport, "timestamp DESC"); v != nil && now.Sub(v.Timestamp) <= powerStaleAfter {
+			kw := v.Value
+			if !strings.EqualFold(v.Unit, "kW") {
+				kw /= 1000
+			}
+			if cs.Status == entity.SessionSuspended {
+				kw = 0
+			}
+			kw = round2(kw)
+			p.PowerKW = &kw
+		}
+	}
+
+	firstSoC := sample(db, tx.ID, ocpp.MeasurandSoC, "timestamp")
+	lastSoC := sample(db, tx.ID, ocpp.MeasurandSoC, "timestamp DESC")
+	if lastSoC != nil {
+		soc := lastSoC.Value
+		p.SoC = &soc
+	}
+
+	if cs.Status == entity.SessionCharging {
+		estimateFinish(&p, firstSoC, lastSoC, now)
+	}
+	return p
+}
+
+// estimateFinish เลือกเวลาที่เร็วกว่าระหว่างครบพลังงานที่ซื้อไว้ (ตาม power ปัจจุบัน) กับแบตเต็ม (ตามอัตรา SoC ที่ผ่านมา)
+func estimateFinish(p *Progress, firstSoC, lastSoC *entity.MeterValue, now time.Time) {
+	if p.PowerKW != nil && *p.PowerKW > 0 && p.RemainingKWh > 0 {
+		at := now.Add(time.Duration(p.RemainingKWh / *p.PowerKW * float64(time.Hour))).Truncate(time.Second)
+		p.EstimatedFinish, p.FinishBy = &at, FinishEnergyLimit
+	}
+
+	if firstSoC == nil || lastSoC == nil || lastSoC.Value >= 100 || lastSoC.Value <= firstSoC.Value {
+		return
+	}
+	secs := lastSoC.Timestamp.Sub(firstSoC.Timestamp).Seconds()
+	if secs <= 0 {
+		return
+	}
+	rate := (lastSoC.Value - firstSoC.Value) / secs // % ต่อวินาที
+	at := lastSoC.Timestamp.Add(time.Duration((100 - lastSoC.Value) / rate * float64(time.Second))).Truncate(time.Second)
+	if at.Before(now) {
+		at = now.Truncate(time.Second)
+	}
+	if p.EstimatedFinish == nil || at.Before(*p.EstimatedFinish) {
+		p.EstimatedFinish, p.FinishBy = &at, FinishFullSoC
+	}
+}
@@ -0,0 +1,230 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/controller/tokensign"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/chargetoken"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+// ✅ ชนิดของ event ที่ส่งใน stream
+const (
+	EventSnapshot = "snapshot" // ค่าแรกหลังเชื่อมต่อ
+	EventProgress = "progress" // มิเตอร์ใหม่ / เวลาผ่านไป
+	EventStatus   = "status"   // สถานะ session เปลี่ยน
+	EventEnded    = "ended"    // ค่าสุดท้าย — ปิดการเชื่อมต่อหลังส่ง
+)
+
+// ✅ จังหวะการส่ง
+const (
+	tickInterval = 5 * time.Second        // ส่งซ้ำแม้ไม่มีเหตุการณ์ (เวลา / ค่าใช้จ่ายตามนาที)
+	settleDelay  = 300 * time.Millisecond // รอให้ข้อมูลชุดเดียวกันเข้าครบ (และ commit) ก่อนอ่านใหม่
+	billingGrace = 30 * time.Second       // completed แล้วรอคิดเงินได้นานเท่านี้ก่อนส่ง ended
+)
+
+// Event คือข้อความหนึ่งชิ้นใน stream
+type Event struct {
+	Type string    `json:"type"`
+	Seq  int       `json:"seq"`
+	At   time.Time `json:"at"`
+	Data Progress  `json:"data"`
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ✅ token จาก ?token= (WebSocket / EventSource ตั้ง header เองไม่ได้) หรือ Authorization: Bearer
+func requestToken(c *gin.Context) string {
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+	return strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+}
+
+// authenticate ตรวจว่า token การชาร์จเป็นของ session นี้ — มีแต่เจ้าของ session ที่ถือ token
+// token ที่หมดอายุ / ถูก revoke ยังผ่านได้ (session จบแล้ว จะได้แค่ค่าสุดท้าย)
+func authenticate(db *gorm.DB, sessionID uint, token string) (*entity.ChargingSession, int, string) {
+	if token == "" {
+		return nil, http.StatusUnauthorized, "missing token"
+	}
+
+	if chargetoken.IsSigned(token) {
+		claims, err := tokensign.Verify(db, token)
+		if err != nil && !errors.Is(err, chargetoken.ErrExpired) && !errors.Is(err, tokensign.ErrRevoked) {
+			return nil, http.StatusForbidden, "invalid token"
+		}
+		if claims.SessionID != sessionID {
+			return nil, http.StatusForbidden, "token ไม่ใช่ของ session นี้"
+		}
+	}
+
+	var cs entity.ChargingSession
+	if err := db.Where("token = ?", token).First(&cs).Error; err != nil {
+		return nil, http.StatusForbidden, "invalid token"
+	}
+	if cs.ID != sessionID {
+		return nil, http.StatusForbidden, "token ไม่ใช่ของ session นี้"
+	}
+	session.ExpireIfDue(db, &cs)
+	return &cs, 0, ""
+}
+
+func parseSession(c *gin.Context) (*entity.ChargingSession, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return nil, false
+	}
+	cs, status, msg := authenticate(config.DB(), uint(id), requestToken(c))
+	if cs == nil {
+		c.JSON(status, gin.H{"error": msg})
+		return nil, false
+	}
+	return cs, true
+}
+
+// GET /charging-sessions/:id/progress?token= — ความคืบหน้าครั้งเดียว (สำหรับ polling)
+func GetSessionProgress(c *gin.Context) {
+	cs, ok := parseSession(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, Build(config.DB(), *cs, time.Now()))
+}
+
+// GET /charging-sessions/:id/stream?token=
+// WebSocket (ถ้าขอ upgrade) หรือ Server-Sent Events — ส่ง Event เป็น JSON จนกว่า session จะจบ
+func StreamSession(c *gin.Context) {
+	cs, ok := parseSession(c)
+	if !ok {
+		return
+	}
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		streamSSE(c, cs.ID)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		fmt.Println("❌ Upgrade progress stream error:", err)
+		return
+	}
+	defer conn.Close()
+
+	// 🔸 อ่านทิ้งเพื่อจับตอน client ปิดการเชื่อมต่อ
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	run(ctx, cs.ID, func(ev Event) error {
+		return conn.WriteJSON(ev)
+	})
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"), time.Now().Add(time.Second))
+}
+
+func streamSSE(c *gin.Context, sessionID uint) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	run(ctx, sessionID, func(ev Event) error {
+		c.SSEvent(ev.Type, ev)
+		c.Writer.Flush()
+		return ctx.Err()
+	})
+}
+
+// run ส่ง snapshot แล้วส่งใหม่ทุกครั้งที่ session เปลี่ยน (และทุก tickInterval) จนส่ง ended หรือ ctx ถูกยกเลิก
+func run(ctx context.Context, sessionID uint, send func(Event) error) {
+	changed, stop := session.Watch(sessionID)
+	defer stop()
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	seq := 0
+	lastStatus := ""
+	push := func() bool {
+		db := config.DB()
+		var cs entity.ChargingSession
+		if err := db.First(&cs, sessionID).Error; err != nil {
+			return true
+		}
+		session.ExpireIfDue(db, &cs)
+
+		now := time.Now()
+		p := Build(db, cs, now)
+		typ := EventProgress
+		switch {
+		case ended(cs, p, now):
+			typ = EventEnded
+		case seq == 0:
+			typ = EventSnapshot
+		case cs.Status != lastStatus:
+			typ = EventStatus
+		}
+		seq++
+		lastStatus = cs.Status
+
+		if err := send(Event{Type: typ, Seq: seq, At: now, Data: p}); err != nil {
+			return true
+		}
+		return typ == EventEnded
+	}
+
+	if push() {
+		return
+	}
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			if settle == nil {
+				settle = time.After(settleDelay)
+			}
+			continue
+		case <-settle:
+			settle = nil
+		case <-ticker.C:
+		}
+		if push() {
+			return
+		}
+	}
+}
+
+// ended บอกว่าส่งค่าสุดท้ายได้แล้ว — session จบ และถ้า completed ต้องคิดเงินเสร็จก่อน (หรือรอนานเกิน billingGrace)
+func ended(cs entity.ChargingSession, p Progress, now time.Time) bool {
+	if entity.IsSessionActive(cs.Status) {
+		return false
+	}
+	if cs.Status != entity.SessionCompleted || p.Final {
+		return true
+	}
+	return cs.EndedAt == nil || now.Sub(*cs.EndedAt) > billingGrace
+}
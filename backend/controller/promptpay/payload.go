@@ -0,0 +1,119 @@
+package promptpay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ✅ ค่าคงที่ตามมาตรฐาน Thai QR Payment (EMVCo Merchant Presented Mode)
+const (
+	promptPayAID     = "A000000677010111"
+	currencyTHB      = "764"
+	countryTH        = "TH"
+	staticQR         = "11"
+	dynamicQR        = "12"
+	maxMerchantName  = 25
+	maxReferenceText = 25
+)
+
+var ErrInvalidTarget = errors.New("PromptPay ต้องเป็นเบอร์โทร 10 หลัก, เลขบัตรประชาชน/เลขผู้เสียภาษี 13 หลัก หรือ e-Wallet 15 หลัก")
+
+// ✅ TLV: id (2 หลัก) + ความยาว (2 หลัก) + ค่า
+func tlv(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// CRC16 คำนวณ CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF) ที่ EMVCo ใช้
+func CRC16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// ✅ เก็บเฉพาะตัวเลข
+func digitsOnly(v string) string {
+	var b strings.Builder
+	for _, r := range v {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ✅ EMVCo รองรับเฉพาะตัวอักษร ASCII ที่พิมพ์ได้
+func asciiText(v string, limit int) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(v) {
+		if r >= 0x20 && r <= 0x7E {
+			b.WriteRune(r)
+		}
+	}
+	out := strings.TrimSpace(b.String())
+	if len(out) > limit {
+		out = strings.TrimSpace(out[:limit])
+	}
+	return out
+}
+
+// merchantAccount สร้าง tag 29 จากเบอร์โทร / เลขประจำตัว / e-Wallet
+func merchantAccount(target string) (string, error) {
+	id := digitsOnly(target)
+	var sub string
+	switch {
+	case len(id) == 10 && id[0] == '0':
+		// เบอร์โทร 0812345678 → 0066812345678
+		sub = tlv("01", "0066"+id[1:])
+	case len(id) == 13:
+		sub = tlv("02", id)
+	case len(id) == 15:
+		sub = tlv("03", id)
+	default:
+		return "", ErrInvalidTarget
+	}
+	return tlv("29", tlv("00", promptPayAID)+sub), nil
+}
+
+// Payload สร้างข้อความ PromptPay QR
+// amount <= 0 จะได้ QR แบบไม่ระบุยอด (static) ให้ลูกค้ากรอกเอง
+func Payload(target string, amount float64, merchantName, reference string) (string, error) {
+	account, err := merchantAccount(target)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(tlv("00", "01"))
+	if amount > 0 {
+		b.WriteString(tlv("01", dynamicQR))
+	} else {
+		b.WriteString(tlv("01", staticQR))
+	}
+	b.WriteString(account)
+	b.WriteString(tlv("53", currencyTHB))
+	if amount > 0 {
+		b.WriteString(tlv("54", fmt.Sprintf("%.2f", amount)))
+	}
+	b.WriteString(tlv("58", countryTH))
+	if name := asciiText(merchantName, maxMerchantName); name != "" {
+		b.WriteString(tlv("59", name))
+	}
+	if ref := asciiText(reference, maxReferenceText); ref != "" {
+		b.WriteString(tlv("62", tlv("05", ref)))
+	}
+
+	// 🔸 CRC คิดรวม "6304" ด้วย
+	b.WriteString("6304")
+	payload := b.String()
+	return payload + fmt.Sprintf("%04X", CRC16(payload)), nil
+}
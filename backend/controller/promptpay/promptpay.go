@@ -0,0 +1,114 @@
+package promptpay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/qrcode"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	qrScale  = 8
+	qrBorder = 4
+)
+
+// ✅ บัญชีรับเงินของระบบ (Bank แถวแรก)
+func receivingBank() (*entity.Bank, error) {
+	var bank entity.Bank
+	if err := config.DB().Order("id").First(&bank).Error; err != nil {
+		return nil, fmt.Errorf("ยังไม่ได้ตั้งค่าบัญชี PromptPay")
+	}
+	if bank.PromptPay == "" {
+		return nil, fmt.Errorf("ยังไม่ได้ตั้งค่าบัญชี PromptPay")
+	}
+	return &bank, nil
+}
+
+// BankPayload สร้าง payload จากบัญชีรับเงินของระบบ
+func BankPayload(amount float64, reference string) (string, error) {
+	bank, err := receivingBank()
+	if err != nil {
+		return "", err
+	}
+	return Payload(bank.PromptPay, amount, bank.Manager, reference)
+}
+
+// ✅ ตอบเป็น PNG หรือ JSON (?format=json) ตามที่ขอ
+func respondQR(c *gin.Context, payload string, amount float64) {
+	code, err := qrcode.EncodeString(payload, qrcode.Medium)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scale := qrScale
+	if v := c.Query("scale"); v != "" {
+		if s, err := strconv.Atoi(v); err == nil && s >= 1 && s <= 32 {
+			scale = s
+		}
+	}
+	img, err := code.PNG(scale, qrBorder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้างรูป QR ไม่สำเร็จ"})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"payload": payload,
+			"amount":  amount,
+			"image":   "data:image/png;base64," + base64.StdEncoding.EncodeToString(img),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", img)
+}
+
+// GET /promptpay/qr?amount=&ref=&format=json
+// QR สำหรับโอนเข้าบัญชีของระบบตามยอดที่ระบุ
+func GetPromptPayQR(c *gin.Context) {
+	amount := 0.0
+	if v := c.Query("amount"); v != "" {
+		a, err := strconv.ParseFloat(v, 64)
+		if err != nil || a < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "จำนวนเงินไม่ถูกต้อง"})
+			return
+		}
+		amount = a
+	}
+
+	payload, err := BankPayload(amount, c.Query("ref"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondQR(c, payload, amount)
+}
+
+// GET /payments/:id/promptpay-qr?format=json
+// QR ตามยอดของ Payment ที่สร้างไว้แล้ว
+func GetPaymentPromptPayQR(c *gin.Context) {
+	var payment entity.Payment
+	if err := config.DB().First(&payment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Payment"})
+		return
+	}
+
+	ref := payment.ReferenceNumber
+	if ref == "" {
+		ref = fmt.Sprintf("PAY%d", payment.ID)
+	}
+	payload, err := BankPayload(payment.Amount, ref)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondQR(c, payload, payment.Amount)
+}
@@ -0,0 +1,354 @@
+package qrstart
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/billing"
+	"github.com/Tawunchai/work-project/controller/ocpp"
+	"github.com/Tawunchai/work-project/controller/payment"
+	"github.com/Tawunchai/work-project/controller/tariff"
+	tokening "github.com/Tawunchai/work-project/controller/token"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/qrcode"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	qrScale      = 8
+	qrBorder     = 4
+	deepLinkPath = "/user/qr-start"
+
+	// ✅ secret สั้นกว่านี้เดาได้ง่าย (สติกเกอร์ใช้ได้ตลอด ปลอมได้ตลอดเช่นกัน)
+	minSecretLen = 32
+)
+
+var (
+	// ErrInvalidLink เมื่อลิงก์ไม่ครบหรือลายเซ็นไม่ตรง
+	ErrInvalidLink = errors.New("ลิงก์ QR ไม่ถูกต้อง")
+	// ErrNotConfigured เมื่อยังไม่ได้ตั้ง CONNECTOR_QR_SECRET / APP_BASE_URL — ไม่ออกและไม่ตรวจลิงก์เลย
+	ErrNotConfigured = errors.New("ยังไม่ได้ตั้งค่า CONNECTOR_QR_SECRET (อย่างน้อย 32 ตัวอักษร) และ APP_BASE_URL สำหรับ QR หัวชาร์จ")
+)
+
+// startableStatuses คือสถานะหัวชาร์จที่สั่งเริ่มชาร์จได้
+var startableStatuses = []string{ocpp.StatusAvailable, ocpp.StatusPreparing}
+
+func secret() ([]byte, error) {
+	v := strings.TrimSpace(os.Getenv("CONNECTOR_QR_SECRET"))
+	if len(v) < minSecretLen {
+		return nil, ErrNotConfigured
+	}
+	return []byte(v), nil
+}
+
+func baseURL() (string, error) {
+	v := strings.TrimRight(strings.TrimSpace(os.Getenv("APP_BASE_URL")), "/")
+	if v == "" {
+		return "", ErrNotConfigured
+	}
+	return v, nil
+}
+
+// Signature ลายเซ็นของหัวชาร์จ (HMAC-SHA256) — ค่าเดิมเสมอตราบที่ secret ไม่เปลี่ยน สติกเกอร์จึงใช้ได้ตลอด
+func Signature(chargerID string, connectorNo int) (string, error) {
+	key, err := secret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("connector:" + chargerID + ":" + strconv.Itoa(connectorNo)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// Link คือ deep link ที่พิมพ์ลง QR ของหัวชาร์จ
+func Link(chargerID string, connectorNo int) (string, error) {
+	base, err := baseURL()
+	if err != nil {
+		return "", err
+	}
+	sig, err := Signature(chargerID, connectorNo)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("cp", chargerID)
+	q.Set("c", strconv.Itoa(connectorNo))
+	q.Set("sig", sig)
+	return base + deepLinkPath + "?" + q.Encode(), nil
+}
+
+// ✅ อ่าน cp / c / sig (query หรือ form) แล้วหา Connector ของลิงก์นั้น
+func connectorFromLink(c *gin.Context) (*entity.Connector, error) {
+	chargerID := c.Query("cp")
+	if chargerID == "" {
+		chargerID = c.PostForm("cp")
+	}
+	noStr := c.Query("c")
+	if noStr == "" {
+		noStr = c.PostForm("c")
+	}
+	sig := c.Query("sig")
+	if sig == "" {
+		sig = c.PostForm("sig")
+	}
+
+	no, err := strconv.Atoi(noStr)
+	if chargerID == "" || err != nil || no < 1 || sig == "" {
+		return nil, ErrInvalidLink
+	}
+	want, err := Signature(chargerID, no)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return nil, ErrInvalidLink
+	}
+
+	var connector entity.Connector
+	err = config.DB().Preload("ChargePoint.EVCabinet").Preload("EVcharging").
+		Joins("JOIN charge_points ON charge_points.id = connectors.charge_point_id AND charge_points.deleted_at IS NULL").
+		Where("charge_points.charger_id = ? AND connectors.connector_no = ?", chargerID, no).
+		First(&connector).Error
+	if err != nil {
+		return nil, err
+	}
+	return &connector, nil
+}
+
+func respondLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหัวชาร์จของลิงก์นี้"})
+}
+
+func isStartable(status string) bool {
+	for _, s := range startableStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GET /connectors/:id/qr?scale=&format=json
+// QR สำหรับติดที่หัวชาร์จ (PNG) — format=json คืน url และรูปแบบ data URI
+func GetConnectorQR(c *gin.Context) {
+	var connector entity.Connector
+	if err := config.DB().Preload("ChargePoint").First(&connector, c.Param("id")).Error; err != nil || connector.ChargePoint == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบหัวชาร์จ"})
+		return
+	}
+	if connector.ConnectorNo < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "connector 0 คือทั้งเครื่อง ไม่มี QR"})
+		return
+	}
+
+	link, err := Link(connector.ChargePoint.ChargerID, connector.ConnectorNo)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	code, err := qrcode.EncodeString(link, qrcode.Medium)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	scale := qrScale
+	if v := c.Query("scale"); v != "" {
+		if s, err := strconv.Atoi(v); err == nil && s >= 1 && s <= 32 {
+			scale = s
+		}
+	}
+	img, err := code.PNG(scale, qrBorder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้างรูป QR ไม่สำเร็จ"})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"url":          link,
+			"charger_id":   connector.ChargePoint.ChargerID,
+			"connector_no": connector.ConnectorNo,
+			"image":        "data:image/png;base64," + base64.StdEncoding.EncodeToString(img),
+		})
+		return
+	}
+	c.Header("Content-Disposition", `inline; filename="`+connector.ChargePoint.ChargerID+`-`+strconv.Itoa(connector.ConnectorNo)+`.png"`)
+	c.Data(http.StatusOK, "image/png", img)
+}
+
+// GET /qr-start/resolve?cp=&c=&sig=
+// ข้อมูลหลังสแกน: ตู้ / หัวชาร์จ / สถานะสด / ราคา
+func ResolveQR(c *gin.Context) {
+	connector, err := connectorFromLink(c)
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+
+	point := connector.ChargePoint
+	online := ocpp.IsConnected(point.ChargerID)
+	resp := gin.H{
+		"connector_id":      connector.ID,
+		"charger_id":        point.ChargerID,
+		"connector_no":      connector.ConnectorNo,
+		"status":            connector.Status,
+		"error_code":        connector.ErrorCode,
+		"status_updated_at": connector.StatusUpdatedAt,
+		"online":            online,
+		"available":         online && isStartable(connector.Status) && connector.EVcharging != nil,
+		"cabinet":           point.EVCabinet,
+		"pay_and_start":     "/qr-start/pay-and-start",
+	}
+
+	if connector.EVcharging != nil {
+		now := time.Now()
+		t := tariff.Resolve(point.EVCabinetID, *connector.EVcharging, now)
+		label, perKWh, perMinute := tariff.RateAt(t, now)
+		resp["evcharging"] = gin.H{
+			"ID":    connector.EVcharging.ID,
+			"Name":  connector.EVcharging.Name,
+			"Price": connector.EVcharging.Price,
+		}
+		resp["tariff"] = gin.H{
+			"id":          t.ID,
+			"name":        t.Name,
+			"band":        label,
+			"per_kwh":     perKWh,
+			"per_minute":  perMinute,
+			"session_fee": t.SessionFee,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// paidNotStarted ตอบเมื่อเก็บเงินไปแล้วแต่เริ่มชาร์จไม่สำเร็จ — ต้องเป็น 2xx เสมอ
+// (Idempotent ลบ key เมื่อ status ≥ 500 ถ้าตอบ error ไป client ที่ลองใหม่ด้วย key เดิมจะถูกเก็บเงินซ้ำ)
+func paidNotStarted(c *gin.Context, result gin.H, reason interface{}) {
+	result["message"] = "ชำระเงินแล้ว แต่ยังสั่งเริ่มชาร์จไม่สำเร็จ"
+	result["remote_start_error"] = reason
+	c.JSON(http.StatusAccepted, result)
+}
+
+// POST /qr-start/pay-and-start (multipart: cp, c, sig, method_id, amount, coupon_code, picture)
+// ชำระเงิน → จองพลังงานของหัวนี้ → ออก token → RemoteStart ในครั้งเดียว
+// route อยู่หลัง JwtAuth — ผู้จ่ายเงินคือเจ้าของ JWT
+// 201 = เริ่มชาร์จแล้ว, 202 = สลิปรอตรวจ หรือจ่ายแล้วแต่เริ่มไม่สำเร็จ (มี remote_start_error)
+func PayAndStart(c *gin.Context) {
+	connector, err := connectorFromLink(c)
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+	point := connector.ChargePoint
+
+	amount, err := strconv.ParseFloat(c.PostForm("amount"), 64)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "จำนวนเงินไม่ถูกต้อง"})
+		return
+	}
+	userID := c.GetInt("UserID")
+	if userID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+		return
+	}
+	methodID, err := strconv.ParseUint(c.PostForm("method_id"), 10, 32)
+	if err != nil || methodID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Method ID ไม่ถูกต้อง"})
+		return
+	}
+
+	// ❌ ตรวจก่อนเก็บเงิน — หัวต้องพร้อมและรู้ราคา
+	if connector.EVcharging == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "หัวชาร์จนี้ยังไม่ได้ผูกกับ EVcharging"})
+		return
+	}
+	if !ocpp.IsConnected(point.ChargerID) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "เครื่องชาร์จไม่ได้เชื่อมต่อ"})
+		return
+	}
+	if !isStartable(connector.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "หัวชาร์จไม่ว่าง", "status": connector.Status})
+		return
+	}
+
+	// 1) Payment (ตรวจสลิป / หัก Coin / คูปอง)
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	pay := payment.CreateCharge(c, payment.ChargeInput{
+		Date:       today,
+		Amount:     amount,
+		UserID:     uint(userID),
+		MethodID:   uint(methodID),
+		CabinetID:  point.EVCabinetID,
+		CouponCode: c.PostForm("coupon_code"),
+	})
+	if pay == nil {
+		return
+	}
+
+	// 2) จองพลังงานทั้งหมดไว้ที่หัวนี้
+	db := config.DB()
+	line, err := billing.QuoteLine(pay.ID, connector.EVcharging.ID, 100)
+	if err == nil {
+		err = db.Create(line).Error
+	}
+	if err != nil {
+		paidNotStarted(c, gin.H{"payment": pay}, "ไม่สามารถบันทึกรายการชาร์จได้: "+err.Error())
+		return
+	}
+
+	// 🔸 สลิปรอ admin ตรวจ → ยังเริ่มชาร์จไม่ได้ (อนุมัติแล้วจะได้ token ไปสั่ง remote-start)
+	if pay.Status != entity.PaymentApproved {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "ได้รับสลิปแล้ว รอผู้ดูแลตรวจสอบก่อนเริ่มชาร์จ",
+			"payment": pay,
+		})
+		return
+	}
+
+	// 3) Session + token
+	cs, err := tokening.IssueSession(db, uint(userID), pay.ID)
+	if err != nil {
+		paidNotStarted(c, gin.H{"payment": pay}, "cannot create session")
+		return
+	}
+	result := gin.H{
+		"payment":        pay,
+		"session_id":     cs.ID,
+		"charging_token": cs.Token,
+		"expires_at":     cs.ExpiresAt,
+		"charger_id":     point.ChargerID,
+		"connector_id":   connector.ConnectorNo,
+	}
+
+	// 4) RemoteStart — ไม่สำเร็จยังคืน token ให้ลองใหม่ผ่าน /charging-session/remote-start
+	resp, err := tokening.StartRemote(c.Request.Context(), db, cs, point.ChargerID, connector.ConnectorNo)
+	if err != nil {
+		_, body := tokening.RemoteStartError(resp, err)
+		if status, ok := body["status"]; ok {
+			result["status"] = status
+		}
+		paidNotStarted(c, result, body["error"])
+		return
+	}
+
+	result["message"] = "ชำระเงินและส่งคำสั่งเริ่มชาร์จสำเร็จ"
+	result["status"] = resp.Status
+	c.JSON(http.StatusCreated, result)
+}
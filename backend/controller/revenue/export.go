@@ -0,0 +1,196 @@
+package revenue
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/services/xlsx"
+	"github.com/gin-gonic/gin"
+)
+
+// columns คือหัวตารางของ Row (คอลัมน์เติม Coin มีเฉพาะการจัดกลุ่มตามช่วงเวลา)
+func columns(groupBy string) []string {
+	cols := []string{"key", "label", "payments", "gross", "discount", "refunds", "net", "kwh"}
+	if isPeriod(groupBy) {
+		cols = append(cols, "topups", "topup_refunds", "bonus_coin")
+	}
+	return cols
+}
+
+func (r Row) values(groupBy string) []interface{} {
+	v := []interface{}{r.Key, r.Label, r.Payments, r.Gross, r.Discount, r.Refunds, r.Net, r.KWh}
+	if isPeriod(groupBy) {
+		v = append(v, r.TopUps, r.TopUpRefunds, r.BonusCoin)
+	}
+	return v
+}
+
+func (s Summary) values() [][2]interface{} {
+	return [][2]interface{}{
+		{"payments", s.Payments},
+		{"gross", s.Gross},
+		{"discount", s.Discount},
+		{"refunds", s.Refunds},
+		{"net", s.Net},
+		{"net_qr", s.NetQR},
+		{"net_coin", s.NetCoin},
+		{"kwh", s.KWh},
+		{"topups", s.TopUps},
+		{"topup_refunds", s.TopUpRefunds},
+		{"bonus_coin", s.BonusCoin},
+		{"wallet_liability", s.WalletLiability},
+		{"liability_as_of", s.LiabilityAsOf.In(bangkok)},
+		{"unverified_payments", s.UnverifiedPayments},
+		{"unverified_payment_amount", s.UnverifiedPaymentAmount},
+		{"unverified_topups", s.UnverifiedTopUps},
+		{"unverified_topup_amount", s.UnverifiedTopUpAmount},
+	}
+}
+
+func csvValue(v interface{}) string {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	case *uint:
+		if t == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*t), 10)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// CSV ตารางหลักตามด้วยบรรทัดว่างและยอดสรุป (มี BOM ให้ Excel อ่านภาษาไทยได้)
+func (rep *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+
+	w.Write(columns(rep.GroupBy))
+	for _, r := range rep.Rows {
+		vals := r.values(rep.GroupBy)
+		record := make([]string, len(vals))
+		for i, v := range vals {
+			record[i] = csvValue(v)
+		}
+		w.Write(record)
+	}
+	w.Write(nil)
+	w.Write([]string{"summary", rep.From + " - " + rep.To})
+	for _, kv := range rep.Summary.values() {
+		w.Write([]string{csvValue(kv[0]), csvValue(kv[1])})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// XLSX แยกเป็น 3 แผ่น: ยอดตามกลุ่ม, สรุป, สลิปที่รอตรวจ
+func (rep *Report) XLSX() ([]byte, error) {
+	wb := xlsx.New()
+
+	rows := wb.AddSheet("Revenue by " + rep.GroupBy)
+	rows.AddHeader(columns(rep.GroupBy)...)
+	for _, r := range rep.Rows {
+		rows.AddRow(r.values(rep.GroupBy)...)
+	}
+
+	summary := wb.AddSheet("Summary")
+	summary.AddHeader("item", "value")
+	summary.AddRow("from", rep.From)
+	summary.AddRow("to", rep.To)
+	summary.AddRow("generated_at", rep.GeneratedAt.In(bangkok))
+	for _, kv := range rep.Summary.values() {
+		summary.AddRow(kv[0], kv[1])
+	}
+
+	pending := wb.AddSheet("Unverified slips")
+	pending.AddHeader("type", "id", "user_id", "amount", "reference_number", "status", "status_reason", "created_at")
+	for _, u := range rep.Unverified {
+		var userID interface{}
+		if u.UserID != nil {
+			userID = *u.UserID
+		}
+		pending.AddRow(u.Type, u.ID, userID, u.Amount, u.ReferenceNumber, u.Status, u.StatusReason, u.CreatedAt.In(bangkok))
+	}
+
+	return wb.Bytes()
+}
+
+// parseRange อ่าน from / to (YYYY-MM-DD ตามเวลาไทย, รวมวัน to) — ค่าเริ่มต้นคือต้นเดือนถึงวันนี้
+func parseRange(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now().In(bangkok)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, bangkok)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, bangkok)
+	to := today
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, bangkok)
+		if err != nil {
+			return from, to, fmt.Errorf("รูปแบบวันที่ from ไม่ถูกต้อง ต้องเป็น YYYY-MM-DD")
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, bangkok)
+		if err != nil {
+			return from, to, fmt.Errorf("รูปแบบวันที่ to ไม่ถูกต้อง ต้องเป็น YYYY-MM-DD")
+		}
+		to = t
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("วันที่ to ต้องไม่น้อยกว่า from")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// GET /reports/revenue?group_by=day|week|month|cabinet|method|tariff&from=&to=&format=json|csv|xlsx
+func GetRevenueReport(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", GroupDay)
+	if !ValidGroup(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by ต้องเป็น day, week, month, cabinet, method หรือ tariff"})
+		return
+	}
+	from, to, err := parseRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rep, err := Build(config.DB(), groupBy, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := fmt.Sprintf("revenue-%s-%s-%s", groupBy, rep.From, rep.To)
+	switch c.DefaultQuery("format", "json") {
+	case "csv":
+		data, err := rep.CSV()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "xlsx":
+		data, err := rep.XLSX()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.xlsx"`)
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+	case "json":
+		c.JSON(http.StatusOK, rep)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format ต้องเป็น json, csv หรือ xlsx"})
+	}
+}
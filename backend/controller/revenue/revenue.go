@@ -0,0 +1,438 @@
+package revenue
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/controller/wallet"
+	"github.com/Tawunchai/work-project/entity"
+	"gorm.io/gorm"
+)
+
+// ✅ การจัดกลุ่มรายงาน
+const (
+	GroupDay     = "day"
+	GroupWeek    = "week"
+	GroupMonth   = "month"
+	GroupCabinet = "cabinet"
+	GroupMethod  = "method"
+	GroupTariff  = "tariff"
+)
+
+var groups = []string{GroupDay, GroupWeek, GroupMonth, GroupCabinet, GroupMethod, GroupTariff}
+
+// ValidGroup บอกว่า group_by ถูกต้อง
+func ValidGroup(g string) bool {
+	for _, v := range groups {
+		if v == g {
+			return true
+		}
+	}
+	return false
+}
+
+func isPeriod(g string) bool {
+	return g == GroupDay || g == GroupWeek || g == GroupMonth
+}
+
+var bangkok = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Bangkok"); err == nil {
+		return loc
+	}
+	return time.FixedZone("ICT", 7*60*60)
+}()
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Row คือยอดของหนึ่งกลุ่ม
+// Gross = ยอดก่อนส่วนลด, Net = Gross - Discount - Refunds (รายได้ค่าชาร์จสุทธิ)
+// TopUps / TopUpRefunds / BonusCoin มีเฉพาะการจัดกลุ่มตามช่วงเวลา (เงินเติม Coin เป็นหนี้สิน ไม่ใช่รายได้)
+type Row struct {
+	Key          string  `json:"key"`
+	Label        string  `json:"label"`
+	Payments     int     `json:"payments"`
+	Gross        float64 `json:"gross"`
+	Discount     float64 `json:"discount"`
+	Refunds      float64 `json:"refunds"`
+	Net          float64 `json:"net"`
+	KWh          float64 `json:"kwh"`
+	TopUps       float64 `json:"topups,omitempty"`
+	TopUpRefunds float64 `json:"topup_refunds,omitempty"`
+	BonusCoin    float64 `json:"bonus_coin,omitempty"`
+
+	sort string
+}
+
+// Summary คือยอดรวมของช่วงเวลา พร้อมยอดคงค้างที่ใช้กระทบยอด
+type Summary struct {
+	Payments     int     `json:"payments"`
+	Gross        float64 `json:"gross"`
+	Discount     float64 `json:"discount"`
+	Refunds      float64 `json:"refunds"`
+	Net          float64 `json:"net"`
+	NetQR        float64 `json:"net_qr"`   // รายได้ที่รับเป็นเงินโอน
+	NetCoin      float64 `json:"net_coin"` // รายได้ที่ตัดจาก Coin (ลดหนี้สิน wallet)
+	KWh          float64 `json:"kwh"`
+	TopUps       float64 `json:"topups"`
+	TopUpRefunds float64 `json:"topup_refunds"`
+	BonusCoin    float64 `json:"bonus_coin"`
+
+	// 🔸 Coin คงค้างของผู้ใช้ทั้งหมด ณ สิ้นช่วง (จาก ledger บัญชี wallet)
+	WalletLiability float64   `json:"wallet_liability"`
+	LiabilityAsOf   time.Time `json:"liability_as_of"`
+
+	// 🔸 สลิปที่ยังไม่ได้ตรวจ ณ ตอนออกรายงาน (ทุกช่วงเวลา)
+	UnverifiedPayments      int     `json:"unverified_payments"`
+	UnverifiedPaymentAmount float64 `json:"unverified_payment_amount"`
+	UnverifiedTopUps        int     `json:"unverified_topups"`
+	UnverifiedTopUpAmount   float64 `json:"unverified_topup_amount"`
+}
+
+// Unverified คือรายการที่รอ admin ตรวจสลิป
+type Unverified struct {
+	Type            string    `json:"type"` // payment, payment_coin
+	ID              uint      `json:"id"`
+	UserID          *uint     `json:"user_id"`
+	Amount          float64   `json:"amount"`
+	ReferenceNumber string    `json:"reference_number"`
+	Status          string    `json:"status"`
+	StatusReason    string    `json:"status_reason"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Report คือรายงานรายได้หนึ่งชุด
+type Report struct {
+	GroupBy     string       `json:"group_by"`
+	From        string       `json:"from"`
+	To          string       `json:"to"` // วันสุดท้าย (นับรวม)
+	GeneratedAt time.Time    `json:"generated_at"`
+	Rows        []Row        `json:"rows"`
+	Summary     Summary      `json:"summary"`
+	Unverified  []Unverified `json:"unverified"`
+}
+
+// recognized คือสถานะที่นับเป็นรายได้ (refunded ยังนับ แล้วหักด้วยยอดคืนเงิน)
+var recognized = []string{entity.PaymentApproved, entity.PaymentRefunded}
+
+// recognizedAt คือเวลาที่รับรู้รายการ (เวลาอนุมัติ ถ้าไม่มีใช้เวลาสร้าง)
+const recognizedAt = "COALESCE(reviewed_at, created_at)"
+
+func isCoin(m *entity.Method) bool {
+	return m != nil && strings.Contains(strings.ToLower(m.Medthod), "coin")
+}
+
+// share คือส่วนของรายการที่ตกอยู่ในกลุ่มหนึ่ง
+type share struct {
+	key, label, sort string
+	ratio, kwh       float64
+}
+
+// builder รวมยอดเข้ากลุ่ม
+type builder struct {
+	groupBy string
+	rows    map[string]*Row
+	lines   map[uint][]entity.EVChargingPayment // บรรทัดที่ใช้คิด kWh/Tariff ของแต่ละ Payment
+}
+
+func (b *builder) row(s share) *Row {
+	r, ok := b.rows[s.key]
+	if !ok {
+		r = &Row{Key: s.key, Label: s.label, sort: s.sort}
+		b.rows[s.key] = r
+	}
+	return r
+}
+
+func periodShare(groupBy string, at time.Time) share {
+	t := at.In(bangkok)
+	switch groupBy {
+	case GroupWeek:
+		year, week := t.ISOWeek()
+		monday := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		key := fmt.Sprintf("%d-W%02d", year, week)
+		return share{key: key, label: monday.Format("2006-01-02"), sort: key, ratio: 1}
+	case GroupMonth:
+		key := t.Format("2006-01")
+		return share{key: key, label: key, sort: key, ratio: 1}
+	default:
+		key := t.Format("2006-01-02")
+		return share{key: key, label: key, sort: key, ratio: 1}
+	}
+}
+
+// sharesOf แบ่งยอดของ Payment เข้ากลุ่ม (Tariff แบ่งตามสัดส่วนราคาของแต่ละบรรทัด)
+func (b *builder) sharesOf(p *entity.Payment, at time.Time) []share {
+	kwh := 0.0
+	for _, l := range b.lines[p.ID] {
+		kwh += l.Power
+	}
+	switch b.groupBy {
+	case GroupCabinet:
+		if p.EVCabinet == nil {
+			return []share{{key: "cabinet:none", label: "ไม่ระบุตู้", sort: "~", ratio: 1, kwh: kwh}}
+		}
+		return []share{{key: fmt.Sprintf("cabinet:%d", p.EVCabinet.ID), label: p.EVCabinet.Name,
+			sort: fmt.Sprintf("%010d", p.EVCabinet.ID), ratio: 1, kwh: kwh}}
+	case GroupMethod:
+		if p.Method == nil {
+			return []share{{key: "method:none", label: "ไม่ระบุวิธีชำระ", sort: "~", ratio: 1, kwh: kwh}}
+		}
+		return []share{{key: fmt.Sprintf("method:%d", p.Method.ID), label: p.Method.Medthod,
+			sort: fmt.Sprintf("%010d", p.Method.ID), ratio: 1, kwh: kwh}}
+	case GroupTariff:
+		return b.tariffShares(p)
+	default:
+		s := periodShare(b.groupBy, at)
+		s.kwh = kwh
+		return []share{s}
+	}
+}
+
+func (b *builder) tariffShares(p *entity.Payment) []share {
+	lines := b.lines[p.ID]
+	total := 0.0
+	for _, l := range lines {
+		total += l.Price
+	}
+	if len(lines) == 0 || total <= 0 {
+		return []share{{key: "tariff:none", label: "ไม่ระบุ Tariff", sort: "~~", ratio: 1}}
+	}
+	byKey := map[string]*share{}
+	var order []string
+	for _, l := range lines {
+		key, label, sortKey := "tariff:flat", "ราคาต่อหน่วยของหัวชาร์จ", "~0"
+		if l.Tariff != nil {
+			key, label, sortKey = fmt.Sprintf("tariff:%d", l.Tariff.ID), l.Tariff.Name, fmt.Sprintf("%010d", l.Tariff.ID)
+		}
+		s, ok := byKey[key]
+		if !ok {
+			s = &share{key: key, label: label, sort: sortKey}
+			byKey[key] = s
+			order = append(order, key)
+		}
+		s.ratio += l.Price / total
+		s.kwh += l.Power
+	}
+	out := make([]share, 0, len(order))
+	for _, k := range order {
+		out = append(out, *byKey[k])
+	}
+	return out
+}
+
+// loadLines โหลดบรรทัดค่าชาร์จของ Payment — ใช้ยอดจากมิเตอร์จริงถ้ามี ไม่มีใช้ยอดที่จองไว้ตอนชำระ
+func loadLines(db *gorm.DB, ids []uint) (map[uint][]entity.EVChargingPayment, error) {
+	out := map[uint][]entity.EVChargingPayment{}
+	for start := 0; start < len(ids); start += 500 {
+		end := start + 500
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var lines []entity.EVChargingPayment
+		if err := db.Preload("Tariff").Where("payment_id IN ?", ids[start:end]).Order("id").Find(&lines).Error; err != nil {
+			return nil, err
+		}
+		metered := map[uint]bool{}
+		for _, l := range lines {
+			if l.ChargingTransactionID != nil {
+				metered[l.PaymentID] = true
+			}
+		}
+		for _, l := range lines {
+			if metered[l.PaymentID] == (l.ChargingTransactionID != nil) {
+				out[l.PaymentID] = append(out[l.PaymentID], l)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Build สร้างรายงานช่วง [from, to) ตามการจัดกลุ่ม groupBy
+func Build(db *gorm.DB, groupBy string, from, to time.Time) (*Report, error) {
+	rep := &Report{
+		GroupBy:     groupBy,
+		From:        from.In(bangkok).Format("2006-01-02"),
+		To:          to.In(bangkok).AddDate(0, 0, -1).Format("2006-01-02"),
+		GeneratedAt: time.Now(),
+		Rows:        []Row{},
+		Unverified:  []Unverified{},
+	}
+	sum := &rep.Summary
+
+	// 🔸 SQLite เทียบเวลาเป็น string — ขอบเขตต้องเป็น UTC แบบเดียวกับค่าที่บันทึกไว้ (...Z)
+	qFrom, qTo := from.UTC(), to.UTC()
+
+	// 🔹 ค่าชาร์จที่อนุมัติในช่วง
+	var payments []entity.Payment
+	if err := db.Preload("Method").Preload("EVCabinet").
+		Where("status IN ?", recognized).
+		Where(recognizedAt+" >= ? AND "+recognizedAt+" < ?", qFrom, qTo).
+		Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	// 🔹 คืนเงินที่เสร็จในช่วง
+	var refunds []entity.Refund
+	if err := db.Preload("Payment.Method").Preload("Payment.EVCabinet").
+		Where("status = ? AND decided_at >= ? AND decided_at < ?", entity.RefundCompleted, qFrom, qTo).
+		Order("id").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(payments)+len(refunds))
+	for _, p := range payments {
+		ids = append(ids, p.ID)
+	}
+	for _, r := range refunds {
+		if r.PaymentID != nil {
+			ids = append(ids, *r.PaymentID)
+		}
+	}
+	lines, err := loadLines(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{groupBy: groupBy, rows: map[string]*Row{}, lines: lines}
+
+	for i := range payments {
+		p := &payments[i]
+		at := p.CreatedAt
+		if p.ReviewedAt != nil {
+			at = *p.ReviewedAt
+		}
+		gross := p.Amount + p.Discount
+		for _, s := range b.sharesOf(p, at) {
+			r := b.row(s)
+			r.Payments++
+			r.Gross += gross * s.ratio
+			r.Discount += p.Discount * s.ratio
+			r.KWh += s.kwh
+		}
+		sum.Payments++
+		sum.Gross += gross
+		sum.Discount += p.Discount
+		for _, l := range lines[p.ID] {
+			sum.KWh += l.Power
+		}
+		if isCoin(p.Method) {
+			sum.NetCoin += p.Amount
+		} else {
+			sum.NetQR += p.Amount
+		}
+	}
+
+	for _, rf := range refunds {
+		switch {
+		case rf.Payment != nil:
+			for _, s := range b.sharesOf(rf.Payment, *rf.DecidedAt) {
+				b.row(s).Refunds += rf.Amount * s.ratio
+			}
+			sum.Refunds += rf.Amount
+			if isCoin(rf.Payment.Method) {
+				sum.NetCoin -= rf.Amount
+			} else {
+				sum.NetQR -= rf.Amount
+			}
+		case rf.PaymentCoinID != nil:
+			sum.TopUpRefunds += rf.Amount
+			if isPeriod(groupBy) {
+				b.row(periodShare(groupBy, *rf.DecidedAt)).TopUpRefunds += rf.Amount
+			}
+		}
+	}
+
+	// 🔹 เติม Coin ที่อนุมัติในช่วง (เงินรับล่วงหน้า)
+	var coins []entity.PaymentCoin
+	if err := db.Where("status IN ?", recognized).
+		Where(recognizedAt+" >= ? AND "+recognizedAt+" < ?", qFrom, qTo).
+		Order("id").Find(&coins).Error; err != nil {
+		return nil, err
+	}
+	for _, pc := range coins {
+		sum.TopUps += pc.Amount
+		sum.BonusCoin += pc.BonusCoin
+		if !isPeriod(groupBy) {
+			continue
+		}
+		at := pc.CreatedAt
+		if pc.ReviewedAt != nil {
+			at = *pc.ReviewedAt
+		}
+		r := b.row(periodShare(groupBy, at))
+		r.TopUps += pc.Amount
+		r.BonusCoin += pc.BonusCoin
+	}
+
+	for _, r := range b.rows {
+		r.Gross = round2(r.Gross)
+		r.Discount = round2(r.Discount)
+		r.Refunds = round2(r.Refunds)
+		r.Net = round2(r.Gross - r.Discount - r.Refunds)
+		r.KWh = math.Round(r.KWh*1000) / 1000
+		r.TopUps = round2(r.TopUps)
+		r.TopUpRefunds = round2(r.TopUpRefunds)
+		r.BonusCoin = round2(r.BonusCoin)
+		rep.Rows = append(rep.Rows, *r)
+	}
+	sort.Slice(rep.Rows, func(i, j int) bool { return rep.Rows[i].sort < rep.Rows[j].sort })
+
+	sum.Gross = round2(sum.Gross)
+	sum.Discount = round2(sum.Discount)
+	sum.Refunds = round2(sum.Refunds)
+	sum.Net = round2(sum.Gross - sum.Discount - sum.Refunds)
+	sum.NetQR = round2(sum.NetQR)
+	sum.NetCoin = round2(sum.NetCoin)
+	sum.KWh = math.Round(sum.KWh*1000) / 1000
+	sum.TopUps = round2(sum.TopUps)
+	sum.TopUpRefunds = round2(sum.TopUpRefunds)
+	sum.BonusCoin = round2(sum.BonusCoin)
+
+	// 🔹 Coin คงค้าง ณ สิ้นช่วง
+	asOf := to
+	if now := time.Now(); asOf.After(now) {
+		asOf = now
+	}
+	sum.LiabilityAsOf = asOf
+	if err := db.Model(&entity.WalletEntry{}).
+		Where("account = ? AND created_at < ?", wallet.AccountWallet, asOf.UTC()).
+		Select("COALESCE(SUM(credit - debit), 0)").Scan(&sum.WalletLiability).Error; err != nil {
+		return nil, err
+	}
+	sum.WalletLiability = round2(sum.WalletLiability)
+
+	// 🔹 สลิปที่ยังรอตรวจ
+	var pending []entity.Payment
+	if err := db.Where("status IN ?", entity.ReviewQueueStatuses).Order("created_at").Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range pending {
+		sum.UnverifiedPayments++
+		sum.UnverifiedPaymentAmount += p.Amount
+		rep.Unverified = append(rep.Unverified, Unverified{
+			Type: "payment", ID: p.ID, UserID: p.UserID, Amount: p.Amount, ReferenceNumber: p.ReferenceNumber,
+			Status: p.Status, StatusReason: p.StatusReason, CreatedAt: p.CreatedAt,
+		})
+	}
+	var pendingCoins []entity.PaymentCoin
+	if err := db.Where("status IN ?", entity.ReviewQueueStatuses).Order("created_at").Find(&pendingCoins).Error; err != nil {
+		return nil, err
+	}
+	for _, pc := range pendingCoins {
+		userID := pc.UserID
+		sum.UnverifiedTopUps++
+		sum.UnverifiedTopUpAmount += pc.Amount
+		rep.Unverified = append(rep.Unverified, Unverified{
+			Type: "payment_coin", ID: pc.ID, UserID: &userID, Amount: pc.Amount, ReferenceNumber: pc.ReferenceNumber,
+			Status: pc.Status, StatusReason: pc.StatusReason, CreatedAt: pc.CreatedAt,
+		})
+	}
+	sum.UnverifiedPaymentAmount = round2(sum.UnverifiedPaymentAmount)
+	sum.UnverifiedTopUpAmount = round2(sum.UnverifiedTopUpAmount)
+
+	return rep, nil
+}
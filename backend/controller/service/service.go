@@ -50,6 +50,10 @@ func UpdateServiceByID(c *gin.Context) {
 		Location   string `json:"location"`
 		MapURL     string `json:"map_url"`
 		EmployeeID *uint  `json:"employee_id"`
+
+		CompanyName *string `json:"company_name"`
+		TaxID       *string `json:"tax_id"`
+		Branch      *string `json:"branch"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
@@ -63,6 +67,17 @@ func UpdateServiceByID(c *gin.Context) {
 	service.MapURL = input.MapURL
 	service.EmployeeID = input.EmployeeID
 
+	// 🔹 ข้อมูลผู้ขาย (ส่งมาเฉพาะเมื่อต้องการแก้)
+	if input.CompanyName != nil {
+		service.CompanyName = *input.CompanyName
+	}
+	if input.TaxID != nil {
+		service.TaxID = *input.TaxID
+	}
+	if input.Branch != nil {
+		service.Branch = *input.Branch
+	}
+
 	if err := db.Save(&service).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,237 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/tokensign"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ แหล่งที่มาของการเปลี่ยนสถานะ
+const (
+	SourceOCPP   = "ocpp"
+	SourceAPI    = "api"
+	SourceSystem = "system"
+)
+
+// ErrExpired เมื่อ token เลยเวลา ExpiresAt แล้ว
+var ErrExpired = errors.New("session expired")
+
+// TransitionError เมื่อเปลี่ยนสถานะไม่ได้ (สถานะไม่อนุญาต หรือถูกเปลี่ยนไปก่อนแล้ว)
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("ไม่สามารถเปลี่ยนสถานะ session จาก %s เป็น %s ได้", e.From, e.To)
+}
+
+// Transition เปลี่ยนสถานะแบบมีเงื่อนไข (WHERE status = เดิม) พร้อมบันทึกประวัติ
+// สำเร็จแล้วค่าใน s จะถูกอัปเดตตามไปด้วย
+func Transition(tx *gorm.DB, s *entity.ChargingSession, to, reason, source string) error {
+	from := s.Status
+	if !entity.CanTransitionSession(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":            to,
+		"status_reason":     reason,
+		"status_changed_at": now,
+	}
+	switch to {
+	case entity.SessionAuthorized:
+		updates["authorized_at"] = now
+	case entity.SessionCharging:
+		if s.StartedAt == nil {
+			updates["started_at"] = now
+		}
+	case entity.SessionCompleted, entity.SessionExpired, entity.SessionCancelled:
+		updates["ended_at"] = now
+	}
+
+	res := tx.Model(&entity.ChargingSession{}).Where("id = ? AND status = ?", s.ID, from).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &TransitionError{From: from, To: to}
+	}
+	if err := tx.Create(&entity.ChargingSessionEvent{
+		ChargingSessionID: s.ID,
+		FromStatus:        from,
+		ToStatus:          to,
+		Reason:            reason,
+		Source:            source,
+	}).Error; err != nil {
+		return err
+	}
+	// 🔸 จบ session ก่อนหมดอายุ → token แบบลงชื่อต้องเข้า denylist (ตู้ที่ตรวจ offline จะได้ไม่รับซ้ำ)
+	if to == entity.SessionCompleted || to == entity.SessionCancelled {
+		if err := tokensign.Revoke(tx, s.Token, s.ID, reason, nil); err != nil {
+			return err
+		}
+	}
+
+	s.Status, s.StatusReason, s.StatusChangedAt = to, reason, &now
+	switch to {
+	case entity.SessionAuthorized:
+		s.AuthorizedAt = &now
+	case entity.SessionCharging:
+		if s.StartedAt == nil {
+			s.StartedAt = &now
+		}
+	case entity.SessionCompleted, entity.SessionExpired, entity.SessionCancelled:
+		s.EndedAt = &now
+	}
+	Notify(s.ID)
+	return nil
+}
+
+// Move เปลี่ยนสถานะถ้าทำได้ — ถ้าอยู่ในสถานะปลายทางแล้วหรือเปลี่ยนไม่ได้จะไม่ถือเป็น error
+// ใช้กับเหตุการณ์จาก charger ที่อาจมาซ้ำหรือมาไม่ตรงลำดับ
+func Move(tx *gorm.DB, s *entity.ChargingSession, to, reason, source string) (bool, error) {
+	if s.Status == to {
+		return false, nil
+	}
+	err := Transition(tx, s, to, reason, source)
+	var terr *TransitionError
+	if errors.As(err, &terr) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MoveByID โหลด session แล้วเรียก Move
+func MoveByID(tx *gorm.DB, id uint, to, reason, source string) (bool, error) {
+	var s entity.ChargingSession
+	if err := tx.First(&s, id).Error; err != nil {
+		return false, err
+	}
+	return Move(tx, &s, to, reason, source)
+}
+
+// IsExpired บอกว่า token เลยเวลาแล้วและยังไม่ได้เริ่มชาร์จ (กำลังชาร์จอยู่ไม่ถือว่าหมดอายุ)
+func IsExpired(s *entity.ChargingSession, now time.Time) bool {
+	if s.Status == entity.SessionExpired {
+		return true
+	}
+	startable := s.Status == entity.SessionCreated || s.Status == entity.SessionAuthorized
+	return startable && !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)
+}
+
+// ExpireIfDue เปลี่ยน session ที่เลยเวลาเป็น expired — คืน ErrExpired ถ้าหมดอายุ
+func ExpireIfDue(tx *gorm.DB, s *entity.ChargingSession) error {
+	if !IsExpired(s, time.Now()) {
+		return nil
+	}
+	if s.Status != entity.SessionExpired {
+		if _, err := Move(tx, s, entity.SessionExpired, "token หมดอายุ", SourceSystem); err != nil {
+			return err
+		}
+	}
+	return ErrExpired
+}
+
+// ExpireDue เปลี่ยนทุก session ที่ยังไม่เริ่มชาร์จและเลยเวลาแล้วเป็น expired
+func ExpireDue(db *gorm.DB) (int, error) {
+	var due []entity.ChargingSession
+	if err := db.Where("status IN ? AND expires_at < ?", entity.SessionStartableStates, time.Now()).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range due {
+		moved, err := Move(db, &due[i], entity.SessionExpired, "token หมดอายุ", SourceSystem)
+		if err != nil {
+			return expired, err
+		}
+		if moved {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// CancelByPayment ยกเลิกทุก session ที่ยังไม่จบของ Payment
+func CancelByPayment(tx *gorm.DB, paymentID uint, reason, source string) (int, error) {
+	var sessions []entity.ChargingSession
+	if err := tx.Where("payment_id = ? AND status IN ?", paymentID, entity.SessionActiveStates).
+		Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	cancelled := 0
+	for i := range sessions {
+		moved, err := Move(tx, &sessions[i], entity.SessionCancelled, reason, source)
+		if err != nil {
+			return cancelled, err
+		}
+		if moved {
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+// EnsureSessionStatuses แปลงสถานะแบบ bool เดิม (1 = ใช้งานอยู่, 0 = จบแล้ว) เป็นสถานะใหม่
+func EnsureSessionStatuses() {
+	db := config.DB()
+	db.Model(&entity.ChargingSession{}).
+		Where("status IN ?", []string{"1", "true"}).
+		Updates(map[string]interface{}{"status": entity.SessionCreated, "status_reason": "ย้ายจากสถานะเดิม"})
+	db.Model(&entity.ChargingSession{}).
+		Where("status IN ? AND id IN (?)", []string{"0", "false"},
+			db.Model(&entity.ChargingTransaction{}).Select("charging_session_id").Where("charging_session_id IS NOT NULL")).
+		Updates(map[string]interface{}{"status": entity.SessionCompleted, "status_reason": "ย้ายจากสถานะเดิม"})
+	db.Model(&entity.ChargingSession{}).
+		Where("status IN ? OR status IS NULL OR status = ''", []string{"0", "false"}).
+		Updates(map[string]interface{}{"status": entity.SessionCancelled, "status_reason": "ย้ายจากสถานะเดิม"})
+}
+
+// View คือ session ที่ส่งให้ endpoint อ่านข้อมูล — Token (สิทธิ์เริ่มชาร์จ) ถูกบังด้วย field ว่างที่ omitempty
+// token ให้เห็นเฉพาะ response ที่ออก token ให้เจ้าของเท่านั้น
+type View struct {
+	entity.ChargingSession
+	Token string `json:"Token,omitempty"`
+}
+
+// Views แปลงรายการ session เป็น View (ไม่มี token)
+func Views(sessions []entity.ChargingSession) []View {
+	views := make([]View, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, View{ChargingSession: s})
+	}
+	return views
+}
+
+// GET /charging-sessions/:id/events — ประวัติการเปลี่ยนสถานะ
+func ListSessionEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	db := config.DB()
+	var s entity.ChargingSession
+	if err := db.First(&s, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ session"})
+		return
+	}
+	ExpireIfDue(db, &s)
+
+	var events []entity.ChargingSessionEvent
+	if err := db.Where("charging_session_id = ?", s.ID).Order("id").Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session": View{ChargingSession: s}, "data": events})
+}
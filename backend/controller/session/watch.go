@@ -0,0 +1,44 @@
+package session
+
+import "sync"
+
+// ✅ ผู้ที่ติดตาม session (เช่น stream ความคืบหน้าการชาร์จ) แยกตาม session ID
+var (
+	watchers   = make(map[uint]map[chan struct{}]bool)
+	watchersMu sync.Mutex
+)
+
+// Watch ลงทะเบียนรับสัญญาณเมื่อ session มีการเปลี่ยนแปลง (สถานะ / มิเตอร์)
+// สัญญาณไม่มีข้อมูล — ผู้รับต้องอ่านค่าล่าสุดจากฐานข้อมูลเอง และต้องเรียก cancel เมื่อเลิกใช้
+func Watch(sessionID uint) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	watchersMu.Lock()
+	if watchers[sessionID] == nil {
+		watchers[sessionID] = make(map[chan struct{}]bool)
+	}
+	watchers[sessionID][ch] = true
+	watchersMu.Unlock()
+
+	return ch, func() {
+		watchersMu.Lock()
+		delete(watchers[sessionID], ch)
+		if len(watchers[sessionID]) == 0 {
+			delete(watchers, sessionID)
+		}
+		watchersMu.Unlock()
+	}
+}
+
+// Notify แจ้งผู้ติดตาม session ว่ามีข้อมูลใหม่ (ไม่ block — สัญญาณที่ค้างอยู่แล้วจะถูกรวมเป็นครั้งเดียว)
+func Notify(sessionID uint) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+
+	for ch := range watchers[sessionID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
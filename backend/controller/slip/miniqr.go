@@ -0,0 +1,97 @@
+package slip
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/services/qrcode"
+	"github.com/gin-gonic/gin"
+)
+
+// MiniQR คือข้อมูลใน QR ขนาดเล็กบนสลิปของธนาคารไทย (Slip Verification QR)
+// เช่น 0041 [00 06 000001][01 03 004][02 20 <transRef>] 5102TH 9104XXXX
+type MiniQR struct {
+	APIID       string `json:"api_id"`
+	SendingBank string `json:"sending_bank"`
+	TransRef    string `json:"trans_ref"`
+	Country     string `json:"country"`
+	Payload     string `json:"payload"`
+}
+
+// ErrNoMiniQR เมื่อไม่พบ QR ของสลิปในรูป (หรือเป็น QR ชนิดอื่น เช่น PromptPay)
+var ErrNoMiniQR = errors.New("ไม่พบ QR ของสลิปในรูปภาพ")
+
+// ✅ แยก TLV (id 2 หลัก + ความยาว 2 หลัก + ค่า)
+func parseTLV(s string) (map[string]string, bool) {
+	fields := map[string]string{}
+	for len(s) > 0 {
+		if len(s) < 4 {
+			return nil, false
+		}
+		n, err := strconv.Atoi(s[2:4])
+		if err != nil || len(s) < 4+n {
+			return nil, false
+		}
+		fields[s[:2]] = s[4 : 4+n]
+		s = s[4+n:]
+	}
+	return fields, true
+}
+
+// ParseMiniQR แปลงข้อความใน QR ของสลิปเป็น MiniQR
+// (ไม่ตรวจ CRC tag 91 เพราะ Reed-Solomon ของ QR ยืนยันความถูกต้องของข้อมูลแล้ว)
+func ParseMiniQR(payload string) (*MiniQR, error) {
+	payload = strings.TrimSpace(payload)
+	fields, ok := parseTLV(payload)
+	if !ok {
+		return nil, ErrNoMiniQR
+	}
+	sub, ok := parseTLV(fields["00"])
+	if !ok || sub["02"] == "" {
+		return nil, ErrNoMiniQR
+	}
+	return &MiniQR{
+		APIID:       sub["00"],
+		SendingBank: sub["01"],
+		TransRef:    sub["02"],
+		Country:     fields["51"],
+		Payload:     payload,
+	}, nil
+}
+
+// ReadMiniQR หา QR บนรูปสลิป (JPEG/PNG) แล้วอ่านเลขอ้างอิงโดยไม่ต้องพึ่ง provider
+func ReadMiniQR(data []byte) (*MiniQR, error) {
+	text, err := qrcode.DecodeBytes(data)
+	if err != nil {
+		return nil, ErrNoMiniQR
+	}
+	return ParseMiniQR(string(text))
+}
+
+// POST /api/decode-slip (multipart "file" หรือ JSON {"img": ...})
+// อ่าน QR บนสลิปในเครื่อง แล้วบอกว่าเลขอ้างอิงถูกใช้ไปแล้วหรือยัง
+func DecodeSlipQR(c *gin.Context) {
+	img, err := ReadSlipImage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	qr, err := ReadMiniQR(img.Data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "reason": ReasonUnreadable})
+		return
+	}
+
+	resp := gin.H{"data": qr, "used": false}
+	if existing, err := FindReference(config.DB(), qr.TransRef); err == nil {
+		kind, id := (&DuplicateReferenceError{Existing: *existing}).Holder()
+		resp["used"] = true
+		resp["held_by"] = kind
+		resp["held_by_id"] = id
+	}
+	c.JSON(http.StatusOK, resp)
+}
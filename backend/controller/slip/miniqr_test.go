@@ -0,0 +1,68 @@
+package slip
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ✅ สลิปจริงจาก uploads/payment (ข้อมูลใน QR ตรวจ CRC tag 91 แล้วถูกต้อง)
+func TestReadMiniQR(t *testing.T) {
+	tests := []struct {
+		file     string
+		bank     string
+		transRef string
+	}{
+		{"slip_kbank.jpg", "004", "015190020440BPP02530"},
+		{"slip_ktb.jpg", "006", "A5af47691acff44a6"},
+		{"slip_scb.jpg", "014", "2025102264TNP7f2Jhjdbyj6c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := ReadMiniQR(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.TransRef != tt.transRef {
+				t.Errorf("TransRef %q, want %q", m.TransRef, tt.transRef)
+			}
+			if m.SendingBank != tt.bank {
+				t.Errorf("SendingBank %q, want %q", m.SendingBank, tt.bank)
+			}
+			if m.APIID != "000001" || m.Country != "TH" {
+				t.Errorf("APIID %q Country %q, want 000001 TH", m.APIID, m.Country)
+			}
+		})
+	}
+}
+
+func TestParseMiniQR(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		transRef string
+		wantErr  error
+	}{
+		{"slip", "0041000600000101030040220015190020440BPP025305102TH9104EFA7", "015190020440BPP02530", nil},
+		{"trailing newline", "0038000600000101030060217A5af47691acff44a65102TH9104D090\n", "A5af47691acff44a6", nil},
+		{"promptpay", "00020101021229370016A0000006770101110113006681234567853037645406150.505802TH5910EV STATION62110507INV000163047FBF", "", ErrNoMiniQR},
+		{"truncated", "0041000600000101030040220015190020440BPP", "", ErrNoMiniQR},
+		{"text", "hello", "", ErrNoMiniQR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMiniQR(tt.payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && m.TransRef != tt.transRef {
+				t.Errorf("TransRef %q, want %q", m.TransRef, tt.transRef)
+			}
+		})
+	}
+}
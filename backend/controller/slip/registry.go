@@ -0,0 +1,171 @@
+package slip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DuplicateReferenceError คือเลขอ้างอิงสลิปที่มีรายการอื่นใช้ไปแล้ว
+type DuplicateReferenceError struct {
+	Existing entity.SlipReference
+}
+
+func (e *DuplicateReferenceError) Error() string {
+	if strings.HasPrefix(e.Existing.Reference, imageReferencePrefix) {
+		return "รูปสลิปนี้ถูกส่งมาแล้ว"
+	}
+	return fmt.Sprintf("สลิปนี้ (%s) ถูกใช้ไปแล้ว", e.Existing.Reference)
+}
+
+// Holder บอกว่ารายการไหนถือเลขอ้างอิงนี้อยู่
+func (e *DuplicateReferenceError) Holder() (kind string, id uint) {
+	if e.Existing.PaymentID != nil {
+		return "Payment", *e.Existing.PaymentID
+	}
+	if e.Existing.PaymentCoinID != nil {
+		return "PaymentCoin", *e.Existing.PaymentCoinID
+	}
+	return "", 0
+}
+
+func normalizeReference(ref string) string {
+	return strings.ToUpper(strings.TrimSpace(ref))
+}
+
+// FindReference หาเลขอ้างอิงในทะเบียน (รวมรายการที่ถูกลบแล้ว)
+func FindReference(db *gorm.DB, ref string) (*entity.SlipReference, error) {
+	var existing entity.SlipReference
+	err := db.Unscoped().Where("reference = ?", normalizeReference(ref)).First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// imageReferencePrefix นำหน้า hash ของรูปสลิปในทะเบียน (แยกจาก transRef ของธนาคาร)
+const imageReferencePrefix = "IMG-"
+
+// ImageReference คือเลขอ้างอิงแทนของรูปสลิปที่อ่านเลขอ้างอิงไม่ได้ (sha256 ของไฟล์)
+// ใช้จองรูปเดิมไว้ตอนส่งให้ admin ตรวจ — อัปโหลดรูปเดิมซ้ำจะไม่ได้รายการรอตรวจเพิ่ม
+func ImageReference(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return imageReferencePrefix + hex.EncodeToString(sum[:])
+}
+
+// UploadReference คือ ImageReference ของรูปใน form (ไม่มีรูป / อ่านไม่ได้ คืนค่าว่าง)
+func UploadReference(c *gin.Context, field string) string {
+	img, err := ReadFormImage(c, field)
+	if err != nil {
+		return ""
+	}
+	return ImageReference(img.Data)
+}
+
+// RegisterReference จองเลขอ้างอิงสลิปให้ Payment หรือ PaymentCoin
+// ต้องเรียกภายใน transaction เดียวกับที่สร้างรายการ — unique index กันการใช้ซ้ำแม้มี request พร้อมกัน
+func RegisterReference(tx *gorm.DB, ref string, paymentID, paymentCoinID *uint) error {
+	ref = normalizeReference(ref)
+	if ref == "" {
+		return nil
+	}
+
+	if existing, err := FindReference(tx, ref); err == nil {
+		return &DuplicateReferenceError{Existing: *existing}
+	}
+
+	row := entity.SlipReference{Reference: ref, PaymentID: paymentID, PaymentCoinID: paymentCoinID}
+	if err := tx.Create(&row).Error; err != nil {
+		// ชนกับ request อื่นที่บันทึกไปก่อน
+		if existing, findErr := FindReference(tx, ref); findErr == nil {
+			return &DuplicateReferenceError{Existing: *existing}
+		}
+		return err
+	}
+	return nil
+}
+
+// ✅ อ่านเลขอ้างอิงจาก QR บนรูปสลิปที่บันทึกไว้ (ไม่พบไฟล์/อ่านไม่ได้ คืนค่าว่าง)
+func referenceFromPicture(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	qr, err := ReadMiniQR(data)
+	if err != nil {
+		return ""
+	}
+	return qr.TransRef
+}
+
+// fillReferencesFromPictures เติม ReferenceNumber ของรายการเก่าที่ว่างจากรูปสลิปที่บันทึกไว้
+func fillReferencesFromPictures(db *gorm.DB) {
+	var payments []entity.Payment
+	db.Where("reference_number = '' AND picture <> ''").Find(&payments)
+	for _, p := range payments {
+		if ref := referenceFromPicture(p.Picture); ref != "" {
+			db.Model(&p).UpdateColumn("reference_number", ref)
+		}
+	}
+
+	var coins []entity.PaymentCoin
+	db.Where("reference_number = '' AND picture <> ''").Find(&coins)
+	for _, pc := range coins {
+		if ref := referenceFromPicture(pc.Picture); ref != "" {
+			db.Model(&pc).UpdateColumn("reference_number", ref)
+		}
+	}
+}
+
+// EnsureReferenceRegistry เติมทะเบียนจาก Payment / PaymentCoin ที่มีอยู่ก่อน
+// ถ้าเลขซ้ำกันอยู่แล้ว รายการแรกจะได้เลขนั้นไป
+func EnsureReferenceRegistry() {
+	db := config.DB()
+	fillReferencesFromPictures(db)
+
+	var payments []entity.Payment
+	db.Unscoped().
+		Where("reference_number <> '' AND id NOT IN (?)",
+			db.Model(&entity.SlipReference{}).Unscoped().Select("payment_id").Where("payment_id IS NOT NULL")).
+		Order("id").
+		Find(&payments)
+	for _, p := range payments {
+		id := p.ID
+		if err := RegisterReference(db, p.ReferenceNumber, &id, nil); err != nil {
+			fmt.Println("⚠️ Slip reference of Payment", p.ID, "not registered:", err)
+		}
+	}
+
+	var coins []entity.PaymentCoin
+	db.Unscoped().
+		Where("reference_number <> '' AND id NOT IN (?)",
+			db.Model(&entity.SlipReference{}).Unscoped().Select("payment_coin_id").Where("payment_coin_id IS NOT NULL")).
+		Order("id").
+		Find(&coins)
+	for _, pc := range coins {
+		id := pc.ID
+		if err := RegisterReference(db, pc.ReferenceNumber, nil, &id); err != nil {
+			fmt.Println("⚠️ Slip reference of PaymentCoin", pc.ID, "not registered:", err)
+		}
+	}
+}
+
+// IsDuplicate บอกว่า error มาจากเลขอ้างอิงซ้ำหรือไม่
+func IsDuplicate(err error) bool {
+	var dup *DuplicateReferenceError
+	return errors.As(err, &dup)
+}
@@ -1,126 +1,187 @@
 package slip
 
 import (
-	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-const ThunderAuthToken = "0e163198-4d01-4ebe-8bab-1985348d41a9"
+// verifyTimeout คือเวลาสูงสุดที่รอผู้ให้บริการตรวจสลิป
+const verifyTimeout = 30 * time.Second
 
-func CheckSlipThunder(c *gin.Context) {
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบไฟล์ในคำขอ"})
-		return
-	}
+// maxSlipSize คือขนาดรูปสลิปสูงสุดที่รับ (5 MB)
+const maxSlipSize = 5 << 20
 
-	f, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "เปิดไฟล์ไม่ได้"})
-		return
-	}
-	defer f.Close()
+type SlipRequest struct {
+	Img string `json:"img"`
+}
 
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+// ✅ mockRaw แปลงผลจาก MockVerifier เป็น JSON รูปแบบเดียวกับ SlipOK
+func mockRaw(r *SlipResult) []byte {
+	raw, _ := json.Marshal(gin.H{
+		"success": true,
+		"data": gin.H{
+			"ref":           r.TransRef,
+			"amount":        r.Amount,
+			"date":          r.Date.Format(time.RFC3339),
+			"sender_name":   r.SenderName,
+			"sender_bank":   r.SenderBank,
+			"sender_id":     r.SenderAccount,
+			"receiver_name": r.ReceiverName,
+			"receiver_bank": r.ReceiverBank,
+			"receiver_id":   r.ReceiverAccount,
+		},
+	})
+	return raw
+}
 
-	part, err := writer.CreateFormFile("file", file.Filename)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง form file ไม่ได้"})
-		return
+// ✅ provider ของ endpoint เดิม — ถ้าตั้ง SLIP_PROVIDER=mock จะใช้ mock แทนเพื่อทำงาน offline
+func legacyVerifier(provider string) (rawVerifier, error) {
+	if strings.EqualFold(getenv("SLIP_PROVIDER", ""), ProviderMock) {
+		return NewMockVerifier(), nil
 	}
-
-	_, err = io.Copy(part, f)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "เขียนไฟล์ลง form ไม่ได้"})
-		return
+	if provider == ProviderThunder {
+		return NewThunderVerifier()
 	}
+	return NewSlipOKVerifier(), nil
+}
 
-	err = writer.Close()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ปิด writer ไม่ได้"})
-		return
+// ReadSlipImage อ่านรูปสลิปจาก multipart (field "file" หรือ "slip") หรือ JSON {"img": "data:...;base64,..."}
+func ReadSlipImage(c *gin.Context) (SlipImage, error) {
+	for _, field := range []string{"file", "slip"} {
+		if c.ContentType() != gin.MIMEMultipartPOSTForm {
+			break
+		}
+		file, err := c.FormFile(field)
+		if err != nil || file == nil {
+			continue
+		}
+		if file.Size > maxSlipSize {
+			return SlipImage{}, fmt.Errorf("รูปสลิปต้องมีขนาดไม่เกิน 5 MB")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return SlipImage{}, fmt.Errorf("เปิดไฟล์ไม่ได้")
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return SlipImage{}, fmt.Errorf("อ่านไฟล์ไม่ได้")
+		}
+		return SlipImage{Data: data, Filename: file.Filename, ContentType: file.Header.Get("Content-Type")}, nil
 	}
 
-	req, err := http.NewRequest("POST", "https://api.thunder.in.th/v1/verify", body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้าง request ไม่ได้"})
-		return
+	var req SlipRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Img == "" {
+		return SlipImage{}, fmt.Errorf("ไม่พบไฟล์ในคำขอ")
 	}
+	return DecodeDataURL(req.Img)
+}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+ThunderAuthToken)
+// DecodeDataURL แปลง "data:image/png;base64,..." (หรือ base64 ล้วน) เป็น SlipImage
+func DecodeDataURL(v string) (SlipImage, error) {
+	contentType := ""
+	if strings.HasPrefix(v, "data:") {
+		comma := strings.Index(v, ",")
+		if comma < 0 {
+			return SlipImage{}, fmt.Errorf("รูปภาพไม่ถูกต้อง")
+		}
+		contentType = strings.TrimSuffix(strings.TrimPrefix(v[:comma], "data:"), ";base64")
+		v = v[comma+1:]
+	}
+	data, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(data) == 0 {
+		return SlipImage{}, fmt.Errorf("รูปภาพไม่ถูกต้อง")
+	}
+	if len(data) > maxSlipSize {
+		return SlipImage{}, fmt.Errorf("รูปสลิปต้องมีขนาดไม่เกิน 5 MB")
+	}
+	return SlipImage{Data: data, ContentType: contentType}, nil
+}
 
-	fmt.Println("Authorization header:", req.Header.Get("Authorization"))
+// ✅ แปลง error จาก provider เป็น HTTP response
+func respondVerifyError(c *gin.Context, err error) {
+	var perr *ProviderError
+	switch {
+	case errors.As(err, &perr):
+		c.JSON(perr.StatusCode, gin.H{"error": "ตรวจสอบสลิปล้มเหลว", "detail": perr.Body})
+	case errors.Is(err, ErrSlipUnreadable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "ระบบตรวจสลิปไม่ตอบกลับ"})
+	case errors.Is(err, ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnknownProvider):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "เชื่อมต่อกับระบบตรวจสลิปล้มเหลว"})
+	}
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// ✅ endpoint เดิม: ส่งต่อ JSON ของ provider ให้ frontend
+func proxyRaw(c *gin.Context, provider string) {
+	v, err := legacyVerifier(provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ส่งคำขอไป Thunder API ไม่สำเร็จ"})
+		respondVerifyError(c, err)
 		return
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
+	img, err := ReadSlipImage(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "อ่าน response ไม่ได้"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(resp.StatusCode, gin.H{"error": "Thunder API error", "detail": string(respBody)})
+	ctx, cancel := context.WithTimeout(c.Request.Context(), verifyTimeout)
+	defer cancel()
+
+	raw, err := v.fetch(ctx, img)
+	if err != nil {
+		respondVerifyError(c, err)
 		return
 	}
-
-	c.Data(http.StatusOK, "application/json", respBody)
+	c.Data(http.StatusOK, "application/json", raw)
 }
 
-
-type SlipRequest struct {
-	Img string `json:"img"`
+// POST /api/check-slip (multipart "file")
+func CheckSlipThunder(c *gin.Context) {
+	proxyRaw(c, ProviderThunder)
 }
 
+// POST /api/check-slipok (JSON {"img": "data:image/...;base64,..."})
 func CheckSlipOI(c *gin.Context) {
-	var req SlipRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "รูปภาพไม่ถูกต้อง"})
-		return
-	}
+	proxyRaw(c, ProviderSlipOK)
+}
 
-	// ❌ ไม่ใช้ amount แล้ว
-	apiURL := "https://slip-c.oiioioiiioooioio.download/api/slip"
-	// เตรียม JSON payload
-	payload, err := json.Marshal(req)
+// POST /api/verify-slip
+// ตรวจสลิปด้วย provider ที่ตั้งไว้ (SLIP_PROVIDER) แล้วคืนข้อมูลรูปแบบเดียวกันทุก provider
+func VerifySlip(c *gin.Context) {
+	verifier, err := NewVerifier("")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "แปลงข้อมูลล้มเหลว"})
+		respondVerifyError(c, err)
 		return
 	}
 
-	// POST ไปยัง OIIO API
-	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(payload))
+	img, err := ReadSlipImage(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "เชื่อมต่อกับระบบตรวจสลิปล้มเหลว"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "อ่านข้อมูลตอบกลับล้มเหลว"})
-		return
-	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), verifyTimeout)
+	defer cancel()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(resp.StatusCode, gin.H{"error": "ตรวจสอบสลิปล้มเหลว", "detail": string(body)})
+	result, err := verifier.Verify(ctx, img)
+	if err != nil {
+		respondVerifyError(c, err)
 		return
 	}
-
-	// สำเร็จ
-	c.Data(http.StatusOK, "application/json", body)
+	c.JSON(http.StatusOK, gin.H{"data": result})
 }
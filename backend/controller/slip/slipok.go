@@ -0,0 +1,105 @@
+package slip
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+const defaultSlipOKURL = "https://slip-c.oiioioiiioooioio.download/api/slip"
+
+// SlipOKVerifier ตรวจสลิปผ่าน API ที่รับรูปเป็น base64 (JSON {"img": ...})
+type SlipOKVerifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewSlipOKVerifier อ่าน SLIPOK_API_URL จาก env
+func NewSlipOKVerifier() *SlipOKVerifier {
+	return &SlipOKVerifier{
+		URL:    getenv("SLIPOK_API_URL", defaultSlipOKURL),
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (v *SlipOKVerifier) Name() string { return ProviderSlipOK }
+
+// ✅ รูปแบบ data URL ที่ API ต้องการ
+func dataURL(img SlipImage) string {
+	contentType := img.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(img.Data)
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+func (v *SlipOKVerifier) post(ctx context.Context, img string) ([]byte, error) {
+	payload, err := json.Marshal(SlipRequest{Img: img})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ProviderError{Provider: v.Name(), StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
+
+func (v *SlipOKVerifier) fetch(ctx context.Context, img SlipImage) ([]byte, error) {
+	return v.post(ctx, dataURL(img))
+}
+
+func parseSlipOK(raw []byte) (*SlipResult, error) {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil || resp.Data == nil {
+		return nil, ErrSlipUnreadable
+	}
+	d := resp.Data
+
+	result := &SlipResult{
+		Provider:        ProviderSlipOK,
+		TransRef:        stringField(d, "ref", "transRef", "trans_ref"),
+		Amount:          parseAmount(d["amount"]),
+		Date:            parseSlipDate(stringField(d, "date", "transDate", "trans_date")),
+		SenderName:      stringField(d, "sender_name", "senderName"),
+		SenderBank:      stringField(d, "sender_bank", "sendingBank"),
+		SenderAccount:   stringField(d, "sender_id", "sender_account"),
+		ReceiverName:    stringField(d, "receiver_name", "receiverName"),
+		ReceiverBank:    stringField(d, "receiver_bank", "receivingBank"),
+		ReceiverAccount: stringField(d, "receiver_id", "receiver_account", "receiver_proxy"),
+	}
+	if result.TransRef == "" {
+		return nil, ErrSlipUnreadable
+	}
+	return result, nil
+}
+
+func (v *SlipOKVerifier) Verify(ctx context.Context, img SlipImage) (*SlipResult, error) {
+	raw, err := v.fetch(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return parseSlipOK(raw)
+}
@@ -0,0 +1,165 @@
+package slip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+const defaultThunderURL = "https://api.thunder.in.th/v1/verify"
+
+// ThunderVerifier ตรวจสลิปผ่าน Thunder API (ส่งไฟล์แบบ multipart)
+type ThunderVerifier struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewThunderVerifier อ่าน THUNDER_API_URL / THUNDER_API_TOKEN จาก env (ไม่มี token = ErrNotConfigured)
+func NewThunderVerifier() (*ThunderVerifier, error) {
+	token := getenv("THUNDER_API_TOKEN", "")
+	if token == "" {
+		return nil, fmt.Errorf("%w: THUNDER_API_TOKEN", ErrNotConfigured)
+	}
+	return &ThunderVerifier{
+		URL:    getenv("THUNDER_API_URL", defaultThunderURL),
+		Token:  token,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (v *ThunderVerifier) Name() string { return ProviderThunder }
+
+func (v *ThunderVerifier) fetch(ctx context.Context, img SlipImage) ([]byte, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	filename := img.Filename
+	if filename == "" {
+		filename = "slip.jpg"
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(img.Data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ProviderError{Provider: v.Name(), StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
+type thunderParty struct {
+	Bank struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Short string `json:"short"`
+	} `json:"bank"`
+	Account struct {
+		Name struct {
+			Th string `json:"th"`
+			En string `json:"en"`
+		} `json:"name"`
+		Bank *struct {
+			Type    string `json:"type"`
+			Account string `json:"account"`
+		} `json:"bank"`
+		Proxy *struct {
+			Type    string `json:"type"`
+			Account string `json:"account"`
+		} `json:"proxy"`
+	} `json:"account"`
+}
+
+func (p thunderParty) name() string {
+	if p.Account.Name.En != "" {
+		return p.Account.Name.En
+	}
+	return p.Account.Name.Th
+}
+
+// ✅ PromptPay ใช้ proxy (เบอร์โทร/เลขบัตร) ถ้าไม่มีใช้เลขบัญชี
+func (p thunderParty) account() string {
+	if p.Account.Proxy != nil && p.Account.Proxy.Account != "" {
+		return p.Account.Proxy.Account
+	}
+	if p.Account.Bank != nil {
+		return p.Account.Bank.Account
+	}
+	return ""
+}
+
+func (p thunderParty) bank() string {
+	if p.Bank.ID != "" {
+		return p.Bank.ID
+	}
+	return p.Bank.Short
+}
+
+func parseThunder(raw []byte) (*SlipResult, error) {
+	var resp struct {
+		Data struct {
+			TransRef string `json:"transRef"`
+			Date     string `json:"date"`
+			Amount   struct {
+				Amount float64 `json:"amount"`
+			} `json:"amount"`
+			Sender   thunderParty `json:"sender"`
+			Receiver thunderParty `json:"receiver"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, ErrSlipUnreadable
+	}
+	d := resp.Data
+	if d.TransRef == "" {
+		return nil, ErrSlipUnreadable
+	}
+	return &SlipResult{
+		Provider:        ProviderThunder,
+		TransRef:        d.TransRef,
+		Amount:          parseAmount(d.Amount.Amount),
+		Date:            parseSlipDate(d.Date),
+		SenderName:      d.Sender.name(),
+		SenderBank:      d.Sender.bank(),
+		SenderAccount:   d.Sender.account(),
+		ReceiverName:    d.Receiver.name(),
+		ReceiverBank:    d.Receiver.bank(),
+		ReceiverAccount: d.Receiver.account(),
+	}, nil
+}
+
+func (v *ThunderVerifier) Verify(ctx context.Context, img SlipImage) (*SlipResult, error) {
+	raw, err := v.fetch(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return parseThunder(raw)
+}
@@ -0,0 +1,298 @@
+package slip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// ✅ เหตุผลที่สลิปไม่ผ่าน (ส่งกลับใน field "reason")
+const (
+	ReasonMissing          = "slip_missing"
+	ReasonUnreadable       = "slip_unreadable"
+	ReasonProviderError    = "slip_provider_error"
+	ReasonNoBank           = "bank_not_configured"
+	ReasonReceiverMismatch = "receiver_mismatch"
+	ReasonAmountTooLow     = "amount_too_low"
+	ReasonDateMissing      = "slip_date_missing"
+	ReasonTooOld           = "slip_too_old"
+	ReasonInFuture         = "slip_in_future"
+	ReasonDuplicate        = "duplicate_slip"
+	ReasonQRMismatch       = "slip_qr_mismatch"
+)
+
+// DefaultMaxAge คืออายุสลิปสูงสุดที่รับ (ปรับได้ด้วย env SLIP_MAX_AGE เช่น 30m, 2h)
+const DefaultMaxAge = time.Hour
+
+// clockSkew เผื่อเวลาเครื่อง server กับธนาคารไม่ตรงกัน
+const clockSkew = 5 * time.Minute
+
+// minMatchedDigits คือจำนวนหลักที่ต้องตรงกันอย่างน้อยเมื่อเลขบัญชีในสลิปถูกปิดบางส่วน
+const minMatchedDigits = 4
+
+// ValidationError คือสลิปที่อ่านได้แต่ใช้กับรายการนี้ไม่ได้
+type ValidationError struct {
+	Reason  string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+func invalid(reason, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// MaxAge อ่านอายุสลิปสูงสุดจาก env
+func MaxAge() time.Duration {
+	if d, err := time.ParseDuration(getenv("SLIP_MAX_AGE", "")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultMaxAge
+}
+
+// ✅ เลขบัญชี/PromptPay ให้อยู่ในรูปตัวเลข (x = หลักที่ถูกปิด) และเบอร์โทรขึ้นต้นด้วย 0
+func normalizeAccount(v string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(v) {
+		if (r >= '0' && r <= '9') || r == 'x' || r == '*' {
+			if r == '*' {
+				r = 'x'
+			}
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	switch {
+	case strings.HasPrefix(out, "0066") && len(out) == 13:
+		out = "0" + out[4:]
+	case strings.HasPrefix(out, "66") && len(out) == 11:
+		out = "0" + out[2:]
+	}
+	return out
+}
+
+// AccountMatches เทียบเลขในสลิป (อาจถูกปิดบางหลัก) กับเลขจริง โดยชิดขวา
+func AccountMatches(slipAccount, expected string) bool {
+	got, want := normalizeAccount(slipAccount), normalizeAccount(expected)
+	if got == "" || want == "" {
+		return false
+	}
+	if got == want {
+		return true
+	}
+
+	matched := 0
+	for i, j := len(got)-1, len(want)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if got[i] == 'x' {
+			continue
+		}
+		if got[i] != want[j] {
+			return false
+		}
+		matched++
+	}
+	return matched >= minMatchedDigits
+}
+
+// ✅ ชื่อผู้รับ เทียบแบบไม่สนตัวพิมพ์และคำนำหน้า
+// ชื่อต้องตรงทั้งคำ และนามสกุลในสลิป (มักถูกตัด เช่น "TAWANCHAI B.") ต้องเป็นส่วนต้นของนามสกุลจริง
+func nameMatches(slipName, expected string) bool {
+	clean := func(v string) string {
+		v = strings.ToUpper(strings.Join(strings.Fields(v), " "))
+		for _, prefix := range []string{"MR. ", "MRS. ", "MS. ", "MISS ", "นาย ", "นาง ", "นางสาว ", "น.ส. "} {
+			v = strings.TrimPrefix(v, strings.ToUpper(prefix))
+		}
+		return strings.TrimSpace(v)
+	}
+	got, want := strings.Fields(clean(slipName)), strings.Fields(clean(expected))
+	if len(got) == 0 || len(want) == 0 {
+		return false
+	}
+	if strings.Join(got, " ") == strings.Join(want, " ") {
+		return true
+	}
+	if len(got) != 2 || len(want) < 2 || got[0] != want[0] {
+		return false
+	}
+	surname := strings.TrimSuffix(got[1], ".")
+	return surname != "" && strings.HasPrefix(want[len(want)-1], surname)
+}
+
+// Validate ตรวจว่าสลิปใช้จ่ายรายการนี้ได้ (ผู้รับ, ยอดเงิน, วันที่)
+func Validate(result *SlipResult, bank entity.Bank, minAmount float64, now time.Time) error {
+	if result == nil || result.TransRef == "" {
+		return invalid(ReasonUnreadable, "ไม่สามารถอ่านข้อมูลจากสลิปได้")
+	}
+
+	// 🔸 ผู้รับต้องเป็นบัญชี PromptPay ของระบบ
+	if bank.PromptPay == "" {
+		return invalid(ReasonNoBank, "ยังไม่ได้ตั้งค่าบัญชี PromptPay")
+	}
+	switch {
+	case result.ReceiverAccount != "":
+		if !AccountMatches(result.ReceiverAccount, bank.PromptPay) {
+			return invalid(ReasonReceiverMismatch, "บัญชีผู้รับในสลิป (%s) ไม่ตรงกับบัญชีของระบบ", result.ReceiverAccount)
+		}
+	case result.ReceiverName != "":
+		// provider บางเจ้าไม่ส่งเลขบัญชีผู้รับ → เทียบชื่อเจ้าของบัญชีแทน
+		if !nameMatches(result.ReceiverName, bank.Manager) {
+			return invalid(ReasonReceiverMismatch, "ชื่อผู้รับในสลิป (%s) ไม่ตรงกับเจ้าของบัญชี", result.ReceiverName)
+		}
+	default:
+		return invalid(ReasonReceiverMismatch, "ไม่พบข้อมูลผู้รับในสลิป")
+	}
+
+	// 🔸 ยอดเงิน
+	if math.Round(result.Amount*100) < math.Round(minAmount*100) {
+		return invalid(ReasonAmountTooLow, "ยอดเงินในสลิป %.2f บาท น้อยกว่ายอดที่ต้องชำระ %.2f บาท", result.Amount, minAmount)
+	}
+
+	// 🔸 วันที่ต้องเป็นสลิปล่าสุด
+	if result.Date.IsZero() {
+		return invalid(ReasonDateMissing, "ไม่พบวันที่ทำรายการในสลิป")
+	}
+	if result.Date.After(now.Add(clockSkew)) {
+		return invalid(ReasonInFuture, "วันที่ในสลิปอยู่ในอนาคต")
+	}
+	if maxAge := MaxAge(); now.Sub(result.Date) > maxAge {
+		return invalid(ReasonTooOld, "สลิปเก่าเกิน %s (โอนเมื่อ %s)", maxAge, result.Date.In(bangkok).Format("02/01/2006 15:04"))
+	}
+	return nil
+}
+
+// ReadFormImage อ่านรูปสลิปจาก field ของ multipart form
+func ReadFormImage(c *gin.Context, field string) (SlipImage, error) {
+	file, err := c.FormFile(field)
+	if err != nil || file == nil {
+		return SlipImage{}, invalid(ReasonMissing, "กรุณาแนบรูปสลิปการโอนเงิน")
+	}
+	if file.Size > maxSlipSize {
+		return SlipImage{}, invalid(ReasonMissing, "รูปสลิปต้องมีขนาดไม่เกิน 5 MB")
+	}
+	f, err := file.Open()
+	if err != nil {
+		return SlipImage{}, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return SlipImage{}, err
+	}
+	return SlipImage{Data: data, Filename: file.Filename, ContentType: file.Header.Get("Content-Type")}, nil
+}
+
+// VerifyUpload อ่านสลิปจาก form แล้วตรวจกับ provider และเงื่อนไขของรายการ
+// (ยังไม่ตรวจสลิปซ้ำ — ให้เรียก RegisterReference ภายใน transaction ตอนบันทึก)
+// ถ้า error เป็นกรณีที่ส่งให้ admin ตรวจได้ (NeedsReview) result จะมีข้อมูลเท่าที่อ่านได้ เช่น TransRef จาก QR บนสลิป
+func VerifyUpload(c *gin.Context, field string, bank entity.Bank, minAmount float64) (*SlipResult, error) {
+	img, err := ReadFormImage(c, field)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔹 อ่าน QR บนสลิปเองก่อน — สลิปที่ใช้ไปแล้วถูกปฏิเสธได้แม้ provider ล่ม
+	local, _ := ReadMiniQR(img.Data)
+	var partial *SlipResult
+	if local != nil {
+		if existing, err := FindReference(config.DB(), local.TransRef); err == nil {
+			return nil, &DuplicateReferenceError{Existing: *existing}
+		}
+		partial = &SlipResult{Provider: "local", TransRef: local.TransRef, SenderBank: local.SendingBank}
+	}
+
+	verifier, err := NewVerifier("")
+	if errors.Is(err, ErrNotConfigured) {
+		// ตั้งค่าไม่ครบ = ตรวจอัตโนมัติไม่ได้ → ให้ admin ตรวจแทน (เหมือน provider ล่ม)
+		fmt.Println("⚠️ Slip verifier:", err)
+		return partial, &ValidationError{Reason: ReasonProviderError, Message: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), verifyTimeout)
+	defer cancel()
+
+	result, err := verifier.Verify(ctx, img)
+	if err != nil {
+		if errors.Is(err, ErrSlipUnreadable) {
+			return partial, invalid(ReasonUnreadable, "ไม่สามารถอ่านข้อมูลจากสลิปได้")
+		}
+		var perr *ProviderError
+		if errors.As(err, &perr) && perr.StatusCode < 500 {
+			// provider ปฏิเสธสลิป (เช่น สลิปปลอม/ไม่พบรายการ)
+			return partial, invalid(ReasonUnreadable, "ระบบตรวจสลิปไม่ยืนยันสลิปนี้")
+		}
+		return partial, &ValidationError{Reason: ReasonProviderError, Message: "เชื่อมต่อกับระบบตรวจสลิปล้มเหลว"}
+	}
+
+	if local != nil {
+		switch {
+		case result.TransRef == "":
+			result.TransRef = local.TransRef
+		case normalizeReference(result.TransRef) != normalizeReference(local.TransRef):
+			return nil, invalid(ReasonQRMismatch, "เลขอ้างอิงใน QR ของสลิปไม่ตรงกับข้อมูลจากธนาคาร")
+		}
+		if result.SenderBank == "" {
+			result.SenderBank = local.SendingBank
+		}
+	}
+
+	if err := Validate(result, bank, minAmount, time.Now()); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// NeedsReview บอกว่าสลิปที่ไม่ผ่านควรส่งให้ admin ตรวจแทนการปฏิเสธทันที
+// (provider ล่ม/อ่านไม่ได้ หรือปัญหาเรื่องวันที่) — ผู้รับผิด ยอดไม่พอ หรือสลิปซ้ำยังปฏิเสธทันที
+func NeedsReview(err error) bool {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+	switch verr.Reason {
+	case ReasonProviderError, ReasonUnreadable, ReasonDateMissing, ReasonTooOld:
+		return true
+	}
+	return false
+}
+
+// RespondError ตอบ error จากการตรวจสลิปให้ client ในรูปแบบเดียวกัน
+func RespondError(c *gin.Context, err error) {
+	var dup *DuplicateReferenceError
+	if errors.As(err, &dup) {
+		kind, id := dup.Holder()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      dup.Error(),
+			"reason":     ReasonDuplicate,
+			"reference":  dup.Existing.Reference,
+			"held_by":    kind,
+			"held_by_id": id,
+		})
+		return
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		status := http.StatusUnprocessableEntity
+		switch verr.Reason {
+		case ReasonProviderError:
+			status = http.StatusBadGateway
+		case ReasonMissing:
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": verr.Message, "reason": verr.Reason})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
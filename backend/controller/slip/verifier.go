@@ -0,0 +1,150 @@
+package slip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ✅ ชื่อ provider ที่เลือกได้ผ่าน env SLIP_PROVIDER
+const (
+	ProviderThunder = "thunder"
+	ProviderSlipOK  = "slipok"
+	ProviderMock    = "mock"
+)
+
+var (
+	ErrUnknownProvider = errors.New("ไม่รู้จักผู้ให้บริการตรวจสลิป")
+	ErrSlipUnreadable  = errors.New("ไม่สามารถอ่านข้อมูลจากสลิปได้")
+	ErrNotConfigured   = errors.New("ยังไม่ได้ตั้งค่าผู้ให้บริการตรวจสลิป")
+)
+
+// ProviderError คือ error ที่ผู้ให้บริการตรวจสลิปตอบกลับมา
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// SlipImage คือรูปสลิปที่ส่งตรวจ
+type SlipImage struct {
+	Data        []byte
+	Filename    string
+	ContentType string
+}
+
+// SlipResult คือข้อมูลสลิปในรูปแบบเดียวกันไม่ว่าจะมาจาก provider ไหน
+type SlipResult struct {
+	Provider        string    `json:"provider"`
+	TransRef        string    `json:"trans_ref"`
+	Amount          float64   `json:"amount"`
+	Date            time.Time `json:"date"`
+	SenderName      string    `json:"sender_name"`
+	SenderBank      string    `json:"sender_bank"`
+	SenderAccount   string    `json:"sender_account"`
+	ReceiverName    string    `json:"receiver_name"`
+	ReceiverBank    string    `json:"receiver_bank"`
+	ReceiverAccount string    `json:"receiver_account"`
+}
+
+// SlipVerifier ตรวจสลิปโอนเงินแล้วคืนข้อมูลที่ normalise แล้ว
+type SlipVerifier interface {
+	Name() string
+	Verify(ctx context.Context, img SlipImage) (*SlipResult, error)
+}
+
+// rawVerifier คือ provider ที่คืน JSON ดิบได้ด้วย (ใช้กับ endpoint เดิมของ frontend)
+type rawVerifier interface {
+	SlipVerifier
+	fetch(ctx context.Context, img SlipImage) ([]byte, error)
+}
+
+func getenv(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// NewVerifier สร้าง SlipVerifier ตามชื่อ provider (ค่าว่าง = ตาม env SLIP_PROVIDER)
+func NewVerifier(provider string) (SlipVerifier, error) {
+	if provider == "" {
+		provider = getenv("SLIP_PROVIDER", ProviderSlipOK)
+	}
+	switch strings.ToLower(provider) {
+	case ProviderThunder:
+		return NewThunderVerifier()
+	case ProviderSlipOK:
+		return NewSlipOKVerifier(), nil
+	case ProviderMock:
+		return NewMockVerifier(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+}
+
+// ============================================================================
+// 🔹 helper แปลงค่าจาก JSON ของ provider
+// ============================================================================
+
+// ✅ แปลงยอดเงินที่อาจเป็นตัวเลขหรือ string เช่น "1,500.00"
+func parseAmount(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return math.Round(x*100) / 100
+	case string:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(x), ",", ""), 64)
+		if err != nil {
+			return 0
+		}
+		return math.Round(f*100) / 100
+	default:
+		return 0
+	}
+}
+
+var slipDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+}
+
+// bangkok ใช้ตีความวันที่ในสลิปที่ไม่มี timezone
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+func parseSlipDate(v string) time.Time {
+	v = strings.TrimSpace(v)
+	for _, layout := range slipDateLayouts {
+		if t, err := time.ParseInLocation(layout, v, bangkok); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func stringField(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return strings.TrimSpace(v)
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package tariff
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+)
+
+// Location คือเวลาที่ใช้ตัดช่วง TOU (ประเทศไทย ไม่มี DST)
+var Location = time.FixedZone("ICT", 7*60*60)
+
+// EnergyPoint คือค่ามิเตอร์สะสม (Wh) ณ เวลาหนึ่ง ใช้แบ่งพลังงานตามช่วงเวลา
+type EnergyPoint struct {
+	At time.Time
+	Wh float64
+}
+
+// BandUsage คือยอดใช้งานในแต่ละช่วงราคา
+type BandUsage struct {
+	Label      string  `json:"label"`
+	PerKWh     float64 `json:"per_kwh"`
+	PerMinute  float64 `json:"per_minute"`
+	KWh        float64 `json:"kwh"`
+	Minutes    float64 `json:"minutes"`
+	EnergyCost float64 `json:"energy_cost"`
+	TimeCost   float64 `json:"time_cost"`
+}
+
+// Breakdown คือใบแจกแจงค่าใช้จ่ายของหนึ่ง session
+type Breakdown struct {
+	TariffID   uint        `json:"tariff_id"`
+	TariffName string      `json:"tariff_name"`
+	Start      time.Time   `json:"start"`
+	End        time.Time   `json:"end"`
+	KWh        float64     `json:"kwh"`
+	Minutes    float64     `json:"minutes"`
+	Bands      []BandUsage `json:"bands"`
+	SessionFee float64     `json:"session_fee"`
+	EnergyCost float64     `json:"energy_cost"`
+	TimeCost   float64     `json:"time_cost"`
+	Total      float64     `json:"total"`
+}
+
+const baseBandLabel = "Standard"
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ✅ band ใช้กับเวลานี้หรือไม่
+func bandMatches(b entity.TariffBand, t time.Time) bool {
+	local := t.In(Location)
+	if strings.TrimSpace(b.Weekdays) != "" {
+		day := strconv.Itoa(int(local.Weekday()))
+		found := false
+		for _, d := range strings.Split(b.Weekdays, ",") {
+			if strings.TrimSpace(d) == day {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	if b.StartMinute == b.EndMinute {
+		return true // ทั้งวัน
+	}
+	if b.StartMinute < b.EndMinute {
+		return minute >= b.StartMinute && minute < b.EndMinute
+	}
+	// ข้ามเที่ยงคืน เช่น 22:00–09:00
+	return minute >= b.StartMinute || minute < b.EndMinute
+}
+
+// RateAt คืนช่วงราคาที่ใช้ ณ เวลา t (band แรกที่ตรง หรืออัตราพื้นฐานของ Tariff)
+func RateAt(t entity.Tariff, at time.Time) (label string, perKWh, perMinute float64) {
+	_, label, perKWh, perMinute = rateAt(t, at)
+	return
+}
+
+// ✅ เหมือน RateAt แต่คืนลำดับ band ด้วย (-1 = อัตราพื้นฐาน) — label ซ้ำกันได้ จึงใช้ลำดับเป็น key
+func rateAt(t entity.Tariff, at time.Time) (index int, label string, perKWh, perMinute float64) {
+	for i, b := range t.Bands {
+		if bandMatches(b, at) {
+			return i, b.Label, b.PerKWh, b.PerMinute
+		}
+	}
+	return -1, baseBandLabel, t.PerKWh, t.PerMinute
+}
+
+// Calculate คิดค่าใช้จ่ายตาม Tariff จากช่วงเวลาชาร์จและพลังงานที่จ่าย
+// ถ้ามี points (ค่ามิเตอร์สะสม) จะแบ่ง kWh ตามเวลาที่จ่ายจริง ไม่งั้นเฉลี่ยตามเวลา
+func Calculate(t entity.Tariff, start, end time.Time, kWh float64, points []EnergyPoint) Breakdown {
+	if end.Before(start) {
+		end = start
+	}
+
+	usage := map[int]*BandUsage{}
+	order := []int{}
+	keyFor := func(at time.Time) int {
+		index, label, perKWh, perMinute := rateAt(t, at)
+		if _, ok := usage[index]; !ok {
+			usage[index] = &BandUsage{Label: label, PerKWh: perKWh, PerMinute: perMinute}
+			order = append(order, index)
+		}
+		return index
+	}
+
+	// 🔸 นับนาทีในแต่ละช่วง (ทีละไม่เกิน 1 นาที)
+	totalMinutes := end.Sub(start).Minutes()
+	for cur := start; cur.Before(end); {
+		next := cur.Add(time.Minute)
+		if next.After(end) {
+			next = end
+		}
+		usage[keyFor(cur)].Minutes += next.Sub(cur).Minutes()
+		cur = next
+	}
+
+	// 🔸 แบ่ง kWh ตามช่วง
+	sorted := append([]EnergyPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+	var measured float64
+	deltas := map[int]float64{}
+	for i := 1; i < len(sorted); i++ {
+		d := sorted[i].Wh - sorted[i-1].Wh
+		if d <= 0 {
+			continue
+		}
+		mid := sorted[i-1].At.Add(sorted[i].At.Sub(sorted[i-1].At) / 2)
+		deltas[keyFor(mid)] += d
+		measured += d
+	}
+
+	if measured > 0 {
+		for key, d := range deltas {
+			usage[key].KWh = kWh * d / measured
+		}
+	} else if totalMinutes > 0 {
+		for _, u := range usage {
+			u.KWh = kWh * u.Minutes / totalMinutes
+		}
+	} else {
+		usage[keyFor(start)].KWh = kWh
+	}
+
+	bd := Breakdown{
+		TariffID:   t.ID,
+		TariffName: t.Name,
+		Start:      start,
+		End:        end,
+		KWh:        kWh,
+		Minutes:    round2(totalMinutes),
+		SessionFee: t.SessionFee,
+	}
+	for _, key := range order {
+		u := usage[key]
+		u.EnergyCost = round2(u.KWh * u.PerKWh)
+		u.TimeCost = round2(u.Minutes * u.PerMinute)
+		u.Minutes = round2(u.Minutes)
+		bd.EnergyCost += u.EnergyCost
+		bd.TimeCost += u.TimeCost
+		bd.Bands = append(bd.Bands, *u)
+	}
+	bd.EnergyCost = round2(bd.EnergyCost)
+	bd.TimeCost = round2(bd.TimeCost)
+	bd.Total = round2(bd.SessionFee + bd.EnergyCost + bd.TimeCost)
+	return bd
+}
+
+// EnergyFor คำนวณพลังงาน (kWh) ที่ซื้อได้ด้วยเงิน budget เมื่อเริ่มชาร์จเวลา at
+// หักค่าบริการตามสัดส่วน share ก่อน แล้วหารด้วยราคา/kWh ของช่วงเวลานั้น
+// ค่าเวลา (PerMinute) ยังไม่รู้ตอนชำระเงิน — คิดตามจริงตอนจบ transaction
+func EnergyFor(t entity.Tariff, at time.Time, budget, share float64) (float64, Breakdown) {
+	_, _, perKWh, _ := rateAt(t, at)
+	kWh := 0.0
+	if perKWh > 0 {
+		kWh = math.Max(budget-t.SessionFee*share, 0) / perKWh
+	}
+	return kWh, Calculate(t, at, at, kWh, nil).Share(share)
+}
+
+// Find หา Tariff ที่ใช้กับตู้/ประเภทหัวชาร์จ ณ เวลา at
+// ลำดับ: ตรงทั้งตู้และประเภท > ตรงตู้ > ตรงประเภท > ใช้ทุกที่ แล้วดู Priority
+func Find(cabinetID, typeID *uint, at time.Time) *entity.Tariff {
+	var candidates []entity.Tariff
+	config.DB().Preload("Bands").
+		Where("active = ?", true).
+		Where("valid_from IS NULL OR valid_from <= ?", at).
+		Where("valid_to IS NULL OR valid_to > ?", at).
+		Find(&candidates)
+
+	var best *entity.Tariff
+	bestScore := -1
+	for i := range candidates {
+		t := &candidates[i]
+		score := 0
+		if t.EVCabinetID != nil {
+			if cabinetID == nil || *t.EVCabinetID != *cabinetID {
+				continue
+			}
+			score += 2
+		}
+		if t.TypeID != nil {
+			if typeID == nil || *t.TypeID != *typeID {
+				continue
+			}
+			score++
+		}
+		if score > bestScore || (score == bestScore && t.Priority > best.Priority) {
+			best, bestScore = t, score
+		}
+	}
+	return best
+}
+
+// Share ปรับค่าที่ไม่ขึ้นกับ kWh (ค่าบริการ/ค่าเวลา) ตามสัดส่วน เมื่อ session ถูกแบ่งคิดหลายรายการ
+func (b Breakdown) Share(ratio float64) Breakdown {
+	if ratio >= 1 {
+		return b
+	}
+	out := b
+	out.Bands = make([]BandUsage, len(b.Bands))
+	out.TimeCost = 0
+	for i, u := range b.Bands {
+		u.TimeCost = round2(u.TimeCost * ratio)
+		out.TimeCost += u.TimeCost
+		out.Bands[i] = u
+	}
+	out.TimeCost = round2(out.TimeCost)
+	out.SessionFee = round2(b.SessionFee * ratio)
+	out.Total = round2(out.SessionFee + out.EnergyCost + out.TimeCost)
+	return out
+}
@@ -0,0 +1,256 @@
+package tariff
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type bandInput struct {
+	Label     string  `json:"label"`
+	Weekdays  string  `json:"weekdays"` // "1,2,3,4,5"
+	Start     string  `json:"start"`    // "09:00"
+	End       string  `json:"end"`      // "22:00"
+	PerKWh    float64 `json:"per_kwh"`
+	PerMinute float64 `json:"per_minute"`
+}
+
+type tariffInput struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	PerKWh      float64     `json:"per_kwh"`
+	PerMinute   float64     `json:"per_minute"`
+	SessionFee  float64     `json:"session_fee"`
+	ValidFrom   *time.Time  `json:"valid_from"`
+	ValidTo     *time.Time  `json:"valid_to"`
+	Active      *bool       `json:"active"`
+	Priority    int         `json:"priority"`
+	EVCabinetID *uint       `json:"ev_cabinet_id"`
+	TypeID      *uint       `json:"type_id"`
+	Bands       []bandInput `json:"bands"`
+}
+
+// ✅ แปลง "HH:MM" เป็นนาทีของวัน ("24:00" = 1440)
+func parseClock(v string) (int, error) {
+	parts := strings.Split(v, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("เวลา %q ต้องเป็นรูปแบบ HH:MM", v)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("เวลา %q ไม่ถูกต้อง", v)
+	}
+	return h*60 + m, nil
+}
+
+func (in tariffInput) toEntity() (entity.Tariff, error) {
+	t := entity.Tariff{
+		Name:        in.Name,
+		Description: in.Description,
+		PerKWh:      in.PerKWh,
+		PerMinute:   in.PerMinute,
+		SessionFee:  in.SessionFee,
+		ValidFrom:   in.ValidFrom,
+		ValidTo:     in.ValidTo,
+		Active:      in.Active == nil || *in.Active,
+		Priority:    in.Priority,
+		EVCabinetID: in.EVCabinetID,
+		TypeID:      in.TypeID,
+	}
+	if t.ValidFrom != nil && t.ValidTo != nil && !t.ValidTo.After(*t.ValidFrom) {
+		return t, fmt.Errorf("valid_to ต้องอยู่หลัง valid_from")
+	}
+	for _, b := range in.Bands {
+		start, err := parseClock(b.Start)
+		if err != nil {
+			return t, err
+		}
+		end, err := parseClock(b.End)
+		if err != nil {
+			return t, err
+		}
+		t.Bands = append(t.Bands, entity.TariffBand{
+			Label:       b.Label,
+			Weekdays:    b.Weekdays,
+			StartMinute: start,
+			EndMinute:   end,
+			PerKWh:      b.PerKWh,
+			PerMinute:   b.PerMinute,
+		})
+	}
+	return t, nil
+}
+
+// GET /tariffs
+func ListTariffs(c *gin.Context) {
+	var tariffs []entity.Tariff
+
+	db := config.DB()
+	if err := db.Preload("Bands").Preload("EVCabinet").Preload("Type").Find(&tariffs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tariffs)
+}
+
+// POST /create-tariff
+func CreateTariff(c *gin.Context) {
+	var input tariffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := input.toEntity()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB().Create(&t).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Tariff created successfully", "data": t})
+}
+
+// PUT /tariffs/:id — แทนที่ข้อมูลและ band ทั้งหมด
+func UpdateTariffByID(c *gin.Context) {
+	id := c.Param("id")
+	db := config.DB()
+
+	var existing entity.Tariff
+	if err := db.First(&existing, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Tariff"})
+		return
+	}
+
+	var input tariffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := input.toEntity()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t.ID = existing.ID
+	t.CreatedAt = existing.CreatedAt
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tariff_id = ?", t.ID).Delete(&entity.TariffBand{}).Error; err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&t).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตข้อมูลไม่สำเร็จ"})
+		return
+	}
+
+	db.Preload("Bands").First(&t, t.ID)
+	c.JSON(http.StatusOK, gin.H{"data": t})
+}
+
+// DELETE /tariffs/:id
+func DeleteTariffByID(c *gin.Context) {
+	id := c.Param("id")
+	db := config.DB()
+
+	var t entity.Tariff
+	if err := db.First(&t, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Tariff"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tariff_id = ?", t.ID).Delete(&entity.TariffBand{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&t).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบข้อมูลไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ลบ Tariff สำเร็จ"})
+}
+
+// Flat สร้าง Tariff ชั่วคราวจาก EVcharging.Price สำหรับหัวชาร์จที่ยังไม่มี Tariff
+func Flat(ev entity.EVcharging) entity.Tariff {
+	return entity.Tariff{Name: ev.Name, PerKWh: ev.Price, Active: true}
+}
+
+// Resolve หา Tariff ของหัวชาร์จในตู้นี้ ถ้าไม่มีใช้ราคาเดิมของ EVcharging
+func Resolve(cabinetID *uint, ev entity.EVcharging, at time.Time) entity.Tariff {
+	typeID := ev.TypeID
+	if t := Find(cabinetID, &typeID, at); t != nil {
+		return *t
+	}
+	return Flat(ev)
+}
+
+// POST /tariffs/quote
+// ประเมินราคาก่อนชาร์จ: ส่ง kwh + minutes (+ start) และ tariff_id หรือ ev_cabinet_id/evcharging_id
+func QuoteTariff(c *gin.Context) {
+	var input struct {
+		TariffID     *uint      `json:"tariff_id"`
+		EVCabinetID  *uint      `json:"ev_cabinet_id"`
+		EVchargingID *uint      `json:"evcharging_id"`
+		KWh          float64    `json:"kwh"`
+		Minutes      float64    `json:"minutes"`
+		Start        *time.Time `json:"start"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.KWh < 0 || input.Minutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kwh และ minutes ต้องไม่ติดลบ"})
+		return
+	}
+
+	start := time.Now()
+	if input.Start != nil {
+		start = *input.Start
+	}
+	end := start.Add(time.Duration(input.Minutes * float64(time.Minute)))
+
+	db := config.DB()
+	var t entity.Tariff
+	switch {
+	case input.TariffID != nil:
+		if err := db.Preload("Bands").First(&t, *input.TariffID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล Tariff"})
+			return
+		}
+	case input.EVchargingID != nil:
+		var ev entity.EVcharging
+		if err := db.First(&ev, *input.EVchargingID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูล EV Charging"})
+			return
+		}
+		t = Resolve(input.EVCabinetID, ev, start)
+	default:
+		found := Find(input.EVCabinetID, nil, start)
+		if found == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ Tariff ที่ใช้ได้"})
+			return
+		}
+		t = *found
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": Calculate(t, start, end, input.KWh, nil)})
+}
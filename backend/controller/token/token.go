@@ -1,21 +1,108 @@
 package tokening
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/ocpp"
+	"github.com/Tawunchai/work-project/controller/session"
+	"github.com/Tawunchai/work-project/controller/tokensign"
 	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/chargetoken"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// ✅ เมื่อจ่ายเงินสำเร็จ (Coin หรือ QR)
+var (
+	// ErrPaymentNotApproved เมื่อขอ token ของ Payment ที่ยังไม่ได้รับอนุมัติ
+	ErrPaymentNotApproved = errors.New("payment not approved")
+	// ErrNotPaymentOwner เมื่อผู้ขอ token ไม่ใช่เจ้าของ Payment
+	ErrNotPaymentOwner = errors.New("payment does not belong to this user")
+)
+
+// IssueSession ออก token สำหรับ session การชาร์จของ Payment ที่อนุมัติแล้ว (รูปแบบตาม CHARGING_TOKEN_FORMAT)
+// ถ้ามี session ที่ยังใช้งานอยู่ของ Payment นี้แล้วจะคืนอันเดิม — ออกให้เฉพาะเจ้าของ Payment เท่านั้น
+func IssueSession(db *gorm.DB, userID, paymentID uint) (*entity.ChargingSession, error) {
+	return issueSession(db, userID, paymentID, tokensign.DefaultFormat())
+}
+
+func issueSession(db *gorm.DB, userID, paymentID uint, format string) (*entity.ChargingSession, error) {
+	var payment entity.Payment
+	if err := db.First(&payment, paymentID).Error; err != nil {
+		return nil, err
+	}
+	if payment.UserID == nil || *payment.UserID != userID {
+		return nil, ErrNotPaymentOwner
+	}
+	if payment.Status != entity.PaymentApproved {
+		return nil, ErrPaymentNotApproved
+	}
+
+	var existing entity.ChargingSession
+	if err := db.Where("payment_id = ? AND user_id = ? AND status IN ?", paymentID, userID, entity.SessionActiveStates).
+		Order("id DESC").First(&existing).Error; err == nil {
+		if err := session.ExpireIfDue(db, &existing); err == nil {
+			return &existing, nil
+		} else if !errors.Is(err, session.ErrExpired) {
+			return nil, err
+		}
+	}
+
+	// 🟦 สร้าง token สำหรับ session การชาร์จ
+	now := time.Now()
+	cs := entity.ChargingSession{
+		UserID:          userID,
+		Token:           uuid.New().String(),
+		ExpiresAt:       now.Add(300 * time.Minute),
+		Status:          entity.SessionCreated,
+		StatusReason:    "ออก token หลังชำระเงิน",
+		StatusChangedAt: &now,
+		PaymentID:       paymentID,
+	}
+	if err := db.Create(&cs).Error; err != nil {
+		return nil, err
+	}
+	// 🔸 token แบบลงชื่อต้องรู้ session ID ก่อน → ลงชื่อหลังสร้างแล้วแทนที่ UUID (UUID กลายเป็น jti)
+	if format == tokensign.FormatSigned {
+		signed, err := tokensign.Sign(db, &cs, cs.Token)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Model(&cs).Update("token", signed).Error; err != nil {
+			return nil, err
+		}
+		cs.Token = signed
+	}
+	if err := db.Create(&entity.ChargingSessionEvent{
+		ChargingSessionID: cs.ID,
+		ToStatus:          entity.SessionCreated,
+		Reason:            cs.StatusReason,
+		Source:            session.SourceAPI,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+// ✅ เมื่อจ่ายเงินสำเร็จ (Coin หรือ QR) — ออก token ได้เฉพาะ Payment ที่อนุมัติแล้ว
+// route อยู่หลัง JwtAuth — เจ้าของ Payment มาจาก token เท่านั้น (ไม่เชื่อ user_id ใน body)
 func PaymentSuccess(c *gin.Context) {
 	var req struct {
-		UserID    uint `json:"user_id"`
-		PaymentID uint `json:"payment_id"`
+		PaymentID   uint   `json:"payment_id"`
+		TokenFormat string `json:"token_format"` // uuid | signed (ไม่ระบุ = ตามค่าระบบ)
+	}
+
+	userID := c.GetInt("UserID")
+	if userID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+		return
 	}
 
 	// 🟦 ตรวจสอบข้อมูลที่ส่งมา
@@ -23,65 +110,155 @@ func PaymentSuccess(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request data"})
 		return
 	}
-	if req.UserID == 0 || req.PaymentID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user_id or payment_id"})
+	if req.PaymentID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing payment_id"})
 		return
 	}
 
-	// 🟦 ตรวจสอบว่ามี Payment จริงในระบบ
-	var payment entity.Payment
-	if err := config.DB().First(&payment, req.PaymentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
-		return
+	if req.TokenFormat == "" {
+		req.TokenFormat = tokensign.DefaultFormat()
 	}
-
-	// 🟦 สร้าง token สำหรับ session การชาร์จ
-	token := uuid.New().String()
-
-	session := entity.ChargingSession{
-		UserID:    req.UserID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(300 * time.Minute),
-		Status:    true,
-		PaymentID: req.PaymentID,
+	if !tokensign.ValidFormat(req.TokenFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token_format ต้องเป็น uuid หรือ signed"})
+		return
 	}
 
-	// 🟦 บันทึกลงฐานข้อมูล
-	if err := config.DB().Create(&session).Error; err != nil {
+	cs, err := issueSession(config.DB(), uint(userID), req.PaymentID, req.TokenFormat)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		return
+	case errors.Is(err, ErrNotPaymentOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrPaymentNotApproved):
+		var payment entity.Payment
+		config.DB().First(&payment, req.PaymentID)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": payment.Status})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create session"})
 		return
 	}
 
 	// 🟦 ส่งกลับให้ frontend
 	c.JSON(http.StatusOK, gin.H{
-		"charging_token": token,
-		"expires_at":     session.ExpiresAt,
-		"user_id":        session.UserID,
-		"payment_id":     session.PaymentID,
-		"status":         session.Status,
+		"charging_token": cs.Token,
+		"expires_at":     cs.ExpiresAt,
+		"user_id":        cs.UserID,
+		"payment_id":     cs.PaymentID,
+		"status":         cs.Status,
 	})
 }
 
 func VerifyChargingSession(c *gin.Context) {
 	token := c.Query("token")
-	var session entity.ChargingSession
+	var cs entity.ChargingSession
+	db := config.DB()
+
+	// 0) token แบบลงชื่อ → ตรวจลายเซ็นและ denylist ก่อน (หมดอายุตัดสินตามสถานะ session ด้านล่าง)
+	var claims *chargetoken.Claims
+	if chargetoken.IsSigned(token) {
+		var err error
+		claims, err = tokensign.Verify(db, token)
+		switch {
+		case errors.Is(err, chargetoken.ErrExpired):
+			// ให้ ExpireIfDue ด้านล่างตัดสิน (session ที่กำลังชาร์จอยู่ยังใช้ต่อได้)
+		case errors.Is(err, tokensign.ErrRevoked):
+			c.JSON(http.StatusForbidden, gin.H{"error": "token revoked"})
+			return
+		case err != nil:
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid token"})
+			return
+		}
+	}
 
 	// 1) ตรวจว่า token มีจริงไหม
-	if err := config.DB().Where("token = ?", token).First(&session).Error; err != nil {
+	if err := db.Where("token = ?", token).First(&cs).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "invalid token"})
 		return
 	}
 
-	// 2) ตรวจเฉพาะสถานะ session ต้องเป็น true เท่านั้น
-	if !session.Status {
-		c.JSON(http.StatusForbidden, gin.H{"error": "session not active"})
+	// 2) token ที่เลยเวลาและยังไม่ได้เริ่มชาร์จ → expired
+	if err := session.ExpireIfDue(db, &cs); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session expired", "status": cs.Status})
+		return
+	}
+
+	// 3) session ต้องยังไม่จบ
+	if !entity.IsSessionActive(cs.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session not active", "status": cs.Status})
 		return
 	}
 
 	// ✔ ผ่าน — token ใช้ได้ และอยู่ในสถานะ active
+	resp := gin.H{
+		"ok":         true,
+		"status":     cs.Status,
+		"expires_at": cs.ExpiresAt,
+	}
+	if claims != nil {
+		resp["claims"] = claims
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /charging-tokens/revoke — admin ยกเลิก token (ระบุ token หรือ session_id)
+// session ที่ยังไม่จบจะถูก cancel ด้วย, token แบบลงชื่อจะเข้า denylist ให้ตู้ที่ตรวจ offline
+// ผู้ทำรายการมาจาก JWT (JwtAuth) เสมอ
+func RevokeChargingToken(c *gin.Context) {
+	var req struct {
+		Token     string `json:"token"`
+		SessionID uint   `json:"session_id"`
+		Reason    string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Token == "" && req.SessionID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ token หรือ session_id"})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "ยกเลิก token โดยผู้ดูแล"
+	}
+	var actorID *uint
+	if v, ok := c.Get("UserID"); ok {
+		if id, ok := v.(int); ok && id > 0 {
+			uid := uint(id)
+			actorID = &uid
+		}
+	}
+	if actorID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+		return
+	}
+
+	db := config.DB()
+	var cs entity.ChargingSession
+	query := db.Where("token = ?", req.Token)
+	if req.SessionID > 0 {
+		query = db.Where("id = ?", req.SessionID)
+	}
+	if err := query.First(&cs).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ session ของ token นี้"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 🔸 ใส่ denylist ก่อน Move (Move ก็ใส่ให้แต่ไม่รู้ผู้ทำรายการ) — session ที่จบไปแล้วก็ยังต้องเข้า denylist
+		if err := tokensign.Revoke(tx, cs.Token, cs.ID, req.Reason, actorID); err != nil {
+			return err
+		}
+		_, err := session.Move(tx, &cs, entity.SessionCancelled, req.Reason, session.SourceAPI)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"ok":     true,
-		"status": session.Status,
+		"message":    "ยกเลิก token สำเร็จ",
+		"session_id": cs.ID,
+		"status":     cs.Status,
+		"signed":     chargetoken.IsSigned(cs.Token),
 	})
 }
 
@@ -98,12 +275,13 @@ func GetDataByUserID(c *gin.Context) {
 		return
 	}
 
-	// 2) Query DB - เฉพาะ status = true เท่านั้น
+	// 2) Query DB - เฉพาะ session ที่ยังไม่จบ (หมดอายุแล้วไม่นับ)
 	var sessions []entity.ChargingSession
 	db := config.DB()
+	session.ExpireDue(db)
 
 	err = db.
-		Where("user_id = ? AND status = ?", uint(userID), true).
+		Where("user_id = ? AND status IN ?", uint(userID), entity.SessionActiveStates).
 		Preload("Payment").
 		Find(&sessions).Error
 
@@ -116,12 +294,11 @@ func GetDataByUserID(c *gin.Context) {
 
 	// 3) ส่งข้อมูลกลับ
 	c.JSON(http.StatusOK, gin.H{
-		"data": sessions,
+		"data": session.Views(sessions),
 	})
 }
 
-
-// ✅ อัปเดต Status = false โดยอ้างอิงจาก PaymentID
+// ✅ ยกเลิก session ของ PaymentID (สั่งหยุด transaction ที่ยังชาร์จอยู่ด้วย)
 func UpdateStatusByPaymentID(c *gin.Context) {
 
 	// 1) รับค่า payment_id จาก URL
@@ -146,75 +323,258 @@ func UpdateStatusByPaymentID(c *gin.Context) {
 		return
 	}
 
-	// 3) อัปเดต Status = false
-	if err := db.Model(&entity.ChargingSession{}).
-		Where("payment_id = ?", uint(paymentID)).
-		Update("status", false).Error; err != nil {
+	// 3) สั่ง RemoteStop ทุก transaction ที่ยังชาร์จอยู่ของ Payment นี้
+	sessionIDs := make([]uint, 0, len(sessions))
+	for _, s := range sessions {
+		sessionIDs = append(sessionIDs, s.ID)
+	}
+	var openTxs []entity.ChargingTransaction
+	db.Where("charging_session_id IN ? AND stopped_at IS NULL", sessionIDs).Find(&openTxs)
+
+	remoteStops := make([]gin.H, 0, len(openTxs))
+	for _, tx := range openTxs {
+		result := gin.H{"charger_id": tx.ChargerID, "transaction_id": tx.TransactionID}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), remoteCallTimeout)
+		resp, err := ocpp.RemoteStopTransaction(ctx, tx.ChargerID, tx.TransactionID)
+		cancel()
+		if err != nil {
+			result["error"] = err.Error()
+		} else {
+			result["status"] = resp.Status
+		}
+		remoteStops = append(remoteStops, result)
+	}
 
+	// 4) เปลี่ยนสถานะเป็น cancelled
+	var cancelled int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		cancelled, err = session.CancelByPayment(tx, uint(paymentID), "ยกเลิกโดยผู้ใช้", session.SourceAPI)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปเดตสถานะไม่สำเร็จ"})
 		return
 	}
 
-	// 4) ส่ง Response กลับ
+	// 5) ส่ง Response กลับ
 	c.JSON(http.StatusOK, gin.H{
 		"message":         "อัปเดตสถานะสำเร็จ",
 		"payment_id":      paymentID,
-		"updated_records": len(sessions),
+		"updated_records": cancelled,
+		"remote_stop":     remoteStops,
+	})
+}
+
+// ✅ เวลารอคำตอบจาก charger สำหรับคำสั่งจาก REST
+const remoteCallTimeout = 20 * time.Second
+
+// ✅ แปลง error จากการสั่ง charger เป็น HTTP status
+func remoteErrorStatus(err error) int {
+	var callErr *ocpp.CallError
+	switch {
+	case errors.Is(err, ocpp.ErrChargerNotConnected), errors.Is(err, ocpp.ErrChargerDisconnected):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ocpp.ErrCallTimeout):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &callErr):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// POST /charging-session/remote-start
+// สั่ง charger เริ่มชาร์จโดยใช้ token ของ session เป็น idTag
+func RemoteStart(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token"`
+		ChargerID   string `json:"charger_id"`
+		ConnectorID int    `json:"connector_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request data"})
+		return
+	}
+	if req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+
+	db := config.DB()
+	var cs entity.ChargingSession
+	if err := db.Preload("Payment").Where("token = ?", req.Token).First(&cs).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid token"})
+		return
+	}
+	if err := session.ExpireIfDue(db, &cs); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session expired"})
+		return
+	}
+	if cs.Status != entity.SessionCreated && cs.Status != entity.SessionAuthorized {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session not active", "status": cs.Status})
+		return
+	}
+
+	// 🟦 เริ่มได้เฉพาะเครื่องชาร์จของตู้ที่จ่ายเงินไว้ — ไม่ระบุ charger_id → ใช้เครื่องแรกของตู้
+	if cs.Payment.EVCabinetID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment has no linked cabinet"})
+		return
+	}
+	ids := ocpp.ChargerIDsForCabinet(*cs.Payment.EVCabinetID)
+	if req.ChargerID == "" && len(ids) > 0 {
+		req.ChargerID = ids[0]
+	}
+	if req.ChargerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing charger_id and payment has no linked charger"})
+		return
+	}
+	if !slices.Contains(ids, req.ChargerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "charger_id ไม่ใช่เครื่องชาร์จของตู้ที่ชำระเงินไว้"})
+		return
+	}
+
+	resp, err := StartRemote(c.Request.Context(), db, &cs, req.ChargerID, req.ConnectorID)
+	if err != nil {
+		c.JSON(RemoteStartError(resp, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "ส่งคำสั่งเริ่มชาร์จสำเร็จ",
+		"charger_id":   req.ChargerID,
+		"connector_id": req.ConnectorID,
+		"payment_id":   cs.PaymentID,
+		"status":       resp.Status,
+	})
+}
+
+// ErrRemoteStartRejected เมื่อ charger ตอบ Rejected
+var ErrRemoteStartRejected = errors.New("charger rejected remote start")
+
+// StartRemote สั่ง RemoteStartTransaction ด้วย token ของ session แล้วเปลี่ยนเป็น authorized
+func StartRemote(parent context.Context, db *gorm.DB, cs *entity.ChargingSession, chargerID string, connectorID int) (*ocpp.RemoteResponse, error) {
+	ctx, cancel := context.WithTimeout(parent, remoteCallTimeout)
+	defer cancel()
+
+	resp, err := ocpp.RemoteStartTransaction(ctx, chargerID, connectorID, cs.Token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != ocpp.RemoteAccepted {
+		return resp, ErrRemoteStartRejected
+	}
+
+	// 🔸 charger รับคำสั่งแล้ว → authorized (StartTransaction จะเปลี่ยนเป็น charging)
+	if _, err := session.Move(db, cs, entity.SessionAuthorized, "RemoteStart "+chargerID, session.SourceAPI); err != nil {
+		fmt.Println("⚠️ Cannot update session status:", err)
+	}
+	return resp, nil
+}
+
+// RemoteStartError แปลง error จาก StartRemote เป็น HTTP status และ body
+func RemoteStartError(resp *ocpp.RemoteResponse, err error) (int, gin.H) {
+	if errors.Is(err, ErrRemoteStartRejected) {
+		return http.StatusConflict, gin.H{"error": err.Error(), "status": resp.Status}
+	}
+	return remoteErrorStatus(err), gin.H{"error": err.Error()}
+}
+
+// POST /charging-session/remote-stop
+// สั่ง charger หยุด transaction ที่เริ่มด้วย token นี้
+func RemoteStop(c *gin.Context) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
+		return
+	}
+
+	var tx entity.ChargingTransaction
+	if err := config.DB().
+		Where("id_tag = ? AND stopped_at IS NULL", req.Token).
+		Order("started_at DESC").
+		First(&tx).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no active transaction for this token"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), remoteCallTimeout)
+	defer cancel()
+
+	resp, err := ocpp.RemoteStopTransaction(ctx, tx.ChargerID, tx.TransactionID)
+	if err != nil {
+		c.JSON(remoteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if resp.Status != ocpp.RemoteAccepted {
+		c.JSON(http.StatusConflict, gin.H{"error": "charger rejected remote stop", "status": resp.Status})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "ส่งคำสั่งหยุดชาร์จสำเร็จ",
+		"charger_id":     tx.ChargerID,
+		"transaction_id": tx.TransactionID,
+		"status":         resp.Status,
 	})
 }
 
-// GET /charging-session/status/true
+// GET /charging-session/status/true — session ที่ยังไม่จบทั้งหมด
 func GetChargingSessionByStatus(c *gin.Context) {
-    var sessions []entity.ChargingSession
-
-    db := config.DB()
-
-    // Query เฉพาะ Status = true
-    if err := db.
-        Where("status = ?", true).
-        Preload("Payment").
-        Preload("Payment.EVCabinet"). // preload ต่อไปยัง Cabinet
-        Find(&sessions).Error; err != nil {
-
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": err.Error(),
-        })
-        return
-    }
-
-    c.JSON(http.StatusOK, gin.H{
-        "data": sessions,
-    })
+	var sessions []entity.ChargingSession
+
+	db := config.DB()
+	session.ExpireDue(db)
+
+	// Query เฉพาะ session ที่ยังไม่จบ
+	if err := db.
+		Where("status IN ?", entity.SessionActiveStates).
+		Preload("Payment").
+		Preload("Payment.EVCabinet"). // preload ต่อไปยัง Cabinet
+		Find(&sessions).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": session.Views(sessions),
+	})
 }
 
 // GET /charging-session/status/:user_id
 func GetChargingSessionByStatusAndUserID(c *gin.Context) {
 
-    // รับ user_id จาก param
-    userIDParam := c.Param("user_id")
-    userID, err := strconv.ParseUint(userIDParam, 10, 32)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
-        return
-    }
-
-    var sessions []entity.ChargingSession
-    db := config.DB()
-
-    // Query: หาเฉพาะ Status = true และ UserID ที่ส่งมา
-    if err := db.
-        Where("status = ? AND user_id = ?", true, uint(userID)).
-        Preload("Payment").
-        Preload("Payment.EVCabinet").
-        Find(&sessions).Error; err != nil {
-
-        c.JSON(http.StatusInternalServerError, gin.H{
-            "error": err.Error(),
-        })
-        return
-    }
-
-    c.JSON(http.StatusOK, gin.H{
-        "data": sessions,
-    })
-}
\ No newline at end of file
+	// รับ user_id จาก param
+	userIDParam := c.Param("user_id")
+	userID, err := strconv.ParseUint(userIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var sessions []entity.ChargingSession
+	db := config.DB()
+	session.ExpireDue(db)
+
+	// Query: หาเฉพาะ session ที่ยังไม่จบ และ UserID ที่ส่งมา
+	if err := db.
+		Where("status IN ? AND user_id = ?", entity.SessionActiveStates, uint(userID)).
+		Preload("Payment").
+		Preload("Payment.EVCabinet").
+		Find(&sessions).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": session.Views(sessions),
+	})
+}
@@ -0,0 +1,169 @@
+package tokening
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Tawunchai/work-project/config/configtest"
+	"github.com/Tawunchai/work-project/controller/tokensign"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func seedPayment(t *testing.T, db *gorm.DB, userID uint, status string) entity.Payment {
+	t.Helper()
+	p := entity.Payment{Amount: 100, Status: status, UserID: &userID}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func verify(token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/verify-token?token="+url.QueryEscape(token), nil)
+	VerifyChargingSession(c)
+	return w
+}
+
+func revoke(body gin.H, actorID int) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/charging-tokens/revoke", bytes.NewReader(raw))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if actorID > 0 {
+		c.Set("UserID", actorID)
+	}
+	RevokeChargingToken(c)
+	return w
+}
+
+func TestIssueSession(t *testing.T) {
+	db := configtest.Open(t)
+	pending := seedPayment(t, db, 1, entity.PaymentPending)
+	approved := seedPayment(t, db, 1, entity.PaymentApproved)
+
+	if _, err := issueSession(db, 2, approved.ID, tokensign.FormatUUID); !errors.Is(err, ErrNotPaymentOwner) {
+		t.Errorf("other user: err %v, want ErrNotPaymentOwner", err)
+	}
+	if _, err := issueSession(db, 1, pending.ID, tokensign.FormatUUID); !errors.Is(err, ErrPaymentNotApproved) {
+		t.Errorf("pending payment: err %v, want ErrPaymentNotApproved", err)
+	}
+
+	first, err := issueSession(db, 1, approved.ID, tokensign.FormatUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := issueSession(db, 1, approved.ID, tokensign.FormatUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Token != first.Token {
+		t.Errorf("second issue created session %d, want existing %d", again.ID, first.ID)
+	}
+}
+
+func TestVerifyAndRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, format := range []string{tokensign.FormatUUID, tokensign.FormatSigned} {
+		t.Run(format, func(t *testing.T) {
+			db := configtest.Open(t)
+			if _, err := tokensign.Rotate(db); err != nil {
+				t.Fatal(err)
+			}
+			payment := seedPayment(t, db, 1, entity.PaymentApproved)
+			cs, err := issueSession(db, 1, payment.ID, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if w := verify(cs.Token); w.Code != http.StatusOK {
+				t.Fatalf("verify before revoke = %d %s", w.Code, w.Body)
+			}
+			if w := verify("not-a-token"); w.Code != http.StatusForbidden {
+				t.Errorf("verify unknown token = %d, want 403", w.Code)
+			}
+
+			// 🔸 ไม่มีผู้ทำรายการจาก JWT → ไม่ยกเลิก
+			if w := revoke(gin.H{"token": cs.Token}, 0); w.Code != http.StatusUnauthorized {
+				t.Fatalf("revoke without actor = %d, want 401", w.Code)
+			}
+			if w := verify(cs.Token); w.Code != http.StatusOK {
+				t.Fatalf("token stopped working after rejected revoke: %d", w.Code)
+			}
+
+			if w := revoke(gin.H{"token": cs.Token, "reason": "test"}, 9); w.Code != http.StatusOK {
+				t.Fatalf("revoke = %d %s", w.Code, w.Body)
+			}
+			if w := verify(cs.Token); w.Code != http.StatusForbidden {
+				t.Errorf("verify after revoke = %d, want 403", w.Code)
+			}
+
+			var got entity.ChargingSession
+			db.First(&got, cs.ID)
+			if got.Status != entity.SessionCancelled {
+				t.Errorf("session status %q, want %q", got.Status, entity.SessionCancelled)
+			}
+
+			var entries []entity.RevokedToken
+			db.Find(&entries)
+			if format == tokensign.FormatUUID {
+				if len(entries) != 0 {
+					t.Errorf("uuid token added %d denylist entries", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].ChargingSessionID != cs.ID ||
+				entries[0].RevokedBy == nil || *entries[0].RevokedBy != 9 {
+				t.Fatalf("denylist %+v, want one entry revoked by 9", entries)
+			}
+			if _, err := tokensign.Verify(db, cs.Token); !errors.Is(err, tokensign.ErrRevoked) {
+				t.Errorf("tokensign.Verify err %v, want ErrRevoked", err)
+			}
+
+			// ยกเลิกซ้ำไม่เพิ่มรายการใน denylist
+			if w := revoke(gin.H{"session_id": cs.ID}, 9); w.Code != http.StatusOK {
+				t.Fatalf("second revoke = %d %s", w.Code, w.Body)
+			}
+			var n int64
+			db.Model(&entity.RevokedToken{}).Count(&n)
+			if n != 1 {
+				t.Errorf("%d denylist entries after second revoke, want 1", n)
+			}
+		})
+	}
+}
+
+func TestVerifySignedTampered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := configtest.Open(t)
+	if _, err := tokensign.Rotate(db); err != nil {
+		t.Fatal(err)
+	}
+	payment := seedPayment(t, db, 1, entity.PaymentApproved)
+	cs, err := issueSession(db, 1, payment.ID, tokensign.FormatSigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// แก้ตัวอักษรสุดท้ายของลายเซ็น แล้วแทนที่ token ใน DB ให้ตรงกัน → ต้องไม่ผ่านเพราะลายเซ็นผิด
+	last := cs.Token[len(cs.Token)-1]
+	swap := byte('A')
+	if last == 'A' {
+		swap = 'B'
+	}
+	forged := cs.Token[:len(cs.Token)-1] + string(swap)
+	db.Model(&entity.ChargingSession{}).Where("id = ?", cs.ID).Update("token", forged)
+
+	if w := verify(forged); w.Code != http.StatusForbidden {
+		t.Errorf("verify forged token = %d, want 403", w.Code)
+	}
+}
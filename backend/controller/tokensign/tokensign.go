@@ -0,0 +1,269 @@
+package tokensign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services/chargetoken"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ รูปแบบ token การชาร์จ
+const (
+	FormatUUID   = "uuid"   // เดิม: สุ่ม UUID ตรวจได้จากฐานข้อมูลเท่านั้น
+	FormatSigned = "signed" // ลงชื่อด้วย Ed25519 ตรวจ offline ได้
+)
+
+const (
+	// KeyLifetime อายุของ key ที่ใช้ลงชื่อก่อนหมุนเป็น key ใหม่
+	KeyLifetime = 30 * 24 * time.Hour
+	// PublishGrace เผยแพร่ key ที่เลิกใช้ต่ออีกช่วงหนึ่ง (ต้องนานกว่าอายุ token)
+	PublishGrace = 24 * time.Hour
+)
+
+// ErrRevoked เมื่อ token อยู่ใน denylist
+var ErrRevoked = errors.New("chargetoken: token revoked")
+
+// DefaultFormat รูปแบบ token ที่ออกให้ (ตั้งด้วย CHARGING_TOKEN_FORMAT=uuid|signed ค่าเริ่มต้น uuid)
+func DefaultFormat() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("CHARGING_TOKEN_FORMAT")), FormatSigned) {
+		return FormatSigned
+	}
+	return FormatUUID
+}
+
+// ValidFormat บอกว่ารูปแบบที่ขอมาใช้ได้
+func ValidFormat(format string) bool {
+	return format == FormatUUID || format == FormatSigned
+}
+
+// ============================================================================
+// 🔹 Key set
+// ============================================================================
+
+// ActiveKey คืน key ที่ใช้ลงชื่ออยู่ — ถ้ายังไม่มีจะสร้างใหม่
+func ActiveKey(db *gorm.DB) (*entity.SigningKey, error) {
+	var key entity.SigningKey
+	err := db.Where("status = ?", entity.SigningKeyActive).Order("id DESC").First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Rotate(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Rotate สร้าง key ใหม่และเลิกใช้ key เดิม (ยังเผยแพร่ต่อจนถึง PublishUntil)
+func Rotate(db *gorm.DB) (*entity.SigningKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, err
+	}
+
+	key := entity.SigningKey{
+		Kid:        time.Now().Format("20060102") + "-" + hex.EncodeToString(kidBytes),
+		Algorithm:  chargetoken.Algorithm,
+		PublicKey:  pub,
+		PrivateKey: priv,
+		Status:     entity.SigningKeyActive,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		until := now.Add(PublishGrace)
+		if err := tx.Model(&entity.SigningKey{}).Where("status = ?", entity.SigningKeyActive).
+			Updates(map[string]interface{}{
+				"status":        entity.SigningKeyRetired,
+				"retired_at":    now,
+				"publish_until": until,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&key).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RotateIfDue หมุน key เมื่อ key ปัจจุบันอายุเกิน KeyLifetime — คืนจำนวน key ที่สร้าง
+func RotateIfDue(db *gorm.DB) (int, error) {
+	var key entity.SigningKey
+	err := db.Where("status = ?", entity.SigningKeyActive).Order("id DESC").First(&key).Error
+	if err == nil && time.Since(key.CreatedAt) < KeyLifetime {
+		return 0, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if _, err := Rotate(db); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// PublishedKeys คือ key ที่ใช้ตรวจ token ได้ (active + retired ที่ยังไม่พ้น PublishUntil)
+func PublishedKeys(db *gorm.DB) ([]entity.SigningKey, error) {
+	var keys []entity.SigningKey
+	err := db.Where("status = ? OR (status = ? AND publish_until > ?)",
+		entity.SigningKeyActive, entity.SigningKeyRetired, time.Now()).
+		Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func publicKey(db *gorm.DB) func(kid string) (ed25519.PublicKey, bool) {
+	return func(kid string) (ed25519.PublicKey, bool) {
+		var key entity.SigningKey
+		if err := db.Where("kid = ? AND (status = ? OR (status = ? AND publish_until > ?))", kid,
+			entity.SigningKeyActive, entity.SigningKeyRetired, time.Now()).First(&key).Error; err != nil {
+			return nil, false
+		}
+		return ed25519.PublicKey(key.PublicKey), true
+	}
+}
+
+// ============================================================================
+// 🔹 Sign / Verify / Revoke
+// ============================================================================
+
+// Sign ออก token แบบลงชื่อให้ session — jti คือ token UUID เดิมของ session
+func Sign(db *gorm.DB, s *entity.ChargingSession, jti string) (string, error) {
+	key, err := ActiveKey(db)
+	if err != nil {
+		return "", err
+	}
+
+	var payment entity.Payment
+	if err := db.First(&payment, s.PaymentID).Error; err != nil {
+		return "", err
+	}
+	// 🔸 พลังงานที่จองไว้ตอนชำระเงิน (ยังไม่ได้คิดจากมิเตอร์)
+	var maxKWh float64
+	db.Model(&entity.EVChargingPayment{}).
+		Where("payment_id = ? AND charging_transaction_id IS NULL", payment.ID).
+		Select("COALESCE(SUM(power), 0)").Scan(&maxKWh)
+
+	claims := chargetoken.Claims{
+		ID:        jti,
+		SessionID: s.ID,
+		Subject:   s.UserID,
+		PaymentID: payment.ID,
+		CabinetID: payment.EVCabinetID,
+		MaxKWh:    maxKWh,
+		MaxAmount: payment.Amount + payment.Discount,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: s.ExpiresAt.Unix(),
+	}
+	return chargetoken.Sign(ed25519.PrivateKey(key.PrivateKey), key.Kid, claims)
+}
+
+// Verify ตรวจลายเซ็น วันหมดอายุ และ denylist ของ token แบบลงชื่อ
+func Verify(db *gorm.DB, token string) (*chargetoken.Claims, error) {
+	claims, err := chargetoken.Verify(token, publicKey(db), time.Now())
+	if err != nil {
+		return claims, err
+	}
+	var revoked int64
+	db.Model(&entity.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked)
+	if revoked > 0 {
+		return claims, ErrRevoked
+	}
+	return claims, nil
+}
+
+// Revoke ใส่ token แบบลงชื่อลง denylist (token UUID ไม่ต้องทำอะไร — ตรวจจากสถานะ session อยู่แล้ว)
+func Revoke(tx *gorm.DB, token string, sessionID uint, reason string, actorID *uint) error {
+	if !chargetoken.IsSigned(token) {
+		return nil
+	}
+	_, claims, err := chargetoken.Parse(token)
+	if err != nil {
+		return err
+	}
+	entry := entity.RevokedToken{
+		Jti:               claims.ID,
+		ChargingSessionID: sessionID,
+		Reason:            reason,
+		ExpiresAt:         time.Unix(claims.ExpiresAt, 0),
+		RevokedBy:         actorID,
+	}
+	return tx.Where(entity.RevokedToken{Jti: claims.ID}).FirstOrCreate(&entry).Error
+}
+
+// PurgeRevoked ลบรายการ denylist ที่ token หมดอายุไปแล้ว
+func PurgeRevoked(db *gorm.DB) (int, error) {
+	res := db.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{})
+	return int(res.RowsAffected), res.Error
+}
+
+// ============================================================================
+// 🔹 Handlers — สำหรับตู้ / kiosk ที่ตรวจ token เอง
+// ============================================================================
+
+// GET /charging-tokens/keys — public key ในรูปแบบ JWKS
+func GetKeys(c *gin.Context) {
+	db := config.DB()
+	if _, err := ActiveKey(db); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	keys, err := PublishedKeys(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	jwks := make([]chargetoken.JWK, 0, len(keys))
+	for _, k := range keys {
+		jwks = append(jwks, chargetoken.PublicJWK(k.Kid, ed25519.PublicKey(k.PublicKey)))
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": jwks})
+}
+
+// POST /charging-tokens/keys/rotate — admin หมุน key ทันที
+func RotateKeys(c *gin.Context) {
+	key, err := Rotate(config.DB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "หมุน key สำเร็จ", "kid": key.Kid, "created_at": key.CreatedAt})
+}
+
+// GET /charging-tokens/revoked?since=RFC3339 — denylist ที่ยังมีผล (ตู้ sync ไปเก็บไว้ตรวจ offline)
+func ListRevoked(c *gin.Context) {
+	query := config.DB().Where("expires_at > ?", time.Now())
+	if v := c.Query("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since ต้องเป็นเวลาแบบ RFC3339"})
+			return
+		}
+		query = query.Where("created_at > ?", since)
+	}
+
+	var entries []entity.RevokedToken
+	if err := query.Order("id").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	data := make([]gin.H, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, gin.H{"jti": e.Jti, "exp": e.ExpiresAt.Unix(), "revoked_at": e.CreatedAt})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "generated_at": time.Now()})
+}
@@ -0,0 +1,348 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/middlewares"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ✅ ประเภทรายการของกระเป๋า Coin
+const (
+	KindOpening    = "opening"
+	KindTopUp      = "topup"
+	KindCharge     = "charge"
+	KindRefund     = "refund"
+	KindAdjustment = "adjustment"
+	KindPromotion  = "promotion" // Coin โบนัสจากคูปอง
+)
+
+// ✅ บัญชีคู่ของแต่ละประเภท (ฝั่งตรงข้ามกับ wallet ของผู้ใช้)
+const (
+	AccountWallet     = "wallet"
+	AccountCash       = "cash"
+	AccountRevenue    = "revenue"
+	AccountRefund     = "refund"
+	AccountAdjustment = "adjustment"
+	AccountOpening    = "opening"
+	AccountPromotion  = "promotion"
+)
+
+var counterAccounts = map[string]string{
+	KindOpening:    AccountOpening,
+	KindTopUp:      AccountCash,
+	KindCharge:     AccountRevenue,
+	KindRefund:     AccountRefund,
+	KindAdjustment: AccountAdjustment,
+	KindPromotion:  AccountPromotion,
+}
+
+var (
+	ErrInsufficientCoin = errors.New("coin ไม่เพียงพอ")
+	ErrInvalidAmount    = errors.New("จำนวน coin ไม่ถูกต้อง")
+	ErrUnknownKind      = errors.New("ประเภทรายการไม่ถูกต้อง")
+)
+
+// Posting คือคำขอบันทึกรายการ Amount > 0 = เพิ่ม coin, < 0 = หัก coin
+type Posting struct {
+	UserID        uint
+	Kind          string
+	Amount        float64
+	Note          string
+	PaymentID     *uint
+	PaymentCoinID *uint
+	CreatedByID   *uint
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Post บันทึกรายการบัญชีพร้อมอัปเดต User.Coin ภายใน transaction เดียวกัน
+// ต้องส่ง tx ที่อยู่ใน db.Transaction มาเสมอ เพื่อให้ยอดกับรายการเปลี่ยนพร้อมกัน
+func Post(tx *gorm.DB, p Posting) (*entity.WalletTransaction, error) {
+	counter, ok := counterAccounts[p.Kind]
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	amount := round2(p.Amount)
+	if amount == 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, ErrInvalidAmount
+	}
+
+	var user entity.User
+	if err := tx.First(&user, p.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	balance := round2(user.Coin + amount)
+	if balance < 0 {
+		return nil, ErrInsufficientCoin
+	}
+
+	// 🔸 อัปเดตยอดแบบมีเงื่อนไข กันการเขียนทับจาก request ที่มาพร้อมกัน
+	res := tx.Model(&entity.User{}).
+		Where("id = ? AND coin = ?", user.ID, user.Coin).
+		UpdateColumn("coin", balance)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, fmt.Errorf("coin ของผู้ใช้ถูกแก้ไขระหว่างทำรายการ กรุณาลองใหม่")
+	}
+
+	userID := user.ID
+	walletLine := entity.WalletEntry{Account: AccountWallet, UserID: &userID, BalanceAfter: balance}
+	counterLine := entity.WalletEntry{Account: counter}
+	if amount > 0 {
+		// เพิ่ม coin: Debit บัญชีคู่ / Credit wallet (หนี้สินต่อผู้ใช้เพิ่ม)
+		walletLine.Credit = amount
+		counterLine.Debit = amount
+	} else {
+		walletLine.Debit = -amount
+		counterLine.Credit = -amount
+	}
+
+	wt := entity.WalletTransaction{
+		UserID:        user.ID,
+		Kind:          p.Kind,
+		Amount:        amount,
+		Note:          p.Note,
+		PaymentID:     p.PaymentID,
+		PaymentCoinID: p.PaymentCoinID,
+		CreatedByID:   p.CreatedByID,
+		Entries:       []entity.WalletEntry{walletLine, counterLine},
+	}
+	if err := tx.Create(&wt).Error; err != nil {
+		return nil, err
+	}
+	return &wt, nil
+}
+
+// EnsureOpeningBalances สร้างรายการยอดยกมาให้ผู้ใช้ที่มี Coin อยู่ก่อนมีระบบ ledger
+func EnsureOpeningBalances() {
+	db := config.DB()
+
+	var users []entity.User
+	db.Where("coin <> 0 AND id NOT IN (?)",
+		db.Model(&entity.WalletTransaction{}).Select("user_id")).
+		Find(&users)
+
+	for _, u := range users {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// ตั้งยอดเป็น 0 ก่อน แล้วบันทึกยอดยกมาผ่าน Post ให้ตรงกับ ledger
+			if err := tx.Model(&entity.User{}).Where("id = ?", u.ID).UpdateColumn("coin", 0).Error; err != nil {
+				return err
+			}
+			_, err := Post(tx, Posting{UserID: u.ID, Kind: KindOpening, Amount: u.Coin, Note: "ยอดยกมาก่อนใช้ ledger"})
+			return err
+		})
+		if err != nil {
+			fmt.Println("❌ Cannot create opening balance for user", u.ID, err)
+		}
+	}
+}
+
+// ErrorStatus แปลง error จาก Post เป็น HTTP status
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInsufficientCoin), errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrUnknownKind):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ✅ ผู้ทำรายการจาก JwtAuth (ถ้ามี)
+func actorID(c *gin.Context) *uint {
+	if v, ok := c.Get("UserID"); ok {
+		if id, ok := v.(int); ok && id > 0 {
+			uid := uint(id)
+			return &uid
+		}
+	}
+	return nil
+}
+
+// POST /wallet/adjust — admin ปรับยอด coin (amount ติดลบ = หัก)
+// route อยู่หลัง JwtAuth + RequireRoles(Admin) — ผู้ทำรายการมาจาก token เท่านั้น
+func AdjustWallet(c *gin.Context) {
+	createdBy := actorID(c)
+	if createdBy == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+		return
+	}
+	var input struct {
+		UserID uint    `json:"user_id" binding:"required"`
+		Amount float64 `json:"amount" binding:"required"`
+		Note   string  `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var wt *entity.WalletTransaction
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		wt, err = Post(tx, Posting{
+			UserID:      input.UserID,
+			Kind:        KindAdjustment,
+			Amount:      input.Amount,
+			Note:        input.Note,
+			CreatedByID: createdBy,
+		})
+		return err
+	})
+	if err != nil {
+		c.JSON(ErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "ปรับยอด Coin สำเร็จ", "data": wt})
+}
+
+// GET /wallet/:user_id/statement?from=&to=
+// route อยู่หลัง JwtAuth — ดูได้เฉพาะเจ้าของ wallet หรือ admin
+func GetStatement(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	if actor := actorID(c); actor == nil || (*actor != uint(userID) && !middlewares.HasRole(c, middlewares.RoleAdmin)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		return
+	}
+
+	db := config.DB()
+	var user entity.User
+	if err := db.First(&user, uint(userID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	query := db.Preload("Entries").Where("user_id = ?", user.ID)
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from ต้องเป็น YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to ต้องเป็น YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	var txs []entity.WalletTransaction
+	if err := query.Order("id").Find(&txs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var credit, debit float64
+	for _, t := range txs {
+		if t.Amount > 0 {
+			credit += t.Amount
+		} else {
+			debit -= t.Amount
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":      user.ID,
+		"balance":      user.Coin,
+		"total_credit": round2(credit),
+		"total_debit":  round2(debit),
+		"data":         txs,
+	})
+}
+
+// ReconcileResult คือผลตรวจยอดของผู้ใช้หนึ่งคน
+type ReconcileResult struct {
+	UserID     uint    `json:"user_id"`
+	Balance    float64 `json:"balance"`
+	LedgerSum  float64 `json:"ledger_sum"`
+	Difference float64 `json:"difference"`
+}
+
+// Reconcile เทียบ User.Coin กับผลรวม Credit - Debit ของบัญชี wallet
+func Reconcile(userID *uint) ([]ReconcileResult, int64, error) {
+	db := config.DB()
+
+	var rows []ReconcileResult
+	query := db.Table("users").
+		Select("users.id AS user_id, users.coin AS balance, "+
+			"COALESCE(SUM(wallet_entries.credit - wallet_entries.debit), 0) AS ledger_sum").
+		Joins("LEFT JOIN wallet_entries ON wallet_entries.user_id = users.id AND wallet_entries.account = ? AND wallet_entries.deleted_at IS NULL", AccountWallet).
+		Where("users.deleted_at IS NULL").
+		Group("users.id, users.coin")
+	if userID != nil {
+		query = query.Where("users.id = ?", *userID)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	mismatches := make([]ReconcileResult, 0)
+	for _, r := range rows {
+		r.Difference = round2(r.Balance - r.LedgerSum)
+		r.LedgerSum = round2(r.LedgerSum)
+		if r.Difference != 0 {
+			mismatches = append(mismatches, r)
+		}
+	}
+
+	// 🔸 ทุก WalletTransaction ต้องมี Debit = Credit
+	var unbalanced int64
+	db.Table("wallet_entries").
+		Select("wallet_transaction_id").
+		Where("deleted_at IS NULL").
+		Group("wallet_transaction_id").
+		Having("ROUND(SUM(debit) - SUM(credit), 2) <> 0").
+		Count(&unbalanced)
+
+	return mismatches, unbalanced, nil
+}
+
+// GET /wallet/reconcile?user_id=
+func ReconcileWallets(c *gin.Context) {
+	var userID *uint
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+
+	mismatches, unbalanced, err := Reconcile(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":                      len(mismatches) == 0 && unbalanced == 0,
+		"mismatches":              mismatches,
+		"unbalanced_transactions": unbalanced,
+	})
+}
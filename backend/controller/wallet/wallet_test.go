@@ -0,0 +1,197 @@
+package wallet
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Tawunchai/work-project/config/configtest"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/middlewares"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func seedUser(t *testing.T, db *gorm.DB, name, role string) entity.User {
+	t.Helper()
+	r := entity.UserRoles{RoleName: role}
+	db.Where(r).FirstOrCreate(&r)
+	u := entity.User{Username: name, UserRoleID: r.ID}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func post(t *testing.T, db *gorm.DB, p Posting) (*entity.WalletTransaction, error) {
+	t.Helper()
+	var wt *entity.WalletTransaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		wt, err = Post(tx, p)
+		return err
+	})
+	return wt, err
+}
+
+func coin(t *testing.T, db *gorm.DB, id uint) float64 {
+	t.Helper()
+	var u entity.User
+	if err := db.First(&u, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.Coin
+}
+
+func TestPostDoubleEntry(t *testing.T) {
+	db := configtest.Open(t)
+	user := seedUser(t, db, "alice", middlewares.RoleUser)
+
+	tests := []struct {
+		kind    string
+		amount  float64
+		counter string
+		balance float64
+	}{
+		{KindTopUp, 150.25, AccountCash, 150.25},
+		{KindCharge, -40.1, AccountRevenue, 110.15},
+		{KindPromotion, 10.004, AccountPromotion, 120.15}, // ปัดเป็น 10.00
+		{KindRefund, 40.1, AccountRefund, 160.25},
+	}
+	for _, tt := range tests {
+		wt, err := post(t, db, Posting{UserID: user.ID, Kind: tt.kind, Amount: tt.amount})
+		if err != nil {
+			t.Fatalf("%s %.3f: %v", tt.kind, tt.amount, err)
+		}
+		if got := coin(t, db, user.ID); got != tt.balance {
+			t.Errorf("%s: User.Coin %.2f, want %.2f", tt.kind, got, tt.balance)
+		}
+
+		var entries []entity.WalletEntry
+		db.Where("wallet_transaction_id = ?", wt.ID).Order("id").Find(&entries)
+		if len(entries) != 2 {
+			t.Fatalf("%s: %d entries, want 2", tt.kind, len(entries))
+		}
+		w, c := entries[0], entries[1]
+		amount := math.Abs(wt.Amount)
+		if w.Account != AccountWallet || w.UserID == nil || *w.UserID != user.ID || w.BalanceAfter != tt.balance {
+			t.Errorf("%s: wallet line %+v", tt.kind, w)
+		}
+		if c.Account != tt.counter || c.UserID != nil {
+			t.Errorf("%s: counter line account %q, want %q", tt.kind, c.Account, tt.counter)
+		}
+		// เพิ่ม coin = Credit wallet / Debit บัญชีคู่, หัก coin กลับกัน
+		if wt.Amount > 0 && (w.Credit != amount || c.Debit != amount || w.Debit != 0 || c.Credit != 0) ||
+			wt.Amount < 0 && (w.Debit != amount || c.Credit != amount || w.Credit != 0 || c.Debit != 0) {
+			t.Errorf("%s: wallet %.2f/%.2f counter %.2f/%.2f for amount %.2f", tt.kind, w.Debit, w.Credit, c.Debit, c.Credit, wt.Amount)
+		}
+	}
+
+	mismatches, unbalanced, err := Reconcile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 || unbalanced != 0 {
+		t.Errorf("Reconcile = %+v, %d unbalanced; want clean ledger", mismatches, unbalanced)
+	}
+}
+
+func TestPostRejected(t *testing.T) {
+	db := configtest.Open(t)
+	user := seedUser(t, db, "bob", middlewares.RoleUser)
+	if _, err := post(t, db, Posting{UserID: user.ID, Kind: KindTopUp, Amount: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		p    Posting
+		want error
+	}{
+		{"overdraw", Posting{UserID: user.ID, Kind: KindCharge, Amount: -50.01}, ErrInsufficientCoin},
+		{"zero", Posting{UserID: user.ID, Kind: KindTopUp, Amount: 0.001}, ErrInvalidAmount},
+		{"nan", Posting{UserID: user.ID, Kind: KindTopUp, Amount: math.NaN()}, ErrInvalidAmount},
+		{"unknown kind", Posting{UserID: user.ID, Kind: "gift", Amount: 10}, ErrUnknownKind},
+		{"unknown user", Posting{UserID: user.ID + 99, Kind: KindTopUp, Amount: 10}, gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := post(t, db, tt.p); !errors.Is(err, tt.want) {
+				t.Fatalf("err %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// ยอดและ ledger ต้องไม่เปลี่ยน
+	if got := coin(t, db, user.ID); got != 50 {
+		t.Errorf("User.Coin %.2f after rejected postings, want 50", got)
+	}
+	var n int64
+	db.Model(&entity.WalletTransaction{}).Count(&n)
+	if n != 1 {
+		t.Errorf("%d wallet transactions, want 1", n)
+	}
+}
+
+func TestLedgerImmutable(t *testing.T) {
+	db := configtest.Open(t)
+	user := seedUser(t, db, "carol", middlewares.RoleUser)
+	wt, err := post(t, db, Posting{UserID: user.ID, Kind: KindTopUp, Amount: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Model(&wt.Entries[0]).Update("credit", 2000).Error; !errors.Is(err, entity.ErrLedgerImmutable) {
+		t.Errorf("update entry: err %v, want ErrLedgerImmutable", err)
+	}
+	if err := db.Delete(wt).Error; !errors.Is(err, entity.ErrLedgerImmutable) {
+		t.Errorf("delete transaction: err %v, want ErrLedgerImmutable", err)
+	}
+
+	// 🔸 แก้ User.Coin ตรง ๆ โดยไม่ผ่าน ledger → Reconcile ต้องเจอ
+	db.Model(&entity.User{}).Where("id = ?", user.ID).UpdateColumn("coin", 25)
+	mismatches, _, err := Reconcile(&user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Difference != 5 || mismatches[0].LedgerSum != 20 {
+		t.Errorf("Reconcile = %+v, want one mismatch of 5 coin", mismatches)
+	}
+}
+
+func TestGetStatementAccess(t *testing.T) {
+	db := configtest.Open(t)
+	owner := seedUser(t, db, "owner", middlewares.RoleUser)
+	other := seedUser(t, db, "other", middlewares.RoleUser)
+	admin := seedUser(t, db, "admin", middlewares.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		actor  int
+		status int
+	}{
+		{"owner", int(owner.ID), http.StatusOK},
+		{"admin", int(admin.ID), http.StatusOK},
+		{"other user", int(other.ID), http.StatusForbidden},
+		{"no actor", 0, http.StatusForbidden},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/wallet/statement", nil)
+			c.Params = gin.Params{{Key: "user_id", Value: strconv.Itoa(int(owner.ID))}}
+			if tt.actor > 0 {
+				c.Set("UserID", tt.actor)
+			}
+			GetStatement(c)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
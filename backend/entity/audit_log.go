@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditImmutable ป้องกันการแก้/ลบ audit log ที่บันทึกแล้ว
+var ErrAuditImmutable = errors.New("audit log entries are immutable")
+
+// AuditLog คือบันทึกการกระทำที่มีผลทางการเงิน (เช่น การคืนเงิน) เพื่อตรวจสอบย้อนหลัง
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"index"`
+	Action     string    `gorm:"index"` // เช่น refund.requested, refund.completed
+	EntityType string    `gorm:"index:idx_audit_entity"`
+	EntityID   uint      `gorm:"index:idx_audit_entity"`
+	ActorID    *uint     `gorm:"index"` // ผู้ทำรายการ (ถ้ารู้)
+	Detail     string    // JSON
+}
+
+func (AuditLog) BeforeUpdate(*gorm.DB) error { return ErrAuditImmutable }
+func (AuditLog) BeforeDelete(*gorm.DB) error { return ErrAuditImmutable }
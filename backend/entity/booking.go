@@ -17,4 +17,15 @@ type Booking struct {
 	EVCabinet   EVCabinet  `gorm:"foreignKey:EVCabinetID"`
 
 	IsEmailSent bool `gorm:"default:false"` // ✅ ป้องกันส่งซ้ำ
-}
\ No newline at end of file
+
+	// 🔹 ผลการจอง (job ตรวจหลังเลยเวลาสิ้นสุด)
+	Status    string `gorm:"index;default:scheduled"`
+	CheckedAt *time.Time
+}
+
+// ✅ สถานะของ Booking
+const (
+	BookingScheduled = "scheduled" // ยังไม่ถึงเวลา / ยังไม่ได้ตรวจ
+	BookingFulfilled = "fulfilled" // มีการชาร์จในช่วงเวลาที่จอง
+	BookingMissed    = "missed"    // เลยเวลาแล้วแต่ไม่มีการชาร์จ
+)
\ No newline at end of file
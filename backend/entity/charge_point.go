@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChargePoint คือเครื่องชาร์จจริงที่เชื่อมต่อผ่าน OCPP (/ocpp/:chargerID)
+type ChargePoint struct {
+	gorm.Model
+	ChargerID string `gorm:"uniqueIndex"`
+
+	// ✅ ข้อมูลจาก BootNotification
+	Vendor          string
+	ChargerModel    string
+	SerialNumber    string
+	FirmwareVersion string
+	MeterType       string
+	LastBootAt      *time.Time
+
+	Online     bool
+	LastSeenAt *time.Time
+
+	EVCabinetID *uint
+	EVCabinet   *EVCabinet `gorm:"foreignKey:EVCabinetID"`
+
+	Connectors []Connector `gorm:"foreignKey:ChargePointID"`
+}
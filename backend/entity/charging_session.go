@@ -2,14 +2,78 @@ package entity
 
 import "time"
 
+// ✅ สถานะของ ChargingSession
+const (
+	SessionCreated    = "created"    // ออก token แล้ว ยังไม่ได้เสียบหัวชาร์จ
+	SessionAuthorized = "authorized" // charger ยืนยัน token แล้ว (Authorize / RemoteStart) รอเริ่มจ่ายไฟ
+	SessionCharging   = "charging"   // StartTransaction สำเร็จ กำลังจ่ายไฟ
+	SessionSuspended  = "suspended"  // หยุดจ่ายไฟชั่วคราว (SuspendedEV / SuspendedEVSE)
+	SessionCompleted  = "completed"  // StopTransaction แล้ว
+	SessionExpired    = "expired"    // เลย ExpiresAt ก่อนเริ่มชาร์จ
+	SessionCancelled  = "cancelled"  // ยกเลิกผ่าน API (เช่น ผู้ใช้กดหยุด / คืนเงิน)
+)
+
+// SessionActiveStates คือสถานะที่ยังไม่จบ (เดิมคือ Status = true)
+var SessionActiveStates = []string{SessionCreated, SessionAuthorized, SessionCharging, SessionSuspended}
+
+// SessionStartableStates คือสถานะที่ใช้ token เริ่มชาร์จได้ (ต้องยังไม่หมดอายุ)
+var SessionStartableStates = []string{SessionCreated, SessionAuthorized}
+
+var sessionTransitions = map[string][]string{
+	SessionCreated:    {SessionAuthorized, SessionCharging, SessionExpired, SessionCancelled},
+	SessionAuthorized: {SessionCharging, SessionExpired, SessionCancelled},
+	SessionCharging:   {SessionSuspended, SessionCompleted, SessionCancelled},
+	SessionSuspended:  {SessionCharging, SessionCompleted, SessionCancelled},
+}
+
+// CanTransitionSession บอกว่าเปลี่ยนสถานะ session จาก from ไป to ได้หรือไม่
+func CanTransitionSession(from, to string) bool {
+	for _, next := range sessionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSessionActive บอกว่าสถานะนี้ยังไม่จบ
+func IsSessionActive(status string) bool {
+	for _, s := range SessionActiveStates {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 type ChargingSession struct {
 	ID        uint   `gorm:"primaryKey"`
 	UserID    uint   `gorm:"not null"`
 	Token     string `gorm:"uniqueIndex"`
 	ExpiresAt time.Time
 	CreatedAt time.Time
-	Status bool
+
+	// 🔹 สถานะ (ดู entity.Session*) พร้อมเวลาและเหตุผลของการเปลี่ยนล่าสุด
+	Status          string `gorm:"index"`
+	StatusReason    string
+	StatusChangedAt *time.Time
+	AuthorizedAt    *time.Time
+	StartedAt       *time.Time
+	EndedAt         *time.Time // completed / expired / cancelled
 
 	PaymentID uint
 	Payment   Payment `gorm:"foreignKey:PaymentID"`
+
+	Events []ChargingSessionEvent `gorm:"foreignKey:ChargingSessionID"`
+}
+
+// ChargingSessionEvent คือประวัติการเปลี่ยนสถานะของ session
+type ChargingSessionEvent struct {
+	ID                uint `gorm:"primaryKey"`
+	ChargingSessionID uint `gorm:"index"`
+	FromStatus        string
+	ToStatus          string
+	Reason            string
+	Source            string // ocpp, api, system
+	CreatedAt         time.Time
 }
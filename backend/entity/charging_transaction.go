@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChargingTransaction เก็บธุรกรรม OCPP 1.6 (StartTransaction → StopTransaction)
+type ChargingTransaction struct {
+	gorm.Model
+	TransactionID int    `gorm:"uniqueIndex"` // transactionId ที่ส่งกลับให้ charger
+	ChargerID     string `gorm:"index"`
+	ConnectorID   int
+	IdTag         string `gorm:"index"`
+
+	MeterStart int  // Wh
+	MeterStop  *int // Wh (nil = ยังชาร์จอยู่)
+
+	StartedAt  time.Time
+	StoppedAt  *time.Time
+	StopReason string
+
+	ChargingSessionID *uint
+	ChargingSession   *ChargingSession `gorm:"foreignKey:ChargingSessionID"`
+
+	PaymentID *uint
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Connector คือหัวชาร์จของ ChargePoint (connectorId ตาม OCPP, 0 = ทั้งเครื่อง)
+type Connector struct {
+	gorm.Model
+	ChargePointID uint         `gorm:"uniqueIndex:idx_charge_point_connector"`
+	ChargePoint   *ChargePoint `gorm:"foreignKey:ChargePointID"`
+	ConnectorNo   int          `gorm:"uniqueIndex:idx_charge_point_connector"`
+
+	Status          string // Available, Preparing, Charging, SuspendedEV, ...
+	ErrorCode       string
+	Info            string
+	StatusUpdatedAt *time.Time
+
+	// ✅ หัวชาร์จนี้ตรงกับ EVcharging ตัวไหน (ใช้ราคา/ประเภทจาก EVcharging)
+	EVchargingID *uint
+	EVcharging   *EVcharging `gorm:"foreignKey:EVchargingID"`
+
+	StatusHistories []ConnectorStatusHistory `gorm:"foreignKey:ConnectorID"`
+}
+
+// ConnectorStatusHistory เก็บทุกการเปลี่ยนสถานะจาก StatusNotification
+type ConnectorStatusHistory struct {
+	gorm.Model
+	ConnectorID uint       `gorm:"index"`
+	Connector   *Connector `gorm:"foreignKey:ConnectorID"`
+
+	FromStatus string
+	ToStatus   string
+	ErrorCode  string
+	Info       string
+	Valid      bool // false = transition ไม่อยู่ในตาราง OCPP 1.6 (ยังบันทึกตามที่ hardware แจ้ง)
+	ReportedAt time.Time
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ✅ ใช้คูปองกับอะไร
+const (
+	CouponForCharging = "charging" // ส่วนลดค่าชาร์จ (CreatePayment)
+	CouponForTopUp    = "topup"    // Coin โบนัสตอนเติมเงิน (CreatePaymentCoin)
+)
+
+// ✅ วิธีคิดมูลค่า
+const (
+	CouponPercent = "percent" // Value = เปอร์เซ็นต์ของยอด (จำกัดด้วย MaxDiscount ได้)
+	CouponFixed   = "fixed"   // Value = บาท / Coin
+)
+
+// ✅ สถานะการใช้คูปอง
+const (
+	RedemptionPending = "pending" // รายการรอ admin ตรวจ (จองสิทธิ์ไว้แล้ว)
+	RedemptionApplied = "applied"
+	RedemptionVoid    = "void" // รายการไม่ผ่าน — คืนสิทธิ์การใช้
+)
+
+// Coupon คือโค้ดโปรโมชัน ใช้ได้กับการชำระค่าชาร์จหรือการเติม Coin อย่างใดอย่างหนึ่ง
+type Coupon struct {
+	gorm.Model
+	Code        string `gorm:"uniqueIndex"` // ตัวพิมพ์ใหญ่เสมอ
+	Description string
+
+	AppliesTo   string // charging, topup
+	Type        string // percent, fixed
+	Value       float64
+	MaxDiscount float64 // เพดานส่วนลด/โบนัสของแบบ percent (0 = ไม่จำกัด)
+	MinSpend    float64
+
+	StartsAt *time.Time
+	EndsAt   *time.Time
+
+	MaxUses        int // จำนวนครั้งทั้งหมด (0 = ไม่จำกัด)
+	MaxUsesPerUser int // ต่อผู้ใช้ (0 = ไม่จำกัด)
+	Active         bool
+
+	// ว่าง = ใช้ได้ทุกตู้ (คูปองเติม Coin ต้องไม่จำกัดตู้)
+	EVCabinets []EVCabinet `gorm:"many2many:coupon_ev_cabinets;"`
+
+	Redemptions []CouponRedemption `gorm:"foreignKey:CouponID"`
+}
+
+// CouponRedemption คือการใช้คูปองหนึ่งครั้ง (ใช้นับสิทธิ์และทำรายงาน)
+type CouponRedemption struct {
+	gorm.Model
+	CouponID uint    `gorm:"index"`
+	Coupon   *Coupon `gorm:"foreignKey:CouponID"`
+	Code     string
+
+	UserID uint  `gorm:"index"`
+	User   *User `gorm:"foreignKey:UserID"`
+
+	PaymentID     *uint `gorm:"index"`
+	PaymentCoinID *uint `gorm:"index"`
+	EVCabinetID   *uint
+
+	Amount    float64 // ยอดก่อนส่วนลด / ยอดเติม
+	Discount  float64
+	BonusCoin float64
+	Status    string `gorm:"index"` // pending, applied, void
+}
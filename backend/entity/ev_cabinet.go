@@ -23,6 +23,8 @@ type EVCabinet struct {
 	
 	Payment     []Payment    `gorm:"foreignKey:EVCabinetID"` 
 
+	ChargePoints []ChargePoint `gorm:"foreignKey:EVCabinetID"`
+
 	EmployeeID  *uint       
 	Employee    Employee     `gorm:"foreignKey:EmployeeID"`
 }
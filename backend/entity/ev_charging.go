@@ -24,4 +24,6 @@ type EVcharging struct {
     Cabinets []EVCabinet `gorm:"many2many:ev_cabinet_ev_chargings;"`
 
 	EVChargingPayments []EVChargingPayment `gorm:"foreignKey:EVchargingID"`
+
+	Connectors []Connector `gorm:"foreignKey:EVchargingID"`
 }
@@ -5,7 +5,7 @@ import "gorm.io/gorm"
 type EVChargingPayment struct {
 	gorm.Model
 
-	EVchargingID uint
+	EVchargingID uint `gorm:"uniqueIndex:idx_evcharging_payment_billed"`
 	EVcharging   EVcharging `gorm:"foreignKey:EVchargingID"`
 
 	PaymentID uint
@@ -14,4 +14,14 @@ type EVChargingPayment struct {
 	Price float64
 	Percent float64
 	Power float64
+
+	// ✅ ค่าที่คิดจากมิเตอร์จริงตอนจบ transaction (nil = ยอดที่จองไว้ตอนชำระเงิน)
+	// unique คู่กับ EVchargingID — คิดเงินได้ครั้งเดียวต่อ transaction (ยอดจองมีค่า NULL จึงไม่ชนกัน)
+	ChargingTransactionID *uint `gorm:"index;uniqueIndex:idx_evcharging_payment_billed"`
+	ChargingTransaction   *ChargingTransaction `gorm:"foreignKey:ChargingTransactionID"`
+	UnitPrice float64 // บาท/kWh ที่ใช้คิด
+
+	TariffID  *uint
+	Tariff    *Tariff `gorm:"foreignKey:TariffID"`
+	Breakdown string  // JSON รายละเอียดการคิดเงินตาม Tariff
 }
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey เก็บผลลัพธ์ของ request ที่ส่ง Idempotency-Key มา เพื่อตอบซ้ำเมื่อ client retry
+type IdempotencyKey struct {
+	gorm.Model
+	Key         string `gorm:"uniqueIndex:idx_idempotency_scope_key"`
+	Scope       string `gorm:"uniqueIndex:idx_idempotency_scope_key"` // METHOD + path ของ route + เจ้าของ (user:<id> / anonymous)
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Response    []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
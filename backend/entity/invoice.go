@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ✅ ประเภทเอกสาร
+const (
+	InvoiceReceipt    = "receipt"     // ใบเสร็จรับเงิน
+	InvoiceTaxInvoice = "tax-invoice" // ใบกำกับภาษีเต็มรูป/ใบเสร็จรับเงิน
+)
+
+// Invoice คือใบเสร็จ/ใบกำกับภาษีที่ออกให้ Payment (ข้อมูลผู้ขาย/ผู้ซื้อเก็บไว้ตามที่พิมพ์ในเอกสาร)
+type Invoice struct {
+	gorm.Model
+	Kind     string `gorm:"uniqueIndex:idx_invoice_payment_kind"`
+	Number   string `gorm:"uniqueIndex"`
+	IssuedAt time.Time
+
+	PaymentID uint     `gorm:"uniqueIndex:idx_invoice_payment_kind"`
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+
+	Subtotal float64 // มูลค่าก่อนภาษี
+	VATRate  float64
+	VAT      float64
+	Total    float64 // รวมภาษีแล้ว
+
+	SellerName    string
+	SellerTaxID   string
+	SellerBranch  string
+	SellerAddress string
+
+	BuyerName    string
+	BuyerTaxID   string
+	BuyerBranch  string
+	BuyerAddress string
+
+	Lines string // JSON รายการในเอกสาร (snapshot ตอนออก ใช้สร้าง PDF ซ้ำได้)
+	File  string // uploads/invoices/<number>.pdf
+}
+
+// DocumentSequence คือเลขล่าสุดของแต่ละชุดเอกสาร (เช่น INV-2026) — เลขต้องเรียงต่อเนื่องไม่ข้าม
+type DocumentSequence struct {
+	Series string `gorm:"primaryKey"`
+	Last   uint
+}
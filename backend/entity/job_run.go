@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// ✅ สถานะของ JobRun
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobRun คือประวัติการทำงานของ background job แต่ละครั้ง
+type JobRun struct {
+	ID          uint   `gorm:"primaryKey"`
+	Job         string `gorm:"index"`
+	Trigger     string // schedule, manual
+	TriggeredBy *uint  // ผู้สั่งรันเอง (manual)
+	Status      string `gorm:"index"`
+
+	StartedAt  time.Time
+	EndedAt    *time.Time
+	DurationMs int64
+	Items      int // จำนวนรายการที่ job จัดการ
+	Error      string
+}
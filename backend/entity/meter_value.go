@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MeterValue คือ sampledValue หนึ่งค่าจาก MeterValues / StopTransaction
+type MeterValue struct {
+	gorm.Model
+	ChargerID   string `gorm:"index:idx_meter_charger_time"`
+	ConnectorID int
+	Timestamp   time.Time `gorm:"index:idx_meter_charger_time;index:idx_meter_tx_time"`
+
+	Measurand string // Energy.Active.Import.Register, Power.Active.Import, SoC, Voltage, ...
+	Phase     string
+	Unit      string
+	Context   string // Sample.Periodic, Transaction.Begin, Transaction.End, ...
+	Location  string
+	Value     float64
+	RawValue  string
+
+	ChargingTransactionID *uint                `gorm:"index:idx_meter_tx_time"`
+	ChargingTransaction   *ChargingTransaction `gorm:"foreignKey:ChargingTransactionID"`
+}
@@ -12,6 +12,18 @@ type Payment struct {
 	Amount float64
 	ReferenceNumber string
 	Picture string
+
+	// 🔹 สถานะการตรวจสลิป (ดู entity.Payment* ใน payment_status.go)
+	Status       string `gorm:"index"`
+	StatusReason string
+	ReviewerID   *uint
+	Reviewer     *User `gorm:"foreignKey:ReviewerID"`
+	VerifiedAt   *time.Time
+	ReviewedAt   *time.Time
+
+	// 🔹 คูปอง (Amount คือยอดที่ชำระจริงหลังหักส่วนลด)
+	CouponCode string
+	Discount   float64
 	
 	UserID 		*uint
 	User   		*User `gorm:"foreignKey:UserID"`
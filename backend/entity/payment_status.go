@@ -0,0 +1,31 @@
+package entity
+
+// ✅ สถานะของ Payment / PaymentCoin
+const (
+	PaymentPending      = "pending"       // รับเรื่องแล้ว ยังไม่ได้ตรวจสลิป
+	PaymentAutoVerified = "auto-verified" // ตรวจสลิปผ่านอัตโนมัติ รอยืนยัน
+	PaymentManualReview = "manual-review" // ตรวจอัตโนมัติไม่ได้ ต้องให้ admin ตรวจ
+	PaymentApproved     = "approved"      // อนุมัติแล้ว (เติม coin / ออก token ได้)
+	PaymentRejected     = "rejected"
+	PaymentRefunded     = "refunded"
+)
+
+// ReviewQueueStatuses คือสถานะที่ยังรอการตัดสินจาก admin
+var ReviewQueueStatuses = []string{PaymentPending, PaymentAutoVerified, PaymentManualReview}
+
+var paymentTransitions = map[string][]string{
+	PaymentPending:      {PaymentAutoVerified, PaymentManualReview, PaymentApproved, PaymentRejected},
+	PaymentAutoVerified: {PaymentManualReview, PaymentApproved, PaymentRejected},
+	PaymentManualReview: {PaymentApproved, PaymentRejected},
+	PaymentApproved:     {PaymentRefunded},
+}
+
+// CanTransitionPayment บอกว่าเปลี่ยนสถานะจาก from ไป to ได้หรือไม่
+func CanTransitionPayment(from, to string) bool {
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -12,6 +12,18 @@ type PaymentCoin struct {
 	Amount float64
 	ReferenceNumber string
 	Picture string
+
+	// 🔹 สถานะการตรวจสลิป (ดู entity.Payment* ใน payment_status.go)
+	Status       string `gorm:"index"`
+	StatusReason string
+	ReviewerID   *uint
+	Reviewer     *User `gorm:"foreignKey:ReviewerID"`
+	VerifiedAt   *time.Time
+	ReviewedAt   *time.Time
+
+	// 🔹 คูปอง (Coin โบนัสเติมแยกจาก Amount)
+	CouponCode string
+	BonusCoin  float64
 	
 	UserID 		uint
 	User   		*User `gorm:"foreignKey:UserID"`
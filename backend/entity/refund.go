@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ✅ วิธีคืนเงิน
+const (
+	RefundToWallet     = "wallet"        // คืนเป็น Coin เข้ากระเป๋า
+	RefundBankTransfer = "bank_transfer" // โอนคืนผ่านธนาคารโดย admin
+)
+
+// ✅ สถานะคำขอคืนเงิน
+const (
+	RefundRequested = "requested"
+	RefundCompleted = "completed"
+	RefundRejected  = "rejected"
+)
+
+// Refund คือการคืนเงิน (เต็มจำนวนหรือบางส่วน) ของ Payment หรือ PaymentCoin
+type Refund struct {
+	gorm.Model
+	Amount float64
+	Reason string
+	Method string `gorm:"index"` // wallet, bank_transfer
+	Status string `gorm:"index"` // requested, completed, rejected
+
+	PaymentID *uint    `gorm:"index"`
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+
+	PaymentCoinID *uint        `gorm:"index"`
+	PaymentCoin   *PaymentCoin `gorm:"foreignKey:PaymentCoinID"`
+
+	UserID *uint `gorm:"index"` // เจ้าของรายการที่ได้รับเงินคืน
+	User   *User `gorm:"foreignKey:UserID"`
+
+	RequestedByID *uint
+	ApprovedByID  *uint
+	ApprovedBy    *User `gorm:"foreignKey:ApprovedByID"`
+	DecidedAt     *time.Time
+	DecisionNote  string
+
+	BankReference       string // เลขอ้างอิงการโอนคืน (bank_transfer)
+	WalletTransactionID *uint  // รายการใน ledger (ถ้ามี)
+}
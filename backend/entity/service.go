@@ -9,6 +9,11 @@ type Service struct {
 	Phone     string 
 	Location  string 
 	MapURL    string 
+
+	// 🔹 ข้อมูลผู้ขายสำหรับใบเสร็จ/ใบกำกับภาษี
+	CompanyName string
+	TaxID       string
+	Branch      string // เช่น "สำนักงานใหญ่" หรือ "สาขาที่ 00001"
 	
 	EmployeeID *uint 
 	Employee   Employee  `gorm:"foreignKey:EmployeeID"`
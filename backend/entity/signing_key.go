@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// ✅ สถานะของ SigningKey
+const (
+	SigningKeyActive  = "active"  // ใช้ลงชื่อ token ใหม่ (มีได้ครั้งละ 1 key)
+	SigningKeyRetired = "retired" // เลิกลงชื่อแล้ว แต่ยังเผยแพร่ไว้ตรวจ token เก่าจนถึง PublishUntil
+)
+
+// SigningKey คือ key ของ token การชาร์จแบบลงชื่อ (Ed25519)
+type SigningKey struct {
+	ID           uint   `gorm:"primaryKey"`
+	Kid          string `gorm:"uniqueIndex"`
+	Algorithm    string
+	PublicKey    []byte
+	PrivateKey   []byte `json:"-"`
+	Status       string `gorm:"index"`
+	CreatedAt    time.Time
+	RetiredAt    *time.Time
+	PublishUntil *time.Time
+}
+
+// RevokedToken คือ denylist ของ token แบบลงชื่อที่ยังไม่หมดอายุแต่ใช้ไม่ได้แล้ว
+type RevokedToken struct {
+	ID                uint   `gorm:"primaryKey"`
+	Jti               string `gorm:"uniqueIndex"`
+	ChargingSessionID uint   `gorm:"index"`
+	Reason            string
+	ExpiresAt         time.Time // เลยเวลานี้ token หมดอายุเอง ลบออกจาก denylist ได้
+	RevokedBy         *uint
+	CreatedAt         time.Time
+}
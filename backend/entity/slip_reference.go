@@ -0,0 +1,16 @@
+package entity
+
+import "gorm.io/gorm"
+
+// SlipReference คือทะเบียนเลขอ้างอิงสลิป (transRef) ที่ถูกใช้แล้ว ใช้ร่วมกันทั้ง Payment และ PaymentCoin
+// ไม่ลบออกแม้รายการชำระเงินจะถูกลบ เพื่อไม่ให้สลิปเดิมถูกนำมาใช้ซ้ำ
+type SlipReference struct {
+	gorm.Model
+	Reference string `gorm:"uniqueIndex"`
+
+	PaymentID *uint    `gorm:"index"`
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+
+	PaymentCoinID *uint        `gorm:"index"`
+	PaymentCoin   *PaymentCoin `gorm:"foreignKey:PaymentCoinID"`
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tariff คืออัตราค่าชาร์จ (แทน EVcharging.Price แบบราคาเดียว)
+// ผูกได้ทั้งตู้ (EVCabinetID) และ/หรือประเภทหัวชาร์จ (TypeID) — ว่างทั้งคู่ = ใช้ทุกที่
+type Tariff struct {
+	gorm.Model
+	Name        string
+	Description string
+
+	PerKWh     float64 // บาท/kWh เมื่อไม่เข้าช่วงเวลาใดใน Bands
+	PerMinute  float64 // บาท/นาที เมื่อไม่เข้าช่วงเวลาใดใน Bands
+	SessionFee float64 // ค่าบริการต่อครั้ง
+
+	ValidFrom *time.Time
+	ValidTo   *time.Time
+	Active    bool // ค่าเริ่มต้น true ตั้งใน CreateTariff (gorm default จะทิ้งค่า false ตอน Create)
+	Priority  int  // มากกว่า = เลือกก่อน เมื่อ scope เท่ากัน
+
+	EVCabinetID *uint
+	EVCabinet   *EVCabinet `gorm:"foreignKey:EVCabinetID"`
+
+	TypeID *uint
+	Type   *Type `gorm:"foreignKey:TypeID"`
+
+	Bands []TariffBand `gorm:"foreignKey:TariffID"`
+}
+
+// TariffBand คือช่วงเวลาแบบ TOU เช่น On-Peak จันทร์–ศุกร์ 09:00–22:00
+type TariffBand struct {
+	gorm.Model
+	TariffID uint
+
+	Label       string
+	Weekdays    string // "1,2,3,4,5" (0 = อาทิตย์ ตาม time.Weekday), ว่าง = ทุกวัน
+	StartMinute int    // นาทีของวัน เช่น 540 = 09:00
+	EndMinute   int    // ไม่รวม, น้อยกว่า StartMinute = ข้ามเที่ยงคืน
+
+	PerKWh    float64
+	PerMinute float64
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrLedgerImmutable ป้องกันการแก้/ลบรายการบัญชีที่บันทึกแล้ว
+var ErrLedgerImmutable = errors.New("wallet ledger entries are immutable")
+
+// WalletTransaction คือหนึ่งรายการบัญชีของกระเป๋า Coin (ผลรวม Debit = Credit เสมอ)
+type WalletTransaction struct {
+	gorm.Model
+	UserID uint   `gorm:"index"`
+	Kind   string // opening, topup, charge, refund, adjustment
+	Amount float64
+	Note   string
+
+	PaymentCoinID *uint
+	PaymentCoin   *PaymentCoin `gorm:"foreignKey:PaymentCoinID"`
+
+	PaymentID *uint
+	Payment   *Payment `gorm:"foreignKey:PaymentID"`
+
+	CreatedByID *uint // ผู้ทำรายการ (admin) ถ้ามี
+
+	Entries []WalletEntry `gorm:"foreignKey:WalletTransactionID"`
+}
+
+// WalletEntry คือบรรทัด Debit/Credit ของบัญชีหนึ่งใน WalletTransaction
+type WalletEntry struct {
+	gorm.Model
+	WalletTransactionID uint   `gorm:"index"`
+	Account             string `gorm:"index"` // wallet, cash, revenue, refund, adjustment
+	UserID              *uint  `gorm:"index"` // มีค่าเฉพาะบัญชี wallet ของผู้ใช้
+	Debit               float64
+	Credit              float64
+	BalanceAfter        float64 // ยอดคงเหลือของ wallet หลังรายการนี้
+}
+
+func (WalletTransaction) BeforeUpdate(*gorm.DB) error { return ErrLedgerImmutable }
+func (WalletTransaction) BeforeDelete(*gorm.DB) error { return ErrLedgerImmutable }
+func (WalletEntry) BeforeUpdate(*gorm.DB) error       { return ErrLedgerImmutable }
+func (WalletEntry) BeforeDelete(*gorm.DB) error       { return ErrLedgerImmutable }
@@ -4,19 +4,23 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/robfig/cron/v3"
-
 	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/controller/audit"
+	"github.com/Tawunchai/work-project/controller/billing"
 	"github.com/Tawunchai/work-project/controller/booking"
 	"github.com/Tawunchai/work-project/controller/brand"
 	"github.com/Tawunchai/work-project/controller/cabinet"
 	"github.com/Tawunchai/work-project/controller/calendar"
 	"github.com/Tawunchai/work-project/controller/car"
+	"github.com/Tawunchai/work-project/controller/chargepoint"
 	"github.com/Tawunchai/work-project/controller/charging"
+	"github.com/Tawunchai/work-project/controller/coupon"
 	"github.com/Tawunchai/work-project/controller/employee"
 	"github.com/Tawunchai/work-project/controller/gender"
 	"github.com/Tawunchai/work-project/controller/getstarted"
 	"github.com/Tawunchai/work-project/controller/inverter"
+	"github.com/Tawunchai/work-project/controller/invoice"
+	"github.com/Tawunchai/work-project/controller/job"
 	"github.com/Tawunchai/work-project/controller/like"
 	"github.com/Tawunchai/work-project/controller/login"
 	"github.com/Tawunchai/work-project/controller/method"
@@ -26,20 +30,29 @@ import (
 	"github.com/Tawunchai/work-project/controller/ocpp"
 	"github.com/Tawunchai/work-project/controller/otp"
 	"github.com/Tawunchai/work-project/controller/payment"
+	"github.com/Tawunchai/work-project/controller/progress"
+	"github.com/Tawunchai/work-project/controller/promptpay"
+	"github.com/Tawunchai/work-project/controller/qrstart"
 	"github.com/Tawunchai/work-project/controller/report"
+	"github.com/Tawunchai/work-project/controller/revenue"
 	"github.com/Tawunchai/work-project/controller/review"
 	"github.com/Tawunchai/work-project/controller/role"
 	hardware "github.com/Tawunchai/work-project/controller/senddata"
 	"github.com/Tawunchai/work-project/controller/sendemail"
 	"github.com/Tawunchai/work-project/controller/service"
+	"github.com/Tawunchai/work-project/controller/session"
 	"github.com/Tawunchai/work-project/controller/slip"
 	"github.com/Tawunchai/work-project/controller/solar"
 	"github.com/Tawunchai/work-project/controller/status"
+	"github.com/Tawunchai/work-project/controller/tariff"
+	"github.com/Tawunchai/work-project/controller/tokensign"
 	tokening "github.com/Tawunchai/work-project/controller/token"
 	types "github.com/Tawunchai/work-project/controller/type"
 	"github.com/Tawunchai/work-project/controller/user"
+	"github.com/Tawunchai/work-project/controller/wallet"
 	"github.com/Tawunchai/work-project/middlewares"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 const PORT = "8000"
@@ -50,6 +63,17 @@ func main() {
 
 	config.SetupDatabase()
 
+	ocpp.ResetChargePointsOnline()
+
+	wallet.EnsureOpeningBalances()
+
+	payment.EnsurePaymentStatuses()
+	session.EnsureSessionStatuses()
+
+	middlewares.PurgeExpiredIdempotencyKeys()
+
+	slip.EnsureReferenceRegistry()
+
 	r := gin.Default()
 
 	r.Use(CORSMiddleware())
@@ -58,14 +82,59 @@ func main() {
 	r.GET("/me", login.GetProfile)
 	r.POST("/logout", login.Logout)
 
-	// ✅ 2. เพิ่ม Cron Job หลัง DB setup และก่อนรันเซิร์ฟเวอร์
-	c := cron.New()
-	c.AddFunc("0 7 * * *", func() {
-		log.Println("🕖 เริ่มทำงานส่งอีเมลแจ้งเตือน Booking วันนี้...")
-		notify.SendBookingReminder(nil)
+	// ✅ 2. Background jobs (ดูประวัติ / สั่งรันเองได้ที่ /jobs)
+	job.Register(job.Job{
+		Name:        "booking-reminder",
+		Description: "ส่งอีเมลแจ้งเตือน Booking ของวันนี้",
+		Schedule:    "0 7 * * *",
+		Run:         notify.SendTodayReminders,
+	})
+	job.Register(job.Job{
+		Name:        "expire-sessions",
+		Description: "เปลี่ยน ChargingSession ที่เลย ExpiresAt เป็น expired",
+		Schedule:    "@every 1m",
+		Run:         session.ExpireDue,
+	})
+	job.Register(job.Job{
+		Name:        "purge-otps",
+		Description: "ลบ OTP ที่หมดอายุแล้ว",
+		Schedule:    "@every 1h",
+		Run:         otp.PurgeExpiredOTPs,
+	})
+	job.Register(job.Job{
+		Name:        "mark-missed-bookings",
+		Description: "ตรวจ Booking ที่เลยเวลาแล้ว ไม่มีการชาร์จ = missed",
+		Schedule:    "@every 15m",
+		Run:         booking.MarkMissedBookings,
+	})
+	job.Register(job.Job{
+		Name:        "purge-idempotency-keys",
+		Description: "ลบ Idempotency-Key ที่หมดอายุแล้ว",
+		Schedule:    "@every 1h",
+		Run: func(*gorm.DB) (int, error) {
+			return int(middlewares.PurgeExpiredIdempotencyKeys()), nil
+		},
+	})
+	job.Register(job.Job{
+		Name:        "rotate-signing-keys",
+		Description: "หมุน key ลงชื่อ token การชาร์จเมื่อครบอายุ",
+		Schedule:    "0 3 * * *",
+		Run:         tokensign.RotateIfDue,
+	})
+	job.Register(job.Job{
+		Name:        "purge-revoked-tokens",
+		Description: "ลบ token ที่หมดอายุแล้วออกจาก denylist",
+		Schedule:    "@every 1h",
+		Run:         tokensign.PurgeRevoked,
+	})
+	job.Register(job.Job{
+		Name:        "purge-job-runs",
+		Description: "ลบประวัติการรัน job ที่เก่ากว่า 14 วัน",
+		Schedule:    "30 3 * * *",
+		Run:         job.PurgeRuns,
 	})
-	c.Start()
-	log.Println("✅ Scheduler started (runs every day at 07:00 AM).")
+	job.Start()
+	log.Println("✅ Scheduler started (booking reminder runs every day at 07:00 AM).")
 
 	authorized := r.Group("")
 	authorized.Use(middlewares.Authorizes())
@@ -73,12 +142,57 @@ func main() {
 
 	}
 
+	// 🔹 งานของผู้ดูแลระบบ — ต้องมี JWT และเป็น Admin (ผู้ทำรายการมาจาก token เสมอ)
+	admin := r.Group("")
+	admin.Use(middlewares.JwtAuth(), middlewares.RequireRoles(middlewares.RoleAdmin))
+	{
+		admin.POST("/wallet/adjust", wallet.AdjustWallet)
+		admin.GET("/payment-reviews", payment.ListPaymentReviews)
+		admin.POST("/payments/:id/approve", payment.ApprovePayment)
+		admin.POST("/payments/:id/reject", payment.RejectPayment)
+		admin.POST("/payment-coins/:id/approve", payment.ApprovePaymentCoin)
+		admin.POST("/payment-coins/:id/reject", payment.RejectPaymentCoin)
+		admin.GET("/refunds", payment.ListRefunds)
+		admin.POST("/payments/:id/refunds", payment.CreatePaymentRefund)
+		admin.POST("/payment-coins/:id/refunds", payment.CreatePaymentCoinRefund)
+		admin.POST("/refunds/:id/approve", payment.ApproveRefund)
+		admin.POST("/refunds/:id/reject", payment.RejectRefund)
+		admin.POST("/charging-tokens/revoke", tokening.RevokeChargingToken)
+		admin.POST("/charging-tokens/keys/rotate", tokensign.RotateKeys)
+		admin.POST("/coupons", coupon.CreateCoupon)
+		admin.PATCH("/coupons/:id", coupon.UpdateCoupon)
+		admin.DELETE("/coupons/:id", coupon.DeleteCoupon)
+		admin.GET("/coupon-redemptions", coupon.ListRedemptions)
+		admin.POST("/billing/transactions/:id", billing.BillTransactionByID)
+		admin.POST("/create-tariff", tariff.CreateTariff)
+		admin.PUT("/tariffs/:id", tariff.UpdateTariffByID)
+		admin.DELETE("/tariffs/:id", tariff.DeleteTariffByID)
+		admin.GET("/wallet/reconcile", wallet.ReconcileWallets)
+		admin.GET("/audit-logs", audit.ListAuditLogs)
+		admin.GET("/reports/revenue", revenue.GetRevenueReport)
+		admin.POST("/jobs/:name/run", job.TriggerJob)
+		admin.PATCH("/charge-points/:id/cabinet", chargepoint.UpdateChargePointCabinet)
+		admin.PATCH("/connectors/:id/evcharging", chargepoint.UpdateConnectorEVcharging)
+	}
+
+	// 🔹 งานของผู้ใช้ที่ login แล้ว — เจ้าของรายการมาจาก JWT (ไม่รับ user_id จาก request)
+	member := r.Group("")
+	member.Use(middlewares.JwtAuth())
+	{
+		// ✅ สร้าง token หลังชำระเงินสำเร็จ
+		member.POST("/token/payment-success", tokening.PaymentSuccess)
+		member.POST("/qr-start/pay-and-start", middlewares.Idempotent(), qrstart.PayAndStart)
+		member.GET("/wallet/:user_id/statement", wallet.GetStatement) // เจ้าของ wallet หรือ admin
+	}
+
 	public := r.Group("")
 	{
 		//SlipOK
 		public.POST("/api/check-slipok", slip.CheckSlipOI)
 		//CheckSlip
 		public.POST("/api/check-slip", slip.CheckSlipThunder)
+		public.POST("/api/verify-slip", slip.VerifySlip)
+		public.POST("/api/decode-slip", slip.DecodeSlipQR)
 		//Iverter
 		public.GET("/inverter", inverter.GetInverterStatus)
 
@@ -102,22 +216,38 @@ func main() {
 		public.GET("employeebyid/:id", employee.ListEmployeeByID)
 		public.POST("/check-email", user.CheckEmailExists)
 		public.POST("/reset-password", user.ResetPassword)
-		public.PUT("/users/update-coin", user.UpdateCoins)
 
 		//payment
 		public.GET("/payments", payment.ListPayment)
 		public.GET("/payments/user/:user_id", payment.ListPaymentByUserID)
 		public.GET("/banks", payment.ListBank)
 		public.PATCH("/banks/:id", payment.UpdateBank)
-		public.POST("/create-payments", payment.CreatePayment)
+		public.POST("/create-payments", middlewares.Idempotent(), payment.CreatePayment)
 		public.POST("/create-evchargingpayments", payment.CreateEVChargingPayment) //Persen
 		public.GET("/evcharging-payments", payment.ListEVChargingPayment)
+
+		//Tariff
+		public.GET("/tariffs", tariff.ListTariffs)
+		public.POST("/tariffs/quote", tariff.QuoteTariff)
 		public.GET("/payment-coins", payment.ListPaymentCoins)
-		public.POST("/create-payment-coins", payment.CreatePaymentCoin)
+		public.POST("/create-payment-coins", middlewares.Idempotent(), payment.CreatePaymentCoin)
 		public.GET("/payment-coins/:user_id", payment.ListPaymentCoinsByUserID)
 		public.DELETE("/payment-coins", payment.DeletePaymentCoins)
 		public.DELETE("/payments", payment.DeletePayment)
 		public.GET("/ref/:ref", payment.GetDataPaymentByRef)
+		public.GET("/payments/:id/invoices", invoice.ListInvoicesByPayment)
+		public.POST("/payments/:id/receipt", invoice.IssueReceipt)
+		public.GET("/payments/:id/receipt", invoice.DownloadReceipt)
+		public.POST("/payments/:id/tax-invoice", invoice.IssueTaxInvoice)
+		public.GET("/payments/:id/tax-invoice", invoice.DownloadTaxInvoice)
+
+		//Coupon
+		public.GET("/coupons", coupon.ListCoupons)
+		public.POST("/coupons/validate", coupon.ValidateCoupon)
+
+		//PromptPay QR
+		public.GET("/promptpay/qr", promptpay.GetPromptPayQR)
+		public.GET("/payments/:id/promptpay-qr", promptpay.GetPaymentPromptPayQR)
 
 		//Send Email
 		public.GET("/send-emails", sendemail.ListSendEmail)
@@ -215,6 +345,10 @@ func main() {
 		//Notify
 		public.GET("/booking/reminder", notify.SendBookingReminder)
 
+		//Background jobs
+		public.GET("/jobs", job.ListJobs)
+		public.GET("/jobs/:name/runs", job.ListJobRuns)
+
 		//brand
 		public.POST("/create-brand", brand.CreateBrand)
 		public.PATCH("/update-brand/:id", brand.UpdateBrandByID)
@@ -225,19 +359,36 @@ func main() {
 		public.PATCH("/update-modal/:id", modal.UpdateModalByID)
 		public.DELETE("/delete-modal/:id", modal.DeleteModalByID)
 
-		// ✅ สร้าง token หลังชำระเงินสำเร็จ
-		public.POST("/token/payment-success", tokening.PaymentSuccess)
 		public.PUT("/charging-session/update-status/:payment_id", tokening.UpdateStatusByPaymentID)
 		public.GET("/charging-session/status/true", tokening.GetChargingSessionByStatus)
 		public.GET("/charging-session/status/:user_id", tokening.GetChargingSessionByStatusAndUserID)
+		public.POST("/charging-session/remote-start", tokening.RemoteStart)
+		public.POST("/charging-session/remote-stop", tokening.RemoteStop)
 
 		// ✅ ตรวจสอบ token
 		public.GET("/token/verify", tokening.VerifyChargingSession)
 		public.GET("/charging-session/:user_id", tokening.GetDataByUserID)
+		public.GET("/charging-sessions/:id/events", session.ListSessionEvents)
+		public.GET("/charging-sessions/:id/progress", progress.GetSessionProgress)
+		public.GET("/charging-sessions/:id/stream", progress.StreamSession) // WebSocket / SSE ของเจ้าของ session (?token=)
+		public.GET("/charging-tokens/keys", tokensign.GetKeys)
+		public.GET("/charging-tokens/revoked", tokensign.ListRevoked)
 
 		//OCPP Test
 		public.GET("/ocpp/:chargerID", ocpp.HandleOCPP)
 		public.GET("/frontend", ocpp.HandleFrontend) // ส่งให้ frontend
+		public.GET("/charge-points/connected", ocpp.ListConnectedChargers)
+		public.GET("/charge-points", chargepoint.ListChargePoints)
+		public.GET("/charge-points/cabinet/:id", chargepoint.ListChargePointsByCabinetID)
+		public.GET("/connectors", chargepoint.ListConnectors)
+		public.GET("/connectors/:id/history", chargepoint.ListConnectorStatusHistory)
+		public.GET("/connectors/:id/qr", qrstart.GetConnectorQR)
+		public.GET("/qr-start/resolve", qrstart.ResolveQR)
+		public.GET("/charging-transactions", ocpp.ListChargingTransactions)
+		public.GET("/charging-transactions/payment/:payment_id", ocpp.ListChargingTransactionsByPaymentID)
+		public.GET("/meter-values/charger/:chargerID", ocpp.ListMeterValuesByCharger)
+		public.GET("/meter-values/session/:session_id", ocpp.ListMeterValuesBySession)
+		public.GET("/meter-values/transaction/:transaction_id", ocpp.ListMeterValuesByTransaction)
 
 		// 🌞 Solar WebSocket Routes
 		public.GET("/solar/:deviceID", solar.HandleSolar)   // สำหรับพี่คุณส่งข้อมูลเข้ามา
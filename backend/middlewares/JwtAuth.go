@@ -35,6 +35,9 @@ func JwtAuth() gin.HandlerFunc {
 
 		// 🟦 ตรวจสอบความถูกต้องของ JWT
 		token, err := jwt.Parse(clientToken, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
 			return []byte(jwtSecret), nil
 		})
 		if err != nil {
@@ -45,7 +48,11 @@ func JwtAuth() gin.HandlerFunc {
 		// 🟦 อ่าน Claims จาก JWT
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 			// ✅ ใช้ Username แทน Email
-			username, ok := claims["Email"].(string) // field เดิมชื่อ "Email" แต่ใน token เก็บ Username ไว้
+			// Login (services.JwtClaim) เก็บไว้ที่ "username" — token รุ่นเก่าใช้ field ชื่อ "Email"
+			username, ok := claims["username"].(string)
+			if !ok {
+				username, ok = claims["Email"].(string)
+			}
 			if !ok {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
 				return
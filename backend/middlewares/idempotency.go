@@ -0,0 +1,213 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/Tawunchai/work-project/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IdempotencyHeader คือ header ที่ client ส่งมาเพื่อกันการสร้างรายการซ้ำเมื่อ retry
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyTTL คืออายุของ key หลังจากนั้นใช้ key เดิมได้ใหม่
+const IdempotencyTTL = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+// ✅ เก็บ response ที่ handler เขียนไว้เพื่อบันทึกลงฐานข้อมูล
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ✅ hash ของ request — multipart ใช้ค่าใน form (boundary ที่สุ่มใหม่ทุกครั้งไม่มีผล)
+func hashRequest(c *gin.Context, body []byte) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+
+	mediaType, params, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data" && params["boundary"] != "":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		parts := []string{}
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return "", err
+			}
+			sum := sha256.Sum256(data)
+			parts = append(parts, part.FormName()+"="+hex.EncodeToString(sum[:]))
+		}
+		sort.Strings(parts)
+		h.Write([]byte(strings.Join(parts, "&")))
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(values.Encode())) // Encode เรียง key ให้แล้ว
+	default:
+		h.Write(body)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ✅ คืนผลลัพธ์เดิมของ key ที่ทำเสร็จแล้ว
+func replayIdempotent(c *gin.Context, record entity.IdempotencyKey) {
+	c.Header("Idempotent-Replayed", "true")
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(record.StatusCode, contentType, record.Response)
+	c.Abort()
+}
+
+// Idempotent ทำให้ route สร้างข้อมูลเพียงครั้งเดียวต่อ Idempotency-Key
+// - key เดิม + body เดิม → ตอบผลลัพธ์เดิม
+// - key เดิม + body ต่างกัน → 409
+// - request แรกยังทำไม่เสร็จ → 409 ให้ retry ภายหลัง
+// ถ้าไม่ส่ง header มาจะทำงานตามปกติ
+func Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key ยาวเกินไป"})
+			return
+		}
+
+		// 🟦 อ่าน body แล้วใส่คืนให้ handler อ่านต่อได้
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "อ่านข้อมูล request ไม่สำเร็จ"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash, err := hashRequest(c, body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "รูปแบบข้อมูล request ไม่ถูกต้อง"})
+			return
+		}
+
+		db := config.DB()
+		scope := c.Request.Method + " " + c.FullPath() + " " + idempotencyOwner(c)
+		now := time.Now()
+
+		// 🟦 จอง key ก่อนทำงาน (unique index กัน request ที่มาพร้อมกัน)
+		record := entity.IdempotencyKey{Key: key, Scope: scope, RequestHash: hash, ExpiresAt: now.Add(IdempotencyTTL)}
+		if err := db.Create(&record).Error; err != nil {
+			var existing entity.IdempotencyKey
+			if err := db.Where("scope = ? AND `key` = ?", scope, key).First(&existing).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "ตรวจสอบ Idempotency-Key ไม่สำเร็จ"})
+				return
+			}
+
+			switch {
+			case existing.ExpiresAt.Before(now):
+				// key หมดอายุแล้ว → ลบทิ้งแล้วจองใหม่
+				db.Unscoped().Delete(&existing)
+				if err := db.Create(&record).Error; err != nil {
+					c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key นี้กำลังถูกใช้งาน"})
+					return
+				}
+			case existing.RequestHash != hash:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key นี้ถูกใช้กับข้อมูลอื่นแล้ว"})
+				return
+			case !existing.Completed:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request นี้กำลังดำเนินการอยู่ กรุณาลองใหม่ภายหลัง"})
+				return
+			default:
+				replayIdempotent(c, existing)
+				return
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// 🟦 ปล่อย key ใน defer — handler panic / ผิดพลาดฝั่ง server ต้อง retry ได้ ไม่ค้างเป็น "กำลังดำเนินการ"
+		completed := false
+		defer func() {
+			if !completed {
+				db.Unscoped().Delete(&record)
+			}
+		}()
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		completed = db.Model(&record).Updates(map[string]interface{}{
+			"completed":    true,
+			"status_code":  status,
+			"content_type": recorder.Header().Get("Content-Type"),
+			"response":     recorder.body.Bytes(),
+		}).Error == nil
+	}
+}
+
+// ✅ เจ้าของ key — ผู้ใช้จาก JwtAuth หรือจาก token ใน Authorization (route สาธารณะ) ไม่มีก็เป็น anonymous
+// ใส่ไว้ใน scope เพื่อไม่ให้ผู้ใช้คนอื่นที่เดา key ได้ได้รับผลลัพธ์ของเรา
+func idempotencyOwner(c *gin.Context) string {
+	if id, ok := c.Get("UserID"); ok {
+		return fmt.Sprintf("user:%v", id)
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if token != "" {
+		jwtWrapper := services.JwtWrapper{SecretKey: jwtSecret, Issuer: "AuthService"}
+		if claims, err := jwtWrapper.ValidateToken(token); err == nil {
+			return fmt.Sprintf("user:%d", claims.UserID)
+		}
+	}
+	return "anonymous"
+}
+
+// PurgeExpiredIdempotencyKeys ลบ key ที่หมดอายุแล้ว
+func PurgeExpiredIdempotencyKeys() int64 {
+	res := config.DB().Unscoped().Where("expires_at < ?", time.Now()).Delete(&entity.IdempotencyKey{})
+	if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		fmt.Println("❌ Cannot purge idempotency keys:", res.Error)
+	}
+	return res.RowsAffected
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/Tawunchai/work-project/config"
+	"github.com/Tawunchai/work-project/entity"
+	"github.com/gin-gonic/gin"
+)
+
+// ✅ ชื่อ role ตาม entity.UserRoles
+const (
+	RoleAdmin    = "Admin"
+	RoleEmployee = "Employee"
+	RoleUser     = "User"
+)
+
+// RequireRoles ใช้ต่อจาก JwtAuth — อนุญาตเฉพาะ user ที่มี role ตามที่กำหนด
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("UserID")
+		id, isInt := v.(int)
+		if !ok || !isInt || id <= 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization"})
+			return
+		}
+
+		if role, ok := roleOf(id, roles); ok {
+			c.Set("RoleName", role)
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+	}
+}
+
+// HasRole บอกว่าผู้ใช้ที่ผ่าน JwtAuth มี role ตามที่กำหนดหรือไม่ (ใช้ใน route ที่เจ้าของหรือ admin เข้าได้)
+func HasRole(c *gin.Context, roles ...string) bool {
+	id, ok := c.Get("UserID")
+	if uid, isInt := id.(int); ok && isInt && uid > 0 {
+		_, ok := roleOf(uid, roles)
+		return ok
+	}
+	return false
+}
+
+func roleOf(userID int, roles []string) (string, bool) {
+	var user entity.User
+	if err := config.DB().Preload("UserRole").First(&user, userID).Error; err != nil || user.UserRole == nil {
+		return "", false
+	}
+	for _, role := range roles {
+		if user.UserRole.RoleName == role {
+			return role, true
+		}
+	}
+	return "", false
+}
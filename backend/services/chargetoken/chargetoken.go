@@ -0,0 +1,135 @@
+// Package chargetoken เข้ารหัส / ตรวจ token การชาร์จแบบลงชื่อ (รูปแบบ JWT, EdDSA / Ed25519)
+// ตู้ชาร์จหรือ kiosk ตรวจ token เองได้ด้วย public key ที่เผยแพร่ โดยไม่ต้องถามฐานข้อมูล
+package chargetoken
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Algorithm คือค่า alg ใน header (RFC 8037)
+const Algorithm = "EdDSA"
+
+var (
+	ErrMalformed    = errors.New("chargetoken: malformed token")
+	ErrUnknownKey   = errors.New("chargetoken: unknown signing key")
+	ErrBadSignature = errors.New("chargetoken: invalid signature")
+	ErrExpired      = errors.New("chargetoken: token expired")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Header ของ token
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Claims ข้อมูลที่ตู้ใช้ตัดสินใจได้เองโดยไม่ต้องต่อ server
+type Claims struct {
+	ID        string  `json:"jti"`
+	SessionID uint    `json:"sid"`
+	Subject   uint    `json:"sub"` // UserID
+	PaymentID uint    `json:"pid"`
+	CabinetID *uint   `json:"cab,omitempty"`
+	MaxKWh    float64 `json:"max_kwh,omitempty"`    // พลังงานสูงสุดที่จ่ายได้
+	MaxAmount float64 `json:"max_amount,omitempty"` // มูลค่าสูงสุด (บาท)
+	IssuedAt  int64   `json:"iat"`
+	ExpiresAt int64   `json:"exp"`
+}
+
+// Expired บอกว่า token เลยเวลา exp แล้ว
+func (c *Claims) Expired(now time.Time) bool {
+	return c.ExpiresAt > 0 && now.Unix() >= c.ExpiresAt
+}
+
+// JWK คือ public key ในรูปแบบ JSON Web Key (OKP / Ed25519)
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// PublicJWK แปลง public key เป็น JWK สำหรับเผยแพร่
+func PublicJWK(kid string, pub ed25519.PublicKey) JWK {
+	return JWK{Kty: "OKP", Crv: "Ed25519", X: b64.EncodeToString(pub), Kid: kid, Use: "sig", Alg: Algorithm}
+}
+
+// IsSigned บอกว่าเป็น token แบบลงชื่อ (header.payload.signature) ไม่ใช่ UUID เดิม
+func IsSigned(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Sign ลงชื่อ claims ด้วย private key ของ kid
+func Sign(key ed25519.PrivateKey, kid string, c Claims) (string, error) {
+	header, err := json.Marshal(Header{Alg: Algorithm, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	return input + "." + b64.EncodeToString(ed25519.Sign(key, []byte(input))), nil
+}
+
+// Parse อ่าน header และ claims โดยไม่ตรวจลายเซ็น — ใช้เมื่อเชื่อถือที่มาของ token อยู่แล้ว
+func Parse(token string) (*Header, *Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, ErrMalformed
+	}
+	var h Header
+	var c Claims
+	if err := decodePart(parts[0], &h); err != nil {
+		return nil, nil, err
+	}
+	if err := decodePart(parts[1], &c); err != nil {
+		return nil, nil, err
+	}
+	if h.Alg != Algorithm || h.Kid == "" {
+		return nil, nil, ErrMalformed
+	}
+	return &h, &c, nil
+}
+
+// Verify ตรวจลายเซ็นด้วย key ของ kid (ได้จาก keys) และวันหมดอายุ
+func Verify(token string, keys func(kid string) (ed25519.PublicKey, bool), now time.Time) (*Claims, error) {
+	h, c, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := keys(h.Kid)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	i := strings.LastIndexByte(token, '.')
+	sig, err := b64.DecodeString(token[i+1:])
+	if err != nil || len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, []byte(token[:i]), sig) {
+		return nil, ErrBadSignature
+	}
+	if c.Expired(now) {
+		return c, ErrExpired
+	}
+	return c, nil
+}
+
+func decodePart(part string, v interface{}) error {
+	raw, err := b64.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
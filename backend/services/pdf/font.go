@@ -0,0 +1,398 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Font คือฟอนต์ที่ใช้เขียนข้อความได้ — Helvetica มาตรฐาน หรือ TrueType ที่ฝังในไฟล์
+type Font interface {
+	// Width คือความกว้างของข้อความ (point) ที่ขนาด size
+	Width(s string, size float64) float64
+	// Supports บอกว่าฟอนต์มีตัวอักษรนี้หรือไม่
+	Supports(r rune) bool
+
+	encode(s string) string
+	write(w *writer) (int, error)
+}
+
+// ============================================================================
+// 🔹 ฟอนต์มาตรฐาน (ไม่ต้องฝัง) — รองรับเฉพาะ Latin (WinAnsi)
+// ============================================================================
+
+type standardFont struct {
+	name   string
+	widths [95]int // ความกว้างของตัวอักษร 32–126 (1/1000 em)
+}
+
+// ✅ ความกว้างจาก AFM ของ Adobe
+var (
+	Helvetica = &standardFont{name: "Helvetica", widths: [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}}
+	HelveticaBold = &standardFont{name: "Helvetica-Bold", widths: [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}}
+)
+
+func (f *standardFont) Supports(r rune) bool {
+	return (r >= 32 && r < 127) || (r >= 160 && r <= 255)
+}
+
+func (f *standardFont) Width(s string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127:
+			total += f.widths[r-32]
+		case f.Supports(r):
+			total += 556
+		default:
+			total += f.widths['?'-32]
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// encode แปลงเป็น WinAnsi (ตัวอักษรที่ไม่รองรับเป็น ?)
+func (f *standardFont) encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if !f.Supports(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%02X", r)
+	}
+	return b.String()
+}
+
+func (f *standardFont) write(w *writer) (int, error) {
+	id := w.newObject()
+	w.object(id, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.name))
+	return id, nil
+}
+
+// ============================================================================
+// 🔹 TrueType (ฝังทั้งไฟล์ เข้ารหัสแบบ Identity-H) — ใช้กับข้อความภาษาไทย
+// ============================================================================
+
+// ErrInvalidFont เมื่อไฟล์ไม่ใช่ TrueType ที่ใช้ได้
+var ErrInvalidFont = errors.New("pdf: invalid or unsupported TrueType font")
+
+// TrueType คือข้อมูลฟอนต์ที่อ่านแล้ว ใช้ซ้ำได้หลายเอกสาร (เรียก Font() ต่อเอกสาร)
+type TrueType struct {
+	data       []byte
+	name       string
+	unitsPerEm float64
+	bbox       [4]int
+	ascent     int
+	descent    int
+	advances   []uint16
+	cmap       map[rune]uint16
+}
+
+// ParseTrueType อ่านไฟล์ .ttf
+func ParseTrueType(data []byte) (*TrueType, error) {
+	if len(data) < 12 {
+		return nil, ErrInvalidFont
+	}
+	if v := binary.BigEndian.Uint32(data); v != 0x00010000 && v != 0x74727565 {
+		return nil, ErrInvalidFont // OpenType CFF (OTTO) / collection ไม่รองรับ
+	}
+
+	tables := map[string][]byte{}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, ErrInvalidFont
+		}
+		tag := string(data[rec : rec+4])
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, ErrInvalidFont
+		}
+		tables[tag] = data[off : off+length]
+	}
+
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || cmap == nil {
+		return nil, ErrInvalidFont
+	}
+	t := &TrueType{data: data, name: "EmbeddedFont"}
+	t.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if t.unitsPerEm == 0 {
+		return nil, ErrInvalidFont
+	}
+	for i := range t.bbox {
+		t.bbox[i] = t.scale(int(int16(binary.BigEndian.Uint16(head[36+2*i:]))))
+	}
+	t.ascent = t.scale(int(int16(binary.BigEndian.Uint16(hhea[4:]))))
+	t.descent = t.scale(int(int16(binary.BigEndian.Uint16(hhea[6:]))))
+
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numMetrics == 0 || len(hmtx) < numMetrics*4 {
+		return nil, ErrInvalidFont
+	}
+	t.advances = make([]uint16, numMetrics)
+	for i := range t.advances {
+		t.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+	}
+
+	var err error
+	if t.cmap, err = parseCmap(cmap); err != nil {
+		return nil, err
+	}
+	if name := postScriptName(tables["name"]); name != "" {
+		t.name = name
+	}
+	return t, nil
+}
+
+func (t *TrueType) scale(v int) int {
+	return int(float64(v) * 1000 / t.unitsPerEm)
+}
+
+// parseCmap อ่าน Unicode cmap (format 12 ก่อน ถ้าไม่มีใช้ format 4)
+func parseCmap(table []byte) (map[rune]uint16, error) {
+	if len(table) < 4 {
+		return nil, ErrInvalidFont
+	}
+	var format4, format12 []byte
+	n := int(binary.BigEndian.Uint16(table[2:]))
+	for i := 0; i < n; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[rec:])
+		encoding := binary.BigEndian.Uint16(table[rec+2:])
+		off := int(binary.BigEndian.Uint32(table[rec+4:]))
+		if off+4 > len(table) {
+			continue
+		}
+		sub := table[off:]
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(sub) {
+		case 4:
+			format4 = sub
+		case 12:
+			format12 = sub
+		}
+	}
+
+	m := map[rune]uint16{}
+	switch {
+	case format12 != nil && len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for i := 0; i < groups && 16+i*12+12 <= len(format12); i++ {
+			g := format12[16+i*12:]
+			start := rune(binary.BigEndian.Uint32(g))
+			end := rune(binary.BigEndian.Uint32(g[4:]))
+			gid := binary.BigEndian.Uint32(g[8:])
+			for r := start; r <= end && r <= 0x10FFFF; r++ {
+				m[r] = uint16(gid + uint32(r-start))
+			}
+		}
+	case format4 != nil && len(format4) >= 14:
+		segX2 := int(binary.BigEndian.Uint16(format4[6:]))
+		ends, starts := 14, 16+segX2
+		deltas, ranges := starts+segX2, starts+2*segX2
+		if ranges+segX2 > len(format4) {
+			return nil, ErrInvalidFont
+		}
+		for i := 0; i < segX2; i += 2 {
+			end := int(binary.BigEndian.Uint16(format4[ends+i:]))
+			start := int(binary.BigEndian.Uint16(format4[starts+i:]))
+			delta := int(binary.BigEndian.Uint16(format4[deltas+i:]))
+			rangeOff := int(binary.BigEndian.Uint16(format4[ranges+i:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				var gid int
+				if rangeOff == 0 {
+					gid = (c + delta) & 0xFFFF
+				} else {
+					at := ranges + i + rangeOff + 2*(c-start)
+					if at+2 > len(format4) {
+						continue
+					}
+					gid = int(binary.BigEndian.Uint16(format4[at:]))
+					if gid != 0 {
+						gid = (gid + delta) & 0xFFFF
+					}
+				}
+				if gid != 0 {
+					m[rune(c)] = uint16(gid)
+				}
+			}
+		}
+	default:
+		return nil, ErrInvalidFont
+	}
+	return m, nil
+}
+
+// postScriptName อ่านชื่อ PostScript (nameID 6) ถ้ามี
+func postScriptName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		rec := 6 + i*12
+		if rec+12 > len(table) {
+			break
+		}
+		if binary.BigEndian.Uint16(table[rec+6:]) != 6 {
+			continue
+		}
+		platform := binary.BigEndian.Uint16(table[rec:])
+		length := int(binary.BigEndian.Uint16(table[rec+8:]))
+		off := storage + int(binary.BigEndian.Uint16(table[rec+10:]))
+		if off+length > len(table) {
+			continue
+		}
+		raw := table[off : off+length]
+		var b strings.Builder
+		step := 1
+		if platform == 0 || platform == 3 {
+			step = 2 // UTF-16BE
+		}
+		for j := step - 1; j < len(raw); j += step {
+			if c := raw[j]; c > 32 && c < 127 && c != '/' && c != '(' && c != ')' && c != '[' && c != ']' {
+				b.WriteByte(c)
+			}
+		}
+		if b.Len() > 0 {
+			return b.String()
+		}
+	}
+	return ""
+}
+
+// Font สร้างฟอนต์สำหรับเอกสารหนึ่ง (จดจำ glyph ที่ใช้เพื่อเขียนความกว้างและ ToUnicode)
+func (t *TrueType) Font() Font {
+	return &trueTypeFont{face: t, used: map[uint16]rune{}}
+}
+
+type trueTypeFont struct {
+	face *TrueType
+	used map[uint16]rune
+}
+
+func (f *trueTypeFont) Supports(r rune) bool {
+	_, ok := f.face.cmap[r]
+	return ok
+}
+
+func (f *trueTypeFont) glyph(r rune) uint16 {
+	if gid, ok := f.face.cmap[r]; ok {
+		return gid
+	}
+	return f.face.cmap['?']
+}
+
+func (f *trueTypeFont) advance(gid uint16) int {
+	adv := f.face.advances
+	if int(gid) < len(adv) {
+		return f.face.scale(int(adv[gid]))
+	}
+	return f.face.scale(int(adv[len(adv)-1]))
+}
+
+func (f *trueTypeFont) Width(s string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		total += f.advance(f.glyph(r))
+	}
+	return float64(total) * size / 1000
+}
+
+func (f *trueTypeFont) encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		gid := f.glyph(r)
+		if _, ok := f.used[gid]; !ok && gid != 0 {
+			f.used[gid] = r
+		}
+		fmt.Fprintf(&b, "%04X", gid)
+	}
+	return b.String()
+}
+
+func (f *trueTypeFont) write(w *writer) (int, error) {
+	t := f.face
+	fontID := w.newObject()
+	cidID := w.newObject()
+	descID := w.newObject()
+	fileID := w.newObject()
+	unicodeID := w.newObject()
+
+	gids := make([]int, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.advance(uint16(gid)))
+	}
+
+	w.object(fontID, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		t.name, cidID, unicodeID))
+	w.object(cidID, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW %d /W [%s] /CIDToGIDMap /Identity >>",
+		t.name, descID, f.advance(0), widths.String()))
+	w.object(descID, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		t.name, t.bbox[0], t.bbox[1], t.bbox[2], t.bbox[3], t.ascent, t.descent, t.ascent, fileID))
+	if err := w.stream(fileID, fmt.Sprintf(" /Length1 %d", len(t.data)), t.data); err != nil {
+		return 0, err
+	}
+	if err := w.stream(unicodeID, "", toUnicode(gids, f.used)); err != nil {
+		return 0, err
+	}
+	return fontID, nil
+}
+
+// toUnicode สร้าง CMap ให้ค้นหา/คัดลอกข้อความจาก PDF ได้
+func toUnicode(gids []int, used map[uint16]rune) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(gids); start += 100 {
+		end := min(start+100, len(gids))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			r := used[uint16(gid)]
+			if r > 0xFFFF {
+				r -= 0x10000
+				fmt.Fprintf(&b, "<%04X> <%04X%04X>\n", gid, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			} else {
+				fmt.Fprintf(&b, "<%04X> <%04X>\n", gid, r)
+			}
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
@@ -0,0 +1,242 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// ขนาดกระดาษ A4 (หน่วย point)
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Document คือเอกสาร PDF อย่างง่าย (ข้อความ เส้น กรอบ) สำหรับใบเสร็จ/ใบกำกับภาษี
+type Document struct {
+	pages []*Page
+	fonts []Font
+	Title string
+}
+
+// Page คือหน้าหนึ่งของเอกสาร — พิกัดวัดจากมุมซ้ายบน (y ลงล่าง) แล้วแปลงตอนเขียน
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// New สร้างเอกสารเปล่า
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage เพิ่มหน้า A4
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+func (d *Document) fontIndex(f Font) int {
+	for i, existing := range d.fonts {
+		if existing == f {
+			return i
+		}
+	}
+	d.fonts = append(d.fonts, f)
+	return len(d.fonts) - 1
+}
+
+func num(v float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// Text เขียนข้อความโดยให้ baseline อยู่ที่ (x, y)
+func (p *Page) Text(f Font, size, x, y float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td <%s> Tj ET\n",
+		p.doc.fontIndex(f), num(size), num(x), num(A4Height-y), f.encode(s))
+}
+
+// TextRight เขียนข้อความชิดขวาที่ตำแหน่ง x
+func (p *Page) TextRight(f Font, size, x, y float64, s string) {
+	p.Text(f, size, x-f.Width(s, size), y, s)
+}
+
+// TextCenter เขียนข้อความกึ่งกลางที่ตำแหน่ง x
+func (p *Page) TextCenter(f Font, size, x, y float64, s string) {
+	p.Text(f, size, x-f.Width(s, size)/2, y, s)
+}
+
+// Line ลากเส้นตรง
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(A4Height-y1), num(x2), num(A4Height-y2))
+}
+
+// Rect วาดกรอบ (gray < 0 = ไม่เติมสี, 0 = ดำ … 1 = ขาว)
+func (p *Page) Rect(x, y, w, h, width, gray float64) {
+	op := "S"
+	if gray >= 0 {
+		fmt.Fprintf(&p.content, "%s g ", num(gray))
+		op = "B"
+	}
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re %s 0 g\n",
+		num(width), num(x), num(A4Height-y-h), num(w), num(h), op)
+}
+
+// WrapText ตัดข้อความเป็นบรรทัดไม่เกิน maxWidth (ตัดที่ช่องว่างก่อน ถ้าคำยาวเกินตัดตามตัวอักษร)
+func WrapText(f Font, size, maxWidth float64, s string) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if f.Width(candidate, size) <= maxWidth {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && f.Width(line+string(r), size) > maxWidth {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ============================================================================
+// 🔹 เขียนไฟล์
+// ============================================================================
+
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// newObject จองหมายเลข object (เริ่มที่ 1)
+func (w *writer) newObject() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *writer) begin(id int) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n", id)
+}
+
+func (w *writer) object(id int, body string) {
+	w.begin(id)
+	w.buf.WriteString(body)
+	w.buf.WriteString("\nendobj\n")
+}
+
+// stream เขียน stream แบบบีบอัด (FlateDecode) พร้อม entry เพิ่มเติมใน dictionary
+func (w *writer) stream(id int, extra string, data []byte) error {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.begin(id)
+	fmt.Fprintf(&w.buf, "<< /Length %d /Filter /FlateDecode%s >>\nstream\n", z.Len(), extra)
+	w.buf.Write(z.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return nil
+}
+
+// textString แปลงข้อความเป็น PDF string (ASCII = literal, อื่น ๆ = UTF-16BE พร้อม BOM)
+func textString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 32 || r >= 127 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		r := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+		return "(" + r.Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// Bytes สร้างไฟล์ PDF
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalogID := w.newObject()
+	pagesID := w.newObject()
+	infoID := w.newObject()
+
+	// 🔹 ฟอนต์ (ทุกหน้าใช้ resource ชุดเดียวกัน)
+	var fontRefs strings.Builder
+	for i, f := range d.fonts {
+		id, err := f.write(w)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&fontRefs, "/F%d %d 0 R ", i, id)
+	}
+
+	// 🔹 หน้า
+	kids := make([]string, 0, len(d.pages))
+	for _, p := range d.pages {
+		pageID := w.newObject()
+		contentID := w.newObject()
+		if err := w.stream(contentID, "", p.content.Bytes()); err != nil {
+			return nil, err
+		}
+		w.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pagesID, num(A4Width), num(A4Height), fontRefs.String(), contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+
+	w.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	w.object(infoID, fmt.Sprintf("<< /Producer (work-project) /Title %s >>", textString(d.Title)))
+
+	// 🔹 xref + trailer
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalogID, infoID, xref)
+	return w.buf.Bytes(), nil
+}
@@ -0,0 +1,238 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parsed คือ object ของไฟล์ PDF ที่อ่านผ่าน xref (body = dictionary, stream = ข้อมูลหลังถอด Flate)
+type parsed struct {
+	objects map[int]string
+	streams map[int][]byte
+	trailer string
+}
+
+var (
+	startxrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	lengthRe    = regexp.MustCompile(`/Length (\d+)`)
+	refRe       = regexp.MustCompile(`/(\w+) (\d+) 0 R`)
+)
+
+// parsePDF อ่านไฟล์ตาม startxref → ตาราง xref → object แต่ละตัว แล้วตรวจว่า offset / Length ถูกต้อง
+func parsePDF(t *testing.T, data []byte) parsed {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.")) {
+		t.Fatalf("missing PDF header: %q", data[:min(len(data), 16)])
+	}
+	m := startxrefRe.FindSubmatch(data)
+	if m == nil {
+		t.Fatal("missing startxref / EOF marker")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if xref <= 0 || xref >= len(data) || !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point to an xref table", xref)
+	}
+
+	lines := strings.Split(string(data[xref:]), "\n")
+	var count int
+	if _, err := fmt.Sscanf(lines[1], "0 %d", &count); err != nil {
+		t.Fatalf("xref subsection %q: %v", lines[1], err)
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Fatalf("xref entry 0 = %q", lines[2])
+	}
+
+	p := parsed{objects: map[int]string{}, streams: map[int][]byte{}}
+	for id := 1; id < count; id++ {
+		entry := lines[2+id]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q", id, entry)
+		}
+		off, _ := strconv.Atoi(entry[:10])
+		header := fmt.Sprintf("%d 0 obj\n", id)
+		if !bytes.HasPrefix(data[off:], []byte(header)) {
+			t.Fatalf("object %d: offset %d points to %q", id, off, data[off:min(len(data), off+16)])
+		}
+		body := data[off+len(header):]
+		end := bytes.Index(body, []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d: missing endobj", id)
+		}
+		body = body[:end]
+
+		// 🔸 stream ต้องยาวตาม /Length พอดี และถอด FlateDecode ได้
+		if i := bytes.Index(body, []byte(">>\nstream\n")); i >= 0 {
+			lm := lengthRe.FindSubmatch(body[:i])
+			if lm == nil {
+				t.Fatalf("object %d: stream without /Length", id)
+			}
+			n, _ := strconv.Atoi(string(lm[1]))
+			raw := body[i+len(">>\nstream\n"):]
+			if len(raw) != n+len("\nendstream") || !bytes.HasSuffix(raw, []byte("\nendstream")) {
+				t.Fatalf("object %d: /Length %d does not match stream data (%d bytes)", id, n, len(raw)-len("\nendstream"))
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(raw[:n]))
+			if err != nil {
+				t.Fatalf("object %d: %v", id, err)
+			}
+			decoded, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("object %d: %v", id, err)
+			}
+			p.streams[id] = decoded
+			body = body[:i+2]
+		}
+		p.objects[id] = string(body)
+	}
+
+	rest := strings.Join(lines[2+count:], "\n")
+	if !strings.HasPrefix(rest, "trailer\n<< ") {
+		t.Fatalf("missing trailer after xref: %q", rest[:min(len(rest), 20)])
+	}
+	p.trailer = rest
+	if !strings.Contains(p.trailer, fmt.Sprintf("/Size %d ", count)) {
+		t.Errorf("trailer %q: /Size should be %d", p.trailer, count)
+	}
+	return p
+}
+
+// ref คืนหมายเลข object ที่ key อ้างถึงใน dictionary
+func ref(t *testing.T, dict, key string) int {
+	t.Helper()
+	for _, m := range refRe.FindAllStringSubmatch(dict, -1) {
+		if m[1] == key {
+			id, _ := strconv.Atoi(m[2])
+			return id
+		}
+	}
+	t.Fatalf("%q has no /%s reference", dict, key)
+	return 0
+}
+
+func TestDocumentParses(t *testing.T) {
+	doc := New()
+	doc.Title = "ใบกำกับภาษี INV-0001"
+	p1 := doc.AddPage()
+	p1.Text(HelveticaBold, 18, 40, 60, "Receipt (copy)")
+	p1.TextRight(Helvetica, 10, 555, 60, `Total: 1,234.50 \ THB`)
+	p1.Line(40, 70, 555, 70, 0.5)
+	p1.Rect(40, 80, 200, 40, 1, 0.9)
+	doc.AddPage().TextCenter(Helvetica, 12, A4Width/2, 100, "Page 2")
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parsePDF(t, data)
+
+	root := p.objects[ref(t, p.trailer, "Root")]
+	if !strings.Contains(root, "/Type /Catalog") {
+		t.Fatalf("Root %q is not a catalog", root)
+	}
+	pages := p.objects[ref(t, root, "Pages")]
+	if !strings.Contains(pages, "/Type /Pages") || !strings.Contains(pages, "/Count 2") {
+		t.Fatalf("Pages %q, want 2 pages", pages)
+	}
+	info := p.objects[ref(t, p.trailer, "Info")]
+	if !strings.Contains(info, "/Title <FEFF0E43") {
+		t.Errorf("Info %q: Thai title should be UTF-16BE with BOM", info)
+	}
+
+	var content strings.Builder
+	for _, m := range regexp.MustCompile(`(\d+) 0 R`).FindAllStringSubmatch(pages, -1) {
+		id, _ := strconv.Atoi(m[1])
+		page := p.objects[id]
+		if !strings.Contains(page, "/Type /Page ") || !strings.Contains(page, "/MediaBox [0 0 595.28 841.89]") {
+			t.Errorf("page %d: %q", id, page)
+		}
+		stream, ok := p.streams[ref(t, page, "Contents")]
+		if !ok {
+			t.Fatalf("page %d: contents is not a stream", id)
+		}
+		content.Write(stream)
+	}
+
+	// 🔸 ข้อความเข้ารหัสเป็น hex WinAnsi — ตัวอักษรพิเศษอย่างวงเล็บ / backslash ต้องไม่ทำให้ stream เสีย
+	for _, want := range []string{
+		fmt.Sprintf("<%X> Tj", "Receipt (copy)"),
+		fmt.Sprintf("<%X> Tj", `Total: 1,234.50 \ THB`),
+		fmt.Sprintf("<%X> Tj", "Page 2"),
+		"0.5 w 40 771.89 m 555 771.89 l S",
+		"0.9 g 1 w 40 721.89 200 40 re B 0 g",
+	} {
+		if !strings.Contains(content.String(), want) {
+			t.Errorf("page content missing %q", want)
+		}
+	}
+}
+
+// ✅ ฟอนต์ TrueType ฝังเป็น Type0 / CIDFontType2 พร้อม FontFile2 และ ToUnicode
+func TestTrueTypeEmbeds(t *testing.T) {
+	path := "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Skipf("no TrueType font at %s", path)
+	}
+	face, err := ParseTrueType(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	font := face.Font()
+	if !font.Supports('A') || font.Width("AB", 10) <= 0 {
+		t.Fatalf("font should support Latin text")
+	}
+
+	doc := New()
+	doc.AddPage().Text(font, 12, 40, 60, "Hello")
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parsePDF(t, data)
+
+	var fontFile []byte
+	var types []string
+	for _, body := range p.objects {
+		if strings.Contains(body, "/Subtype /Type0") || strings.Contains(body, "/Subtype /CIDFontType2") {
+			types = append(types, body)
+		}
+		if strings.Contains(body, "/FontFile2") {
+			fontFile = p.streams[ref(t, body, "FontFile2")]
+		}
+	}
+	if len(types) != 2 {
+		t.Errorf("want Type0 and CIDFontType2 dictionaries, got %d", len(types))
+	}
+	if !bytes.Equal(fontFile, raw) {
+		t.Errorf("FontFile2 stream (%d bytes) should be the original font (%d bytes)", len(fontFile), len(raw))
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+		want  []string
+	}{
+		{"fits", "EV charging", 200, []string{"EV charging"}},
+		{"wraps at spaces", "EV charging receipt", 60, []string{"EV charging", "receipt"}}, // "EV charging" = 54.47pt
+		{"keeps newlines", "line one\nline two", 200, []string{"line one", "line two"}},
+		{"breaks long words", "ABCDEFGHIJ", 30, []string{"ABCD", "EFGH", "IJ"}}, // "ABCD" = 27.78pt
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapText(Helvetica, 10, tt.width, tt.text)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("WrapText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package qrcode
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrNotFound เมื่อไม่พบ QR ในภาพ หรืออ่าน QR ที่พบไม่ได้
+var ErrNotFound = errors.New("qrcode: no readable QR code found")
+
+// ErrFormat เมื่อ bit matrix ไม่ใช่ QR ที่ถูกต้อง
+var ErrFormat = errors.New("qrcode: invalid format information")
+
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// ✅ หา level/mask ที่ format bits ใกล้เคียงที่สุด (แก้ได้ไม่เกิน 3 bit)
+func decodeFormat(candidates ...int) (Level, int, bool) {
+	bestDist, bestLevel, bestMask := 16, Low, 0
+	for level := Low; level <= High; level++ {
+		for mask := 0; mask < 8; mask++ {
+			want := FormatBits(level, mask)
+			for _, got := range candidates {
+				if d := bits.OnesCount(uint(want ^ got)); d < bestDist {
+					bestDist, bestLevel, bestMask = d, level, mask
+				}
+			}
+		}
+	}
+	return bestLevel, bestMask, bestDist <= 3
+}
+
+// ✅ หา version ที่ version bits ใกล้เคียงที่สุด (ตั้งแต่ version 7)
+func decodeVersion(candidates ...int) (int, bool) {
+	bestDist, bestVer := 19, 0
+	for ver := 7; ver <= 40; ver++ {
+		want := VersionBits(ver)
+		for _, got := range candidates {
+			if d := bits.OnesCount(uint(want ^ got)); d < bestDist {
+				bestDist, bestVer = d, ver
+			}
+		}
+	}
+	return bestVer, bestDist <= 3
+}
+
+// DecodeMatrix อ่านข้อมูลจาก bit matrix (dark[y][x]) ของ QR ที่ตัดขอบแล้ว
+func DecodeMatrix(dark [][]bool) ([]byte, error) {
+	size := len(dark)
+	if size < 21 || size > 177 || (size-17)%4 != 0 {
+		return nil, ErrFormat
+	}
+	for _, row := range dark {
+		if len(row) != size {
+			return nil, ErrFormat
+		}
+	}
+	at := func(x, y int) int {
+		if dark[y][x] {
+			return 1
+		}
+		return 0
+	}
+
+	// 🔹 version
+	ver := (size - 17) / 4
+	if ver >= 7 {
+		var a, b int
+		for i := 17; i >= 0; i-- {
+			a = a<<1 | at(size-11+i%3, i/3)
+			b = b<<1 | at(i/3, size-11+i%3)
+		}
+		v, ok := decodeVersion(a, b)
+		if !ok || v != ver {
+			return nil, ErrFormat
+		}
+	}
+
+	// 🔹 format (level + mask) จากทั้งสองตำแหน่ง
+	var f1, f2 int
+	for i := 14; i >= 0; i-- {
+		switch {
+		case i <= 5:
+			f1 = f1<<1 | at(8, i)
+		case i == 6:
+			f1 = f1<<1 | at(8, 7)
+		case i == 7:
+			f1 = f1<<1 | at(8, 8)
+		case i == 8:
+			f1 = f1<<1 | at(7, 8)
+		default:
+			f1 = f1<<1 | at(14-i, 8)
+		}
+		if i < 8 {
+			f2 = f2<<1 | at(size-1-i, 8)
+		} else {
+			f2 = f2<<1 | at(8, size-15+i)
+		}
+	}
+	level, mask, ok := decodeFormat(f1, f2)
+	if !ok {
+		return nil, ErrFormat
+	}
+
+	// 🔹 ใช้ function pattern ของ encoder เพื่อรู้ว่า module ไหนเป็นข้อมูล
+	q := &Code{Version: ver, Size: size, Level: level, Mask: mask}
+	q.modules = make([][]bool, size)
+	q.isFunction = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.isFunction[i] = make([]bool, size)
+	}
+	q.drawFunctionPatterns()
+
+	// 🔹 อ่าน codeword ตามลำดับ zigzag เดียวกับ drawCodewords
+	raw := make([]byte, numRawDataModules(ver)/8)
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if q.isFunction[y][x] || i >= len(raw)*8 {
+					continue
+				}
+				bit := dark[y][x] != MaskBit(mask, x, y)
+				if bit {
+					raw[i>>3] |= 1 << (7 - uint(i&7))
+				}
+				i++
+			}
+		}
+	}
+
+	data, err := deinterleave(raw, ver, level)
+	if err != nil {
+		return nil, err
+	}
+	return parseSegments(data, ver)
+}
+
+// deinterleave แยก codeword กลับเป็น block แก้ error แล้วต่อ data codeword
+func deinterleave(raw []byte, ver int, level Level) ([]byte, error) {
+	numBlocks := numErrorCorrectionBlocks[level][ver]
+	blockEccLen := eccCodewordsPerBlock[level][ver]
+	numShortBlocks := numBlocks - len(raw)%numBlocks
+	shortBlockLen := len(raw) / numBlocks
+	shortDataLen := shortBlockLen - blockEccLen
+
+	blocks := make([][]byte, numBlocks)
+	for j := range blocks {
+		blocks[j] = make([]byte, shortBlockLen+1)
+	}
+	k := 0
+	for i := 0; i <= shortBlockLen; i++ {
+		for j := range blocks {
+			if i != shortDataLen || j >= numShortBlocks {
+				blocks[j][i] = raw[k]
+				k++
+			}
+		}
+	}
+
+	data := make([]byte, 0, DataCapacity(ver, level))
+	for j, block := range blocks {
+		dataLen := shortDataLen + 1
+		if j < numShortBlocks {
+			// ตัดช่องว่างที่ใส่ไว้ให้ block สั้นยาวเท่ากัน
+			block = append(block[:shortDataLen:shortDataLen], block[shortDataLen+1:]...)
+			dataLen = shortDataLen
+		}
+		if _, err := RSCorrect(block, blockEccLen); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:dataLen]...)
+	}
+	return data, nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) available() int { return len(r.data)*8 - r.pos }
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos>>3]>>(7-uint(r.pos&7))&1)
+		r.pos++
+	}
+	return v
+}
+
+// ✅ จำนวน bit ของความยาวข้อมูล แยกตาม mode และช่วง version
+func countBits(mode, ver int) int {
+	idx := 0
+	switch {
+	case ver >= 27:
+		idx = 2
+	case ver >= 10:
+		idx = 1
+	}
+	switch mode {
+	case 0x1:
+		return [3]int{10, 12, 14}[idx]
+	case 0x2:
+		return [3]int{9, 11, 13}[idx]
+	case 0x4:
+		return [3]int{8, 16, 16}[idx]
+	default: // kanji
+		return [3]int{8, 10, 12}[idx]
+	}
+}
+
+// parseSegments แปลง data codeword เป็นข้อความ (numeric, alphanumeric, byte, kanji)
+func parseSegments(data []byte, ver int) ([]byte, error) {
+	r := &bitReader{data: data}
+	var out []byte
+	for r.available() >= 4 {
+		mode := r.read(4)
+		switch mode {
+		case 0x0: // terminator
+			return out, nil
+		case 0x7: // ECI — ข้ามค่า designator
+			if r.available() < 8 {
+				return nil, ErrFormat
+			}
+			first := r.read(8)
+			switch {
+			case first&0x80 == 0:
+			case first&0xC0 == 0x80:
+				r.read(8)
+			default:
+				r.read(16)
+			}
+			continue
+		case 0x5, 0x9: // FNC1
+			if mode == 0x9 {
+				r.read(8)
+			}
+			continue
+		case 0x3: // structured append
+			r.read(16)
+			continue
+		case 0x1, 0x2, 0x4, 0x8:
+		default:
+			return nil, ErrFormat
+		}
+
+		if r.available() < countBits(mode, ver) {
+			return nil, ErrFormat
+		}
+		count := r.read(countBits(mode, ver))
+		switch mode {
+		case 0x1:
+			for ; count >= 3; count -= 3 {
+				if r.available() < 10 {
+					return nil, ErrFormat
+				}
+				v := r.read(10)
+				out = append(out, byte('0'+v/100), byte('0'+v/10%10), byte('0'+v%10))
+			}
+			switch count {
+			case 2:
+				if r.available() < 7 {
+					return nil, ErrFormat
+				}
+				v := r.read(7)
+				out = append(out, byte('0'+v/10), byte('0'+v%10))
+			case 1:
+				if r.available() < 4 {
+					return nil, ErrFormat
+				}
+				out = append(out, byte('0'+r.read(4)))
+			}
+		case 0x2:
+			for ; count >= 2; count -= 2 {
+				if r.available() < 11 {
+					return nil, ErrFormat
+				}
+				v := r.read(11)
+				if v/45 >= 45 {
+					return nil, ErrFormat
+				}
+				out = append(out, alphanumericCharset[v/45], alphanumericCharset[v%45])
+			}
+			if count == 1 {
+				if r.available() < 6 {
+					return nil, ErrFormat
+				}
+				v := r.read(6)
+				if v >= 45 {
+					return nil, ErrFormat
+				}
+				out = append(out, alphanumericCharset[v])
+			}
+		case 0x4:
+			if r.available() < count*8 {
+				return nil, ErrFormat
+			}
+			for ; count > 0; count-- {
+				out = append(out, byte(r.read(8)))
+			}
+		case 0x8:
+			// kanji คืนเป็น Shift JIS ตามที่เก็บ
+			if r.available() < count*13 {
+				return nil, ErrFormat
+			}
+			for ; count > 0; count-- {
+				v := r.read(13)
+				v = (v/0xC0)<<8 | v%0xC0
+				if v < 0x1F00 {
+					v += 0x8140
+				} else {
+					v += 0xC140
+				}
+				out = append(out, byte(v>>8), byte(v))
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestDecodeMatrix(t *testing.T) {
+	dark := darkMatrix(promptPayMatrix)
+	got, err := DecodeMatrix(dark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != promptPayPayload {
+		t.Errorf("payload %q, want %q", got, promptPayPayload)
+	}
+
+	// 🔸 module เสียที่มุมขวาล่าง (ข้อมูล ไม่ใช่ function pattern) — ECC ต้องแก้ได้
+	for y := 41; y < 45; y++ {
+		for x := 41; x < 45; x++ {
+			dark[y][x] = !dark[y][x]
+		}
+	}
+	got, err = DecodeMatrix(dark)
+	if err != nil {
+		t.Fatalf("damaged matrix: %v", err)
+	}
+	if string(got) != promptPayPayload {
+		t.Errorf("damaged matrix: payload %q, want %q", got, promptPayPayload)
+	}
+}
+
+func TestDecodeMatrixInvalidSize(t *testing.T) {
+	for _, size := range []int{0, 20, 22, 181} {
+		dark := make([][]bool, size)
+		for i := range dark {
+			dark[i] = make([]bool, size)
+		}
+		if _, err := DecodeMatrix(dark); !errors.Is(err, ErrFormat) {
+			t.Errorf("size %d: err %v, want ErrFormat", size, err)
+		}
+	}
+}
+
+func TestRSCorrect(t *testing.T) {
+	tests := []struct {
+		name    string
+		flip    []int // ตำแหน่ง codeword ที่ทำให้เสีย
+		fixed   int
+		wantErr error
+	}{
+		{"clean", nil, 0, nil},
+		{"one data error", []int{3}, 1, nil},
+		{"one ecc error", []int{20}, 1, nil},
+		{"max correctable", []int{0, 5, 9, 14, 22}, 5, nil},
+		{"too many", []int{0, 2, 4, 6, 8, 10}, 0, ErrUncorrectable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := append(append([]byte{}, isoData...), isoECC...)
+			for _, i := range tt.flip {
+				block[i] ^= 0x5A
+			}
+			fixed, err := RSCorrect(block, len(isoECC))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if fixed != tt.fixed {
+				t.Errorf("fixed %d, want %d", fixed, tt.fixed)
+			}
+			if !bytes.Equal(block[:len(isoData)], isoData) {
+				t.Errorf("data % X, want % X", block[:len(isoData)], isoData)
+			}
+		})
+	}
+}
+
+// ✅ หมุนภาพ 90° ตามเข็มนาฬิกา
+func rotate90(src *image.Gray) *image.Gray {
+	b := src.Bounds()
+	dst := image.NewGray(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetGray(b.Dy()-1-y, x, src.GrayAt(x, y))
+		}
+	}
+	return dst
+}
+
+func TestDecodeImage(t *testing.T) {
+	q, err := EncodeString(promptPayPayload, Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpegOf := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 60}); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	pngOf := func(scale, border int) []byte {
+		data, err := q.PNG(scale, border)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"png", pngOf(4, 4)},
+		{"png small modules", pngOf(2, 4)},
+		{"rotated", jpegOf(rotate90(q.Image(5, 4)))},
+		{"jpeg", jpegOf(q.Image(3, 6))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeBytes(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != promptPayPayload {
+				t.Errorf("payload %q, want %q", got, promptPayPayload)
+			}
+		})
+	}
+}
+
+func TestDecodeNotFound(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 200, 200))
+	for i := range blank.Pix {
+		blank.Pix[i] = 0xFF
+	}
+	if _, err := Decode(blank); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err %v, want ErrNotFound", err)
+	}
+}
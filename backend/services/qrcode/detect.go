@@ -0,0 +1,693 @@
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/jpeg" // ✅ รองรับสลิปที่บันทึกเป็น JPEG
+	_ "image/png"
+	"math"
+	"sort"
+)
+
+// ============================================================================
+// 🔹 อ่าน QR จากรูปภาพ (สลิปโอนเงิน, screenshot, รูปถ่าย)
+// ============================================================================
+
+// DecodeBytes อ่าน QR จากไฟล์ภาพ (JPEG/PNG)
+func DecodeBytes(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return Decode(img)
+}
+
+// Decode หา QR ในภาพแล้วคืนข้อความของ QR แรกที่อ่านได้
+func Decode(img image.Image) ([]byte, error) {
+	gray := toGray(img)
+	for _, bin := range []*bitImage{hybridBinarize(gray), globalBinarize(gray)} {
+		if bin == nil {
+			continue
+		}
+		if text, err := decodeBinary(bin); err == nil {
+			return text, nil
+		}
+	}
+
+	// 🔸 QR เล็กมาก (module ~2px) ขยายภาพ 2 เท่าแล้วลองใหม่
+	if gray.w*gray.h <= maxUpscalePixels {
+		if bin := hybridBinarize(gray.upscale2x()); bin != nil {
+			if text, err := decodeBinary(bin); err == nil {
+				return text, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// maxUpscalePixels จำกัดขนาดภาพที่จะขยาย เพื่อไม่ให้ใช้หน่วยความจำ/เวลามากเกินไป
+const maxUpscalePixels = 2500 * 2500
+
+// bitImage คือภาพขาวดำ (true = ดำ)
+type bitImage struct {
+	w, h int
+	bits []bool
+}
+
+func (b *bitImage) black(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.w && y < b.h && b.bits[y*b.w+x]
+}
+
+type grayImage struct {
+	w, h int
+	pix  []uint8
+}
+
+func toGray(img image.Image) *grayImage {
+	r := img.Bounds()
+	g := &grayImage{w: r.Dx(), h: r.Dy(), pix: make([]uint8, r.Dx()*r.Dy())}
+	if src, ok := img.(*image.Gray); ok {
+		for y := 0; y < g.h; y++ {
+			copy(g.pix[y*g.w:(y+1)*g.w], src.Pix[src.PixOffset(r.Min.X, r.Min.Y+y):])
+		}
+		return g
+	}
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			c := img.At(r.Min.X+x, r.Min.Y+y)
+			// พื้นหลังโปร่งใสถือเป็นสีขาว
+			if _, _, _, a := c.RGBA(); a < 0x8000 {
+				g.pix[y*g.w+x] = 255
+				continue
+			}
+			g.pix[y*g.w+x] = color.GrayModel.Convert(c).(color.Gray).Y
+		}
+	}
+	return g
+}
+
+// upscale2x ขยายภาพ 2 เท่าแบบ bilinear
+func (g *grayImage) upscale2x() *grayImage {
+	out := &grayImage{w: g.w * 2, h: g.h * 2, pix: make([]uint8, g.w*g.h*4)}
+	at := func(x, y int) int { return int(g.pix[min(y, g.h-1)*g.w+min(x, g.w-1)]) }
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			sx, sy := x/2, y/2
+			v := at(sx, sy)
+			switch {
+			case x%2 == 1 && y%2 == 1:
+				v = (at(sx, sy) + at(sx+1, sy) + at(sx, sy+1) + at(sx+1, sy+1) + 2) / 4
+			case x%2 == 1:
+				v = (at(sx, sy) + at(sx+1, sy) + 1) / 2
+			case y%2 == 1:
+				v = (at(sx, sy) + at(sx, sy+1) + 1) / 2
+			}
+			out.pix[y*out.w+x] = uint8(v)
+		}
+	}
+	return out
+}
+
+// ✅ threshold เฉพาะที่แบบ block 8x8 (เหมือน HybridBinarizer ของ ZXing) ทนต่อแสงไม่สม่ำเสมอ
+func hybridBinarize(g *grayImage) *bitImage {
+	const block = 8
+	if g.w < 5*block || g.h < 5*block {
+		return nil
+	}
+	bw, bh := (g.w+block-1)/block, (g.h+block-1)/block
+	avg := make([]int, bw*bh)
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			sum, n, lo, hi := 0, 0, 255, 0
+			for y := by * block; y < min((by+1)*block, g.h); y++ {
+				for x := bx * block; x < min((bx+1)*block, g.w); x++ {
+					v := int(g.pix[y*g.w+x])
+					sum += v
+					n++
+					lo, hi = min(lo, v), max(hi, v)
+				}
+			}
+			a := sum / n
+			if hi-lo <= 24 {
+				// block สีเรียบ ถือว่าเป็นพื้นหลัง เว้นแต่ block ข้างเคียงมืดกว่า
+				a = lo / 2
+				if by > 0 && bx > 0 {
+					neighbor := (avg[(by-1)*bw+bx] + 2*avg[by*bw+bx-1] + avg[(by-1)*bw+bx-1]) / 4
+					if lo < neighbor {
+						a = neighbor
+					}
+				}
+			}
+			avg[by*bw+bx] = a
+		}
+	}
+
+	b := &bitImage{w: g.w, h: g.h, bits: make([]bool, g.w*g.h)}
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			cx, cy := min(max(bx, 2), bw-3), min(max(by, 2), bh-3)
+			sum := 0
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					sum += avg[(cy+dy)*bw+cx+dx]
+				}
+			}
+			threshold := sum / 25
+			for y := by * block; y < min((by+1)*block, g.h); y++ {
+				for x := bx * block; x < min((bx+1)*block, g.w); x++ {
+					b.bits[y*g.w+x] = int(g.pix[y*g.w+x]) <= threshold
+				}
+			}
+		}
+	}
+	return b
+}
+
+// ✅ threshold เดียวทั้งภาพด้วยวิธี Otsu (สำรองเมื่อภาพเล็กหรือคมชัดมาก)
+func globalBinarize(g *grayImage) *bitImage {
+	var hist [256]int
+	for _, v := range g.pix {
+		hist[v]++
+	}
+	total := len(g.pix)
+	sumAll := 0
+	for i, n := range hist {
+		sumAll += i * n
+	}
+	bestVar, threshold := -1.0, 127
+	sumB, wB := 0, 0
+	for t := 0; t < 256; t++ {
+		wB += hist[t]
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += t * hist[t]
+		mB := float64(sumB) / float64(wB)
+		mF := float64(sumAll-sumB) / float64(wF)
+		if v := float64(wB) * float64(wF) * (mB - mF) * (mB - mF); v > bestVar {
+			bestVar, threshold = v, t
+		}
+	}
+	b := &bitImage{w: g.w, h: g.h, bits: make([]bool, g.w*g.h)}
+	for i, v := range g.pix {
+		b.bits[i] = int(v) <= threshold
+	}
+	return b
+}
+
+// ============================================================================
+// 🔹 Finder pattern (สี่เหลี่ยม 3 มุม อัตราส่วน 1:1:3:1:1)
+// ============================================================================
+
+type finderPattern struct {
+	x, y       float64
+	moduleSize float64
+	count      int
+}
+
+func sumCounts(counts []int) int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
+
+func foundFinderCross(counts [5]int) bool {
+	total := sumCounts(counts[:])
+	if total < 7 {
+		return false
+	}
+	for _, c := range counts {
+		if c == 0 {
+			return false
+		}
+	}
+	ms := float64(total) / 7
+	variance := ms / 2
+	return math.Abs(ms-float64(counts[0])) < variance &&
+		math.Abs(ms-float64(counts[1])) < variance &&
+		math.Abs(3*ms-float64(counts[2])) < 3*variance &&
+		math.Abs(ms-float64(counts[3])) < variance &&
+		math.Abs(ms-float64(counts[4])) < variance
+}
+
+func centerFromEnd(counts [5]int, end int) float64 {
+	return float64(end-counts[4]-counts[3]) - float64(counts[2])/2
+}
+
+type finderFinder struct {
+	img      *bitImage
+	patterns []*finderPattern
+}
+
+// crossCheck นับ run ตามแนว (dx, dy) จากจุดกลาง แล้วคืนตำแหน่งกึ่งกลางบนแนวนั้น
+func (f *finderFinder) crossCheck(x, y, dx, dy, maxCount, originalTotal int) (float64, bool) {
+	var counts [5]int
+	at := func(step int) bool { return f.img.black(x+dx*step, y+dy*step) }
+	inside := func(step int) bool {
+		xx, yy := x+dx*step, y+dy*step
+		return xx >= 0 && yy >= 0 && xx < f.img.w && yy < f.img.h
+	}
+
+	s := 0
+	for inside(s) && at(s) {
+		counts[2]++
+		s--
+	}
+	if !inside(s) {
+		return 0, false
+	}
+	for inside(s) && !at(s) && counts[1] <= maxCount {
+		counts[1]++
+		s--
+	}
+	if !inside(s) || counts[1] > maxCount {
+		return 0, false
+	}
+	for inside(s) && at(s) && counts[0] <= maxCount {
+		counts[0]++
+		s--
+	}
+	if counts[0] > maxCount {
+		return 0, false
+	}
+
+	s = 1
+	for inside(s) && at(s) {
+		counts[2]++
+		s++
+	}
+	if !inside(s) {
+		return 0, false
+	}
+	for inside(s) && !at(s) && counts[3] < maxCount {
+		counts[3]++
+		s++
+	}
+	if !inside(s) || counts[3] >= maxCount {
+		return 0, false
+	}
+	for inside(s) && at(s) && counts[4] < maxCount {
+		counts[4]++
+		s++
+	}
+	if counts[4] >= maxCount {
+		return 0, false
+	}
+
+	total := sumCounts(counts[:])
+	if 5*abs(total-originalTotal) >= 2*originalTotal || !foundFinderCross(counts) {
+		return 0, false
+	}
+	return centerFromEnd(counts, s), true
+}
+
+func (f *finderFinder) handlePossibleCenter(counts [5]int, row, end int) {
+	total := sumCounts(counts[:])
+	cx := centerFromEnd(counts, end)
+	offY, ok := f.crossCheck(int(cx), row, 0, 1, counts[2], total)
+	if !ok {
+		return
+	}
+	cy := float64(row) + offY
+	offX, ok := f.crossCheck(int(cx), int(cy), 1, 0, counts[2], total)
+	if !ok {
+		return
+	}
+	cx = float64(int(cx)) + offX
+	ms := float64(total) / 7
+
+	for _, p := range f.patterns {
+		if math.Abs(cx-p.x) <= ms && math.Abs(cy-p.y) <= ms {
+			if diff := math.Abs(ms - p.moduleSize); diff <= 1 || diff <= p.moduleSize {
+				n := float64(p.count)
+				p.x = (p.x*n + cx) / (n + 1)
+				p.y = (p.y*n + cy) / (n + 1)
+				p.moduleSize = (p.moduleSize*n + ms) / (n + 1)
+				p.count++
+				return
+			}
+		}
+	}
+	f.patterns = append(f.patterns, &finderPattern{x: cx, y: cy, moduleSize: ms, count: 1})
+}
+
+// findFinders สแกนทุกแถวหา run ดำ-ขาว-ดำ-ขาว-ดำ ที่เป็นอัตราส่วน 1:1:3:1:1
+func findFinders(img *bitImage) []*finderPattern {
+	f := &finderFinder{img: img}
+	for y := 0; y < img.h; y++ {
+		var counts [5]int
+		state := 0
+		for x := 0; x < img.w; x++ {
+			if img.black(x, y) {
+				if state&1 == 1 {
+					state++
+				}
+				counts[state]++
+				continue
+			}
+			if state&1 == 1 {
+				counts[state]++
+				continue
+			}
+			if state != 4 {
+				state++
+				counts[state]++
+				continue
+			}
+			if foundFinderCross(counts) {
+				f.handlePossibleCenter(counts, y, x)
+			}
+			counts = [5]int{counts[2], counts[3], counts[4], 1, 0}
+			state = 3
+		}
+		if foundFinderCross(counts) {
+			f.handlePossibleCenter(counts, y, img.w)
+		}
+	}
+	return f.patterns
+}
+
+func distance(ax, ay, bx, by float64) float64 {
+	return math.Hypot(ax-bx, ay-by)
+}
+
+// ✅ เรียง finder เป็น (bottomLeft, topLeft, topRight) — topLeft คือมุมฉากของสามเหลี่ยม
+func orderFinders(a, b, c *finderPattern) (bl, tl, tr *finderPattern) {
+	ab, bc, ac := distance(a.x, a.y, b.x, b.y), distance(b.x, b.y, c.x, c.y), distance(a.x, a.y, c.x, c.y)
+	switch {
+	case bc >= ab && bc >= ac:
+		tl, bl, tr = a, b, c
+	case ac >= bc && ac >= ab:
+		tl, bl, tr = b, a, c
+	default:
+		tl, bl, tr = c, a, b
+	}
+	if (tr.x-tl.x)*(bl.y-tl.y)-(tr.y-tl.y)*(bl.x-tl.x) < 0 {
+		bl, tr = tr, bl
+	}
+	return bl, tl, tr
+}
+
+type finderTriple struct {
+	bl, tl, tr *finderPattern
+	score      float64
+}
+
+// candidateTriples จับกลุ่ม finder 3 ตัวที่ขนาดใกล้กันและเป็นสามเหลี่ยมมุมฉากหน้าจั่ว
+func candidateTriples(patterns []*finderPattern) []finderTriple {
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].count > patterns[j].count })
+	if len(patterns) > 12 {
+		patterns = patterns[:12]
+	}
+	var out []finderTriple
+	for i := 0; i < len(patterns); i++ {
+		for j := i + 1; j < len(patterns); j++ {
+			for k := j + 1; k < len(patterns); k++ {
+				bl, tl, tr := orderFinders(patterns[i], patterns[j], patterns[k])
+				sizes := []float64{bl.moduleSize, tl.moduleSize, tr.moduleSize}
+				sort.Float64s(sizes)
+				if sizes[2] > 1.5*sizes[0] {
+					continue
+				}
+				top := distance(tl.x, tl.y, tr.x, tr.y)
+				left := distance(tl.x, tl.y, bl.x, bl.y)
+				diag := distance(bl.x, bl.y, tr.x, tr.y)
+				if top < 7*sizes[0] || left < 7*sizes[0] {
+					continue
+				}
+				sideDiff := math.Abs(top-left) / math.Max(top, left)
+				diagDiff := math.Abs(diag-math.Sqrt2*(top+left)/2) / diag
+				if sideDiff > 0.5 || diagDiff > 0.25 {
+					continue
+				}
+				out = append(out, finderTriple{bl: bl, tl: tl, tr: tr, score: sideDiff + diagDiff + (sizes[2]-sizes[0])/sizes[2]})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].score < out[j].score })
+	return out
+}
+
+// ============================================================================
+// 🔹 Alignment pattern (ช่วยแก้ภาพเอียง/มุมกล้อง ตั้งแต่ version 2)
+// ============================================================================
+
+// alignmentMismatch นับ module ที่ไม่ตรงกับ alignment pattern 5x5 รอบจุด (cx, cy)
+// du, dv คือเวกเตอร์ 1 module ตามแนวนอน/แนวตั้งของ QR ในภาพ
+func alignmentMismatch(img *bitImage, cx, cy float64, du, dv [2]float64) int {
+	miss := 0
+	for j := -2; j <= 2; j++ {
+		for i := -2; i <= 2; i++ {
+			want := max(abs(i), abs(j)) != 1
+			x := cx + float64(i)*du[0] + float64(j)*dv[0]
+			y := cy + float64(i)*du[1] + float64(j)*dv[1]
+			if img.black(int(math.Floor(x)), int(math.Floor(y))) != want {
+				miss++
+			}
+		}
+	}
+	return miss
+}
+
+// findAlignment หา alignment pattern ใกล้ module (u, v) ที่คาดไว้จาก transform ประมาณการ
+func findAlignment(img *bitImage, t transform, u, v float64) (float64, float64, bool) {
+	ex, ey := t.apply(u, v)
+	ux, uy := t.apply(u+1, v)
+	vx, vy := t.apply(u, v+1)
+	du, dv := [2]float64{ux - ex, uy - ey}, [2]float64{vx - ex, vy - ey}
+	ms := (math.Hypot(du[0], du[1]) + math.Hypot(dv[0], dv[1])) / 2
+	if ms < 1 {
+		return 0, 0, false
+	}
+
+	type candidate struct{ x, y, dist float64 }
+	for _, factor := range []float64{4, 8} {
+		allowance := int(factor * ms)
+		bestMiss := 3 // ยอมให้ผิดได้ไม่เกิน 2 จาก 25 module
+		var found []candidate
+		for y := max(0, int(ey)-allowance); y <= min(img.h-1, int(ey)+allowance); y++ {
+			for x := max(0, int(ex)-allowance); x <= min(img.w-1, int(ex)+allowance); x++ {
+				if !img.black(x, y) {
+					continue
+				}
+				cx, cy := float64(x)+0.5, float64(y)+0.5
+				miss := alignmentMismatch(img, cx, cy, du, dv)
+				if miss > bestMiss {
+					continue
+				}
+				if miss < bestMiss {
+					bestMiss, found = miss, found[:0]
+				}
+				found = append(found, candidate{cx, cy, distance(cx, cy, ex, ey)})
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		// ใช้กลุ่มที่ใกล้ตำแหน่งคาดการณ์ที่สุด แล้วเฉลี่ยหาจุดกึ่งกลาง
+		nearest := found[0]
+		for _, c := range found {
+			if c.dist < nearest.dist {
+				nearest = c
+			}
+		}
+		var sumX, sumY, n float64
+		for _, c := range found {
+			if distance(c.x, c.y, nearest.x, nearest.y) <= ms {
+				sumX, sumY, n = sumX+c.x, sumY+c.y, n+1
+			}
+		}
+		return sumX / n, sumY / n, true
+	}
+	return 0, 0, false
+}
+
+// ============================================================================
+// 🔹 Perspective transform + สุ่มอ่าน module
+// ============================================================================
+
+// transform คือ homography จากพิกัด module ไปพิกัดภาพ
+type transform [8]float64
+
+func (t transform) apply(u, v float64) (float64, float64) {
+	den := t[6]*u + t[7]*v + 1
+	return (t[0]*u + t[1]*v + t[2]) / den, (t[3]*u + t[4]*v + t[5]) / den
+}
+
+// solveTransform หา homography จากจุดคู่กัน 4 จุด (src = module, dst = ภาพ)
+func solveTransform(src, dst [4][2]float64) (transform, bool) {
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		u, v, x, y := src[i][0], src[i][1], dst[i][0], dst[i][1]
+		m[2*i] = [9]float64{u, v, 1, 0, 0, 0, -u * x, -v * x, x}
+		m[2*i+1] = [9]float64{0, 0, 0, u, v, 1, -u * y, -v * y, y}
+	}
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for r := col + 1; r < 8; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return transform{}, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for r := 0; r < 8; r++ {
+			if r == col {
+				continue
+			}
+			f := m[r][col] / m[col][col]
+			for c := col; c < 9; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+	var t transform
+	for i := range t {
+		t[i] = m[i][8] / m[i][i]
+	}
+	return t, true
+}
+
+func sampleGrid(img *bitImage, t transform, size int) [][]bool {
+	grid := make([][]bool, size)
+	for y := range grid {
+		grid[y] = make([]bool, size)
+		for x := range grid[y] {
+			px, py := t.apply(float64(x)+0.5, float64(y)+0.5)
+			grid[y][x] = img.black(int(math.Floor(px)), int(math.Floor(py)))
+		}
+	}
+	return grid
+}
+
+func transpose(grid [][]bool) [][]bool {
+	out := make([][]bool, len(grid))
+	for y := range out {
+		out[y] = make([]bool, len(grid))
+		for x := range out[y] {
+			out[y][x] = grid[x][y]
+		}
+	}
+	return out
+}
+
+// blackWhiteBlackRun วัดระยะจากกลาง finder ผ่าน ดำ-ขาว-ดำ ไปจนถึงขอบนอก (3.5 module)
+func (b *bitImage) blackWhiteBlackRun(fx, fy, tx, ty float64) float64 {
+	dist := distance(fx, fy, tx, ty)
+	if dist == 0 {
+		return 0
+	}
+	dx, dy := (tx-fx)/dist, (ty-fy)/dist
+	state := 0
+	for step := 0.0; ; step++ {
+		x, y := int(math.Floor(fx+dx*step)), int(math.Floor(fy+dy*step))
+		if x < 0 || y < 0 || x >= b.w || y >= b.h {
+			if state == 2 {
+				return step
+			}
+			return 0
+		}
+		if (state == 1) == b.black(x, y) {
+			if state == 2 {
+				return step
+			}
+			state++
+		}
+	}
+}
+
+// moduleSizeAlong ประมาณขนาด module จาก finder ทั้งสองข้างของเส้นที่เชื่อม a กับ b
+func moduleSizeAlong(img *bitImage, a, b *finderPattern) float64 {
+	both := func(p, q *finderPattern) float64 {
+		forward := img.blackWhiteBlackRun(p.x, p.y, q.x, q.y)
+		backward := img.blackWhiteBlackRun(p.x, p.y, 2*p.x-q.x, 2*p.y-q.y)
+		if forward == 0 || backward == 0 {
+			return 0
+		}
+		return (forward + backward) / 7
+	}
+	sa, sb := both(a, b), both(b, a)
+	switch {
+	case sa == 0:
+		return sb
+	case sb == 0:
+		return sa
+	}
+	return (sa + sb) / 2
+}
+
+// decodeTriple ลองอ่าน QR จาก finder 3 ตัว โดยประมาณขนาดแล้วลองขนาดข้างเคียง
+func decodeTriple(img *bitImage, t finderTriple) ([]byte, error) {
+	bl, tl, tr := t.bl, t.tl, t.tr
+	ms := (moduleSizeAlong(img, tl, tr) + moduleSizeAlong(img, tl, bl)) / 2
+	if ms == 0 {
+		ms = (bl.moduleSize + tl.moduleSize + tr.moduleSize) / 3
+	}
+	span := (distance(tl.x, tl.y, tr.x, tr.y) + distance(tl.x, tl.y, bl.x, bl.y)) / 2
+	estimate := int(math.Round(span/ms)) + 7
+	// ขนาดจริงต้องเป็น 4v+17
+	base := estimate - ((estimate-17)%4+4)%4
+	if estimate-base >= 2 {
+		base += 4
+	}
+
+	for _, size := range []int{base, base - 4, base + 4} {
+		if size < 21 || size > 177 {
+			continue
+		}
+		far := float64(size) - 3.5
+		src := [4][2]float64{{3.5, 3.5}, {far, 3.5}, {3.5, far}, {far, far}}
+		dst := [4][2]float64{{tl.x, tl.y}, {tr.x, tr.y}, {bl.x, bl.y}, {tr.x + bl.x - tl.x, tr.y + bl.y - tl.y}}
+
+		var transforms []transform
+		if size > 21 {
+			// ใช้ alignment pattern มุมขวาล่างแทนมุมที่ 4 ถ้าหาเจอ
+			affine, ok := solveTransform(src, dst)
+			if ok {
+				if fx, fy, found := findAlignment(img, affine, float64(size)-6.5, float64(size)-6.5); found {
+					alignSrc := src
+					alignDst := dst
+					alignSrc[3] = [2]float64{float64(size) - 6.5, float64(size) - 6.5}
+					alignDst[3] = [2]float64{fx, fy}
+					if pt, ok := solveTransform(alignSrc, alignDst); ok {
+						transforms = append(transforms, pt)
+					}
+				}
+				transforms = append(transforms, affine)
+			}
+		} else if affine, ok := solveTransform(src, dst); ok {
+			transforms = append(transforms, affine)
+		}
+
+		for _, tf := range transforms {
+			grid := sampleGrid(img, tf, size)
+			if text, err := DecodeMatrix(grid); err == nil {
+				return text, nil
+			}
+			// QR ที่กลับด้าน (mirror)
+			if text, err := DecodeMatrix(transpose(grid)); err == nil {
+				return text, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func decodeBinary(img *bitImage) ([]byte, error) {
+	for _, t := range candidateTriples(findFinders(img)) {
+		if text, err := decodeTriple(img, t); err == nil {
+			return text, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,178 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// ✅ PromptPay payload จาก promptpay.Payload("0812345678", 150.50, "EV STATION", "INV0001")
+const promptPayPayload = "00020101021229370016A0000006770101110113006681234567853037645406150.505802TH5910EV STATION62110507INV000163047FBF"
+
+// promptPayMatrix คือ module ของ promptPayPayload ที่ version 7-M mask 2 (# = ดำ)
+// เทียบกับ rsc.io/qr/coding (byte mode, version/mask เดียวกัน) แล้วตรงกันทุก module
+var promptPayMatrix = []string{
+	"#######..####....#...#.#.#....##....#.#######",
+	"#.....#........###..#.#.##...#..##.#..#.....#",
+	"#.###.#.##.##..#...#.#..#...#..###.#..#.###.#",
+	"#.###.#.#..##.#...###.#.#.##..#..#.##.#.###.#",
+	"#.###.#.#.###.####..######....#.#.###.#.###.#",
+	"#.....#.#..#####..#.#...#....#.#......#.....#",
+	"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+	"........#..#..#####.#...##..#....#.##........",
+	"#.#####.....#.###.#######.##..##.#.#..#####..",
+	"##..##..#...##.#####.#..##....#..#..#..#..###",
+	"##.#..####.#...####....#...#.#.#..#.####.##..",
+	"#..#.#..##...###.#.##....#.#..####..#...#.#..",
+	"##.####.###...#...#.#..#..#..#....##.#.#.#...",
+	"...#.#..#.#.#..#.#.###..#..##.#.##..#..#..###",
+	"#.#.###....#.#.#.##....####..#.#..######..#..",
+	".#..##.####....#..##.#..##.#..###...#...#.##.",
+	".##.#####..#..###.###..##.#..#.....#...#.#...",
+	"#...#...#.###...##.###.#...#..#.##..#.....###",
+	"..#...##.#..#.##.#####..#.##.#....###.#..##..",
+	"####....##.##.#......#.###..#.####..##..#.#..",
+	".#..######..##.##.#######.##.##..#.#######.#.",
+	"....#...#..##.##.#.##...#..#..##.#..#...#.###",
+	"#.#.#.#.#...##...#.##.#.#....#....###.#.#.#..",
+	"...##...#.#..###....#...#...####.#..#...#.#..",
+	".########.##.#.#.#.#######.#.###.##.######...",
+	"###.##.#..#######.##..###..#..#.##.#.###..###",
+	".##..##.##.#####.#...#.#.###.#....##.#....#..",
+	"#.####...#..##....#..#..##.#..####.##..#..##.",
+	"##.#..#.#.###...#.#.##..###...#..#......##...",
+	"...##..#.#.##....##...#.#...#.#.##...#.#.####",
+	".#.#.#####..##.#.##.#..####..#.#..#....##....",
+	"...#.#...###..#..#...#..#...############..#.#",
+	"##.#..#..##.#..#.##....##..#.#...#......##...",
+	"#..###..#......####.##..##....#.##..#..#..###",
+	"....#.#.##.#..#.#...#.####.#.#....#.......#..",
+	".####.........#.##...######.#.####.####...##.",
+	"#..##.#.#..##...#..######.##.....#..######.#.",
+	"........###.#..#.####...###.#.#..#..#...#.###",
+	"#######..#..#..##..##.#.#..#.#....#.#.#.#.#..",
+	"#.....#.####...##...#...#...#..###..#...#.###",
+	"#.###.#.##...#.#....#####..#.##.....######.#.",
+	"#.###.#.##..##.######.##...#..#..#..###.#.###",
+	"#.###.#.##...#.#.....#..####.#....###.##.###.",
+	"#.....#..#..#.#..#.###.#..#.######.##.....#..",
+	"#######.#####..#..#..#..#..#..#..#...###.#.#.",
+}
+
+func matrixRows(q *Code) []string {
+	rows := make([]string, q.Size)
+	for y := range rows {
+		var b strings.Builder
+		for x := 0; x < q.Size; x++ {
+			if q.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
+
+func darkMatrix(rows []string) [][]bool {
+	dark := make([][]bool, len(rows))
+	for y, row := range rows {
+		dark[y] = make([]bool, len(row))
+		for x := range row {
+			dark[y][x] = row[x] == '#'
+		}
+	}
+	return dark
+}
+
+func TestEncodePromptPayMatrix(t *testing.T) {
+	q, err := EncodeString(promptPayPayload, Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Version != 7 || q.Size != 45 || q.Mask != 2 {
+		t.Fatalf("version %d size %d mask %d, want 7 45 2", q.Version, q.Size, q.Mask)
+	}
+	got := matrixRows(q)
+	for y := range promptPayMatrix {
+		if got[y] != promptPayMatrix[y] {
+			t.Errorf("row %d\n got  %s\n want %s", y, got[y], promptPayMatrix[y])
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		level   Level
+		version int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{14, Medium, 1},
+		{15, Medium, 2},
+		{7, High, 1},
+		{2953, Low, 40},
+	}
+	for _, tt := range tests {
+		q, err := Encode(bytes.Repeat([]byte("a"), tt.length), tt.level)
+		if err != nil {
+			t.Errorf("%d bytes level %d: %v", tt.length, tt.level, err)
+			continue
+		}
+		if q.Version != tt.version {
+			t.Errorf("%d bytes level %d: version %d, want %d", tt.length, tt.level, q.Version, tt.version)
+		}
+	}
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2954), Low); err != ErrTooLong {
+		t.Errorf("2954 bytes: err %v, want ErrTooLong", err)
+	}
+}
+
+// ✅ ตัวอย่างจาก ISO/IEC 18004 Annex I ("01234567" version 1-M)
+var isoData = []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+var isoECC = []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+
+func TestRSRemainder(t *testing.T) {
+	if got := RSRemainder(isoData, len(isoECC)); !bytes.Equal(got, isoECC) {
+		t.Errorf("ecc % X, want % X", got, isoECC)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	tests := []struct {
+		level Level
+		mask  int
+		want  int
+	}{
+		{Low, 0, 0b111011111000100},
+		{Medium, 0, 0b101010000010010},
+		{Medium, 5, 0b100000011001110}, // ตัวอย่างใน ISO/IEC 18004
+		{Quartile, 0, 0b011010101011111},
+		{High, 0, 0b001011010001001},
+		{High, 7, 0b000100000111011},
+	}
+	for _, tt := range tests {
+		if got := FormatBits(tt.level, tt.mask); got != tt.want {
+			t.Errorf("FormatBits(%d, %d) = %015b, want %015b", tt.level, tt.mask, got, tt.want)
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	tests := []struct {
+		version int
+		want    int
+	}{
+		{7, 0x07C94},
+		{8, 0x085BC},
+		{21, 0x15683},
+		{40, 0x28C69},
+	}
+	for _, tt := range tests {
+		if got := VersionBits(tt.version); got != tt.want {
+			t.Errorf("VersionBits(%d) = %#05x, want %#05x", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package qrcode
+
+import "errors"
+
+// ErrUncorrectable เมื่อ block มีจุดเสียเกินกว่าที่ ECC แก้ได้
+var ErrUncorrectable = errors.New("qrcode: too many errors to correct")
+
+// ✅ ตาราง exp/log ของ GF(256) polynomial 0x11D
+var (
+	gfExp [512]byte
+	gfLog [256]int
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(gfLog[a]+255-gfLog[b])%255]
+}
+
+// gfPow คืน α^e
+func gfPow(e int) byte {
+	e %= 255
+	if e < 0 {
+		e += 255
+	}
+	return gfExp[e]
+}
+
+// ✅ ค่าของ polynomial (เรียงจากกำลังต่ำไปสูง) ที่ x
+func polyEval(p []byte, x byte) byte {
+	var y byte
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return y
+}
+
+// RSCorrect แก้ไขข้อผิดพลาดของ block (data + ecc) ในตำแหน่งเดิม คืนจำนวนจุดที่แก้
+// codeword ไบต์แรกคือสัมประสิทธิ์กำลังสูงสุด และ generator มี root α^0 … α^(ecc-1) ตามมาตรฐาน QR
+func RSCorrect(block []byte, ecc int) (int, error) {
+	n := len(block)
+	if ecc <= 0 || ecc >= n {
+		return 0, ErrUncorrectable
+	}
+
+	// 🔹 syndrome S_i = r(α^i)
+	syndromes := make([]byte, ecc)
+	hasError := false
+	for i := 0; i < ecc; i++ {
+		var s byte
+		x := gfPow(i)
+		for _, c := range block {
+			s = gfMul(s, x) ^ c
+		}
+		syndromes[i] = s
+		if s != 0 {
+			hasError = true
+		}
+	}
+	if !hasError {
+		return 0, nil
+	}
+
+	// 🔹 Berlekamp–Massey หา error locator Λ(x)
+	lambda := []byte{1}
+	prev := []byte{1}
+	l, m := 0, 1
+	b := byte(1)
+	for k := 0; k < ecc; k++ {
+		d := syndromes[k]
+		for i := 1; i <= l && i < len(lambda); i++ {
+			d ^= gfMul(lambda[i], syndromes[k-i])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		coef := gfDiv(d, b)
+		next := make([]byte, max(len(lambda), len(prev)+m))
+		copy(next, lambda)
+		for i, p := range prev {
+			next[i+m] ^= gfMul(coef, p)
+		}
+		if 2*l <= k {
+			prev = lambda
+			l = k + 1 - l
+			b = d
+			m = 1
+		} else {
+			m++
+		}
+		lambda = next
+	}
+	if l*2 > ecc {
+		return 0, ErrUncorrectable
+	}
+
+	// 🔹 Chien search หาตำแหน่งที่ผิด
+	positions := []int{}
+	for p := 0; p < n; p++ {
+		xInv := gfPow(-(n - 1 - p))
+		if polyEval(lambda, xInv) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	if len(positions) != l {
+		return 0, ErrUncorrectable
+	}
+
+	// 🔹 Forney: e = X·Ω(X⁻¹)/Λ'(X⁻¹)
+	omega := make([]byte, ecc)
+	for i := 0; i < ecc; i++ {
+		for j := 0; j <= i && j < len(lambda); j++ {
+			omega[i] ^= gfMul(lambda[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(lambda))
+	for i := 1; i < len(lambda); i += 2 {
+		derivative[i-1] = lambda[i]
+	}
+	for _, p := range positions {
+		x := gfPow(n - 1 - p)
+		xInv := gfPow(-(n - 1 - p))
+		denom := polyEval(derivative, xInv)
+		if denom == 0 {
+			return 0, ErrUncorrectable
+		}
+		block[p] ^= gfMul(x, gfDiv(polyEval(omega, xInv), denom))
+	}
+
+	// ตรวจซ้ำว่าแก้ครบจริง
+	for i := 0; i < ecc; i++ {
+		var s byte
+		x := gfPow(i)
+		for _, c := range block {
+			s = gfMul(s, x) ^ c
+		}
+		if s != 0 {
+			return 0, ErrUncorrectable
+		}
+	}
+	return len(positions), nil
+}
@@ -0,0 +1,249 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Workbook คือไฟล์ Excel อย่างง่าย (ข้อความ ตัวเลข วันที่) สำหรับ export รายงาน
+type Workbook struct {
+	sheets []*Sheet
+}
+
+// Sheet คือ worksheet หนึ่งแผ่น
+type Sheet struct {
+	Name   string
+	rows   [][]cell
+	widths []float64
+}
+
+type cell struct {
+	value interface{}
+	bold  bool
+}
+
+// New สร้าง workbook เปล่า
+func New() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet เพิ่ม worksheet (ชื่อยาวเกิน 31 ตัวอักษรถูกตัด ตามข้อจำกัดของ Excel)
+func (w *Workbook) AddSheet(name string) *Sheet {
+	name = strings.NewReplacer("/", "-", "\\", "-", "?", "", "*", "", "[", "(", "]", ")", ":", "-").Replace(name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	s := &Sheet{Name: name}
+	w.sheets = append(w.sheets, s)
+	return s
+}
+
+// AddRow เพิ่มแถว — รองรับ string, ตัวเลข, bool, time.Time และ nil (ช่องว่าง)
+func (s *Sheet) AddRow(values ...interface{}) {
+	s.addRow(false, values)
+}
+
+// AddHeader เพิ่มแถวหัวตาราง (ตัวหนา)
+func (s *Sheet) AddHeader(values ...string) {
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	s.addRow(true, row)
+}
+
+func (s *Sheet) addRow(bold bool, values []interface{}) {
+	row := make([]cell, len(values))
+	for i, v := range values {
+		row[i] = cell{value: v, bold: bold}
+		// 🔸 กว้างคอลัมน์ตามข้อความที่ยาวที่สุด
+		width := float64(len([]rune(display(v)))) + 2
+		for len(s.widths) <= i {
+			s.widths = append(s.widths, 8)
+		}
+		if width > s.widths[i] {
+			s.widths[i] = width
+		}
+	}
+	s.rows = append(s.rows, row)
+}
+
+func display(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return t.Format("2006-01-02 15:04")
+	case float64:
+		return strconv.FormatFloat(t, 'f', 2, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// column แปลงเลขคอลัมน์ (0 = A) เป็นตัวอักษร
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// excelTime แปลงเวลาเป็นเลขวันแบบ Excel (นับจาก 1899-12-30)
+func excelTime(t time.Time) float64 {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	_, offset := t.Zone()
+	return t.Add(time.Duration(offset)*time.Second).Sub(base).Hours() / 24
+}
+
+// สไตล์ที่กำหนดใน styles.xml
+const (
+	styleDefault = 0
+	styleBold    = 1
+	styleNumber  = 2 // #,##0.00
+	styleDate    = 3 // yyyy-mm-dd hh:mm
+)
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.rows) > 0 && s.rows[0][0].bold {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(s.widths) > 0 {
+		b.WriteString("<cols>")
+		for i, w := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, w)
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cl := range row {
+			ref := column(c) + strconv.Itoa(r+1)
+			style := styleDefault
+			if cl.bold {
+				style = styleBold
+			}
+			switch v := cl.value.(type) {
+			case nil:
+				continue
+			case float64:
+				if style == styleDefault {
+					style = styleNumber
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+			case float32:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(float64(v), 'f', -1, 32))
+			case int, int64, int32, uint, uint64, uint32:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+			case bool:
+				n := 0
+				if v {
+					n = 1
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="b"><v>%d</v></c>`, ref, style, n)
+			case time.Time:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, strconv.FormatFloat(excelTime(v), 'f', -1, 64))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(fmt.Sprint(v)))
+			}
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData></worksheet>")
+	return b.String()
+}
+
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="4">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+</styleSheet>`
+
+// Write เขียนไฟล์ .xlsx
+func (w *Workbook) Write(out io.Writer) error {
+	if len(w.sheets) == 0 {
+		w.AddSheet("Sheet1")
+	}
+	z := zip.NewWriter(out)
+
+	var types, sheets, rels strings.Builder
+	for i, s := range w.sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.Name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	stylesID := len(w.sheets) + 1
+
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+` + types.String() + `
+</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>` + sheets.String() + `</sheets>
+</workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+` + rels.String() + fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesID) + `
+</Relationships>`},
+		{"xl/styles.xml", stylesXML},
+	}
+	for i, s := range w.sheets {
+		files = append(files, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// Bytes คืนไฟล์ .xlsx ทั้งไฟล์
+func (w *Workbook) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,249 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ✅ โครงสร้าง XML ที่ต้องอ่านได้ (เฉพาะส่วนที่ตรวจ)
+type xmlSheet struct {
+	Views []struct {
+		Pane struct {
+			YSplit string `xml:"ySplit,attr"`
+			State  string `xml:"state,attr"`
+		} `xml:"sheetView>pane"`
+	} `xml:"sheetViews"`
+	Cols []struct {
+		Min   int     `xml:"min,attr"`
+		Width float64 `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		R     int       `xml:"r,attr"`
+		Cells []xmlCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xmlCell struct {
+	Ref    string `xml:"r,attr"`
+	Style  string `xml:"s,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+func (c xmlCell) text() string {
+	if c.Type == "inlineStr" {
+		return c.Inline
+	}
+	return c.Value
+}
+
+type xmlWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xmlRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xmlTypes struct {
+	Overrides []struct {
+		PartName string `xml:"PartName,attr"`
+	} `xml:"Override"`
+}
+
+// readParts เปิดไฟล์ zip แล้วตรวจว่าทุก part เป็น XML ที่ถูกต้อง
+func readParts(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = body
+	}
+	return parts
+}
+
+func unmarshal(t *testing.T, parts map[string][]byte, name string, v interface{}) {
+	t.Helper()
+	body, ok := parts[name]
+	if !ok {
+		t.Fatalf("missing part %s", name)
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+func TestWorkbookParses(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*3600)
+
+	wb := New()
+	report := wb.AddSheet("Revenue 2025/10")
+	report.AddHeader("date", "cabinet", "kwh", "payments", "refunded", "note")
+	report.AddRow(time.Date(2025, 10, 1, 12, 0, 0, 0, bangkok), "ตู้ A1", 12.5, 3, false, nil)
+	report.AddRow(time.Date(2025, 10, 2, 0, 0, 0, 0, bangkok), "<B&C>", 0.125, int64(1), true, "  spaced  ")
+	wb.AddSheet("A very long sheet name that Excel rejects").AddRow("x")
+
+	data, err := wb.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := readParts(t, data)
+
+	// 🔹 package: content types → workbook → rels → sheet
+	var types xmlTypes
+	unmarshal(t, parts, "[Content_Types].xml", &types)
+	for _, o := range types.Overrides {
+		if _, ok := parts[strings.TrimPrefix(o.PartName, "/")]; !ok {
+			t.Errorf("content type override for missing part %s", o.PartName)
+		}
+	}
+	var rootRels xmlRelationships
+	unmarshal(t, parts, "_rels/.rels", &rootRels)
+	if len(rootRels.Items) != 1 || rootRels.Items[0].Target != "xl/workbook.xml" {
+		t.Fatalf("_rels/.rels = %+v", rootRels.Items)
+	}
+
+	var book xmlWorkbook
+	unmarshal(t, parts, "xl/workbook.xml", &book)
+	var rels xmlRelationships
+	unmarshal(t, parts, "xl/_rels/workbook.xml.rels", &rels)
+	targets := map[string]string{}
+	for _, r := range rels.Items {
+		if _, ok := parts["xl/"+r.Target]; !ok {
+			t.Errorf("relationship %s points to missing part %s", r.ID, r.Target)
+		}
+		targets[r.ID] = "xl/" + r.Target
+	}
+	wantNames := []string{"Revenue 2025-10", "A very long sheet name that Exc"}
+	if len(book.Sheets) != len(wantNames) {
+		t.Fatalf("sheets %+v, want %d", book.Sheets, len(wantNames))
+	}
+	for i, s := range book.Sheets {
+		if s.Name != wantNames[i] {
+			t.Errorf("sheet %d name %q, want %q", i, s.Name, wantNames[i])
+		}
+	}
+
+	var sheet xmlSheet
+	unmarshal(t, parts, targets[book.Sheets[0].RID], &sheet)
+	if len(sheet.Views) != 1 || sheet.Views[0].Pane.YSplit != "1" || sheet.Views[0].Pane.State != "frozen" {
+		t.Errorf("header row should be frozen: %+v", sheet.Views)
+	}
+	if len(sheet.Cols) != 6 || sheet.Cols[0].Width != 18 {
+		t.Errorf("cols %+v, want 6 columns with date width 18", sheet.Cols)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("rows %d, want 3", len(sheet.Rows))
+	}
+
+	// 🔹 ค่าและสไตล์ของแต่ละช่อง (s: 1 = ตัวหนา, 2 = #,##0.00, 3 = วันที่)
+	want := [][]xmlCell{
+		{
+			{Ref: "A1", Style: "1", Type: "inlineStr", Inline: "date"},
+			{Ref: "B1", Style: "1", Type: "inlineStr", Inline: "cabinet"},
+			{Ref: "C1", Style: "1", Type: "inlineStr", Inline: "kwh"},
+			{Ref: "D1", Style: "1", Type: "inlineStr", Inline: "payments"},
+			{Ref: "E1", Style: "1", Type: "inlineStr", Inline: "refunded"},
+			{Ref: "F1", Style: "1", Type: "inlineStr", Inline: "note"},
+		},
+		{
+			{Ref: "A2", Style: "3", Value: "45931.5"}, // 2025-10-01 12:00 ตามเวลาท้องถิ่น
+			{Ref: "B2", Style: "0", Type: "inlineStr", Inline: "ตู้ A1"},
+			{Ref: "C2", Style: "2", Value: "12.5"},
+			{Ref: "D2", Style: "0", Value: "3"},
+			{Ref: "E2", Style: "0", Type: "b", Value: "0"},
+		},
+		{
+			{Ref: "A3", Style: "3", Value: "45932"},
+			{Ref: "B3", Style: "0", Type: "inlineStr", Inline: "<B&C>"},
+			{Ref: "C3", Style: "2", Value: "0.125"},
+			{Ref: "D3", Style: "0", Value: "1"},
+			{Ref: "E3", Style: "0", Type: "b", Value: "1"},
+			{Ref: "F3", Style: "0", Type: "inlineStr", Inline: "  spaced  "},
+		},
+	}
+	for r, row := range sheet.Rows {
+		if row.R != r+1 {
+			t.Errorf("row %d has r=%d", r, row.R)
+		}
+		if len(row.Cells) != len(want[r]) {
+			t.Errorf("row %d: %d cells, want %d", r+1, len(row.Cells), len(want[r]))
+			continue
+		}
+		for c, got := range row.Cells {
+			w := want[r][c]
+			if got.Ref != w.Ref || got.Style != w.Style || got.Type != w.Type || got.text() != w.text() {
+				t.Errorf("cell %s = %+v, want %+v", w.Ref, got, w)
+			}
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := column(tt.index); got != tt.want {
+			t.Errorf("column(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestExcelTime(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		want float64
+	}{
+		{time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 61},
+		{time.Date(2025, 1, 1, 18, 0, 0, 0, time.UTC), 45658.75},
+		{time.Date(2025, 1, 1, 6, 0, 0, 0, time.FixedZone("ICT", 7*3600)), 45658.25},
+	}
+	for _, tt := range tests {
+		if got := excelTime(tt.at); got != tt.want {
+			t.Errorf("excelTime(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
@@ -67,7 +67,8 @@ const ChargingEV = () => {
       const sessions = await GetChargingSessionByUserID(userID);
 
       const active = sessions?.some(
-        (s: any) => s.Status === true || s.Status === 1
+        (s: any) =>
+          ["created", "authorized", "charging", "suspended"].includes(s.Status)
       );
 
       if (active) {
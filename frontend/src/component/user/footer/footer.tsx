@@ -46,7 +46,9 @@ const Footer: React.FC = () => {
       const res = await GetChargingSessionByStatusAndUserID(userID);
       const list = res?.data || [];
 
-      const active = list.filter((s: any) => s.Status === true || s.Status === 1);
+      const active = list.filter((s: any) =>
+        ["created", "authorized", "charging", "suspended"].includes(s.Status)
+      );
 
       setSessions(active);
       setIsChargingActive(active.length > 0);
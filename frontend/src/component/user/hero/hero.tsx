@@ -55,7 +55,8 @@ const Hero = ({ }: HeaderProps) => {
       const list = res?.data || [];
 
       const active = list.filter(
-        (s: any) => s.Status === true || s.Status === 1
+        (s: any) =>
+          ["created", "authorized", "charging", "suspended"].includes(s.Status)
       );
 
       setSessions(active);
@@ -1,13 +1,12 @@
 import React, { useRef, useEffect, useState } from "react";
 import { FaPaypal, FaUpload, FaPaperPlane, FaTimes } from "react-icons/fa";
 import { message, QRCode, Image, InputNumber } from "antd";
-import generatePayload from "promptpay-qr";
 import {
   uploadSlipOK,
-  UpdateCoin,
   getUserByID,
   CreatePaymentCoin,
   ListBank,
+  GetPromptPayQR,
   GetDataPaymentByRef,
 } from "../../../services";
 import { getCurrentUser, initUserProfile } from "../../../services/httpLogin";
@@ -91,8 +90,7 @@ const AddMoneyCoin: React.FC = () => {
   // ✅ สร้าง QR Code จาก PromptPay
   useEffect(() => {
     if (promptPay && totalAmount > 0) {
-      const payload = generatePayload(promptPay, { amount: totalAmount });
-      setQrCode(payload);
+      GetPromptPayQR(totalAmount).then((qr) => setQrCode(qr?.payload || ""));
     } else {
       setQrCode("");
     }
@@ -195,19 +193,19 @@ const AddMoneyCoin: React.FC = () => {
         return;
       }
 
-      // ✅ อัปเดต Coin ใหม่
-      const newTotalCoin = userCoin + coinAmount;
-      const updateResult = await UpdateCoin({
-        user_id: userID,
-        coin: newTotalCoin,
-      });
-
-      if (!updateResult) {
-        message.error("อัปเดต Coin ล้มเหลว");
-        setLoading(false);
+      // ✅ ระบบตรวจสลิปอัตโนมัติไม่ได้ → รอผู้ดูแลอนุมัติก่อนเติม Coin
+      if (paymentResult.Status && paymentResult.Status !== "approved") {
+        message.info("ได้รับสลิปแล้ว รอผู้ดูแลตรวจสอบก่อนเติม Coin");
+        setTimeout(() => {
+          navigate("/user");
+          setLoading(false);
+        }, 1000);
         return;
       }
 
+      // ✅ Backend เพิ่ม Coin ผ่าน ledger พร้อมบันทึก PaymentCoin แล้ว
+      const newTotalCoin = paymentResult.User?.Coin ?? userCoin + coinAmount;
+
       message.success(`เติม Coin สำเร็จ (รวม ${newTotalCoin.toFixed(2)} Coin)`);
 
       setTimeout(() => {
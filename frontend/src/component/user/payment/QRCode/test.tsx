@@ -1,13 +1,13 @@
 import React, { useRef, useEffect, useState } from "react";
 import { FaPaypal, FaUpload, FaPaperPlane, FaTimes } from "react-icons/fa";
 import { message, QRCode, Image } from "antd";
-import generatePayload from "promptpay-qr";
 import { useLocation, useNavigate } from "react-router-dom";
 import {
   uploadSlipOK,
   CreatePayment,
   CreateEVChargingPayment,
   ListBank,
+  GetPromptPayQR,
   CreateChargingToken,
   connectHardwareSocket,
   sendHardwareCommand,
@@ -79,8 +79,7 @@ const PayPalCard: React.FC = () => {
   // สร้าง QR Payload
   useEffect(() => {
     if (amountNumber > 0 && phoneNumber) {
-      const payload = generatePayload(phoneNumber, { amount: amountNumber });
-      setQrCode(payload);
+      GetPromptPayQR(amountNumber).then((qr) => setQrCode(qr?.payload || ""));
     } else {
       setQrCode("");
     }
@@ -166,6 +165,14 @@ const PayPalCard: React.FC = () => {
           }
         }
 
+        // ⏳ สลิปรอผู้ดูแลตรวจสอบ → ยังออก Token ไม่ได้
+        if (paymentResult.Status && paymentResult.Status !== "approved") {
+          message.info("ได้รับสลิปแล้ว รอผู้ดูแลตรวจสอบก่อนเริ่มชาร์จ");
+          setLoading(false);
+          navigate("/user");
+          return;
+        }
+
         // สร้าง Token
         const token = await CreateChargingToken(userID, paymentResult.ID);
         if (!token) {
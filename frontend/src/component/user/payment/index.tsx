@@ -5,7 +5,6 @@ import qrpayment from "../../../assets/PromptPay-logo.png";
 import { Divider, message } from "antd";
 import {
   getUserByID,
-  UpdateCoin,
   ListMethods,
   CreatePayment,
   CreateEVChargingPayment,
@@ -167,17 +166,6 @@ const Index: React.FC = () => {
     try {
       setIsProcessing(true);
 
-      // หัก coin
-      const updatedCoin = (user.Coin || 0) - totalAmount;
-      const result = await UpdateCoin({ user_id: user.ID!, coin: updatedCoin });
-
-      if (!result) {
-        setIsProcessing(false);
-        return message.error("การหัก Coin ล้มเหลว");
-      }
-
-      message.success("ชำระเงินด้วย Coin สำเร็จแล้ว");
-
       // ⭐ สร้างข้อมูล Payment (เพิ่ม ev_cabinet_id)
       const paymentData = {
         date: new Date().toISOString().split("T")[0],
@@ -191,11 +179,14 @@ const Index: React.FC = () => {
 
       const paymentResult = await CreatePayment(paymentData);
 
+      // Backend หัก Coin ผ่าน ledger พร้อมสร้าง Payment
       if (!paymentResult || !paymentResult.ID) {
         setIsProcessing(false);
-        return message.error("สร้าง Payment ล้มเหลว");
+        return message.error("การหัก Coin ล้มเหลว");
       }
 
+      message.success("ชำระเงินด้วย Coin สำเร็จแล้ว");
+
       // ผูก EVChargingPayment
       if (Array.isArray(chargers)) {
         for (const charger of chargers) {
@@ -6,6 +6,8 @@ export interface BookingInterface {
   EVCabinetID?: number;
   User?: any;
   EVCabinet?: any;
+  Status?: "scheduled" | "fulfilled" | "missed"; // ตรวจโดย job หลังเลยเวลาสิ้นสุด
+  CheckedAt?: string | null;
 }
 
 import type { EmployeeInterface } from "./IEmployee";
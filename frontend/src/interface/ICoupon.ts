@@ -0,0 +1,74 @@
+import { UsersInterface } from "./IUser"
+
+export type CouponAppliesTo = "charging" | "topup";
+export type CouponType = "percent" | "fixed";
+export type CouponRedemptionStatus = "pending" | "applied" | "void";
+
+export interface CouponInterface {
+  ID?: number;
+  Code: string;
+  Description?: string;
+  AppliesTo: CouponAppliesTo;
+  Type: CouponType;
+  Value: number;
+  MaxDiscount?: number;
+  MinSpend?: number;
+  StartsAt?: string | null;
+  EndsAt?: string | null;
+  MaxUses?: number;
+  MaxUsesPerUser?: number;
+  Active?: boolean;
+  EVCabinets?: { ID: number; Name?: string }[];
+  CreatedAt?: string;
+}
+
+// body ของ POST /coupons และ PATCH /coupons/:id
+export interface CouponInput {
+  code: string;
+  description?: string;
+  applies_to: CouponAppliesTo;
+  type: CouponType;
+  value: number;
+  max_discount?: number;
+  min_spend?: number;
+  starts_at?: string | null;
+  ends_at?: string | null;
+  max_uses?: number;
+  max_uses_per_user?: number;
+  active?: boolean;
+  ev_cabinet_ids?: number[];
+}
+
+export interface CouponQuote {
+  coupon: CouponInterface;
+  amount: number;
+  discount: number;
+  net: number;
+  bonus_coin: number;
+}
+
+export interface CouponRedemptionInterface {
+  ID?: number;
+  CouponID: number;
+  Code: string;
+  UserID: number;
+  User?: UsersInterface;
+  PaymentID?: number | null;
+  PaymentCoinID?: number | null;
+  EVCabinetID?: number | null;
+  Amount: number;
+  Discount: number;
+  BonusCoin: number;
+  Status: CouponRedemptionStatus;
+  CreatedAt?: string;
+}
+
+export interface CouponRedemptionSummary {
+  coupon_id: number;
+  code: string;
+  uses: number;
+  users: number;
+  amount: number;
+  discount: number;
+  bonus_coin: number;
+}
@@ -0,0 +1,27 @@
+export type InvoiceKind = "receipt" | "tax-invoice";
+
+export interface InvoiceInterface {
+  ID?: number;
+  Kind: InvoiceKind;
+  Number: string;
+  IssuedAt: string;
+  PaymentID: number;
+  Subtotal: number;
+  VATRate: number;
+  VAT: number;
+  Total: number;
+  SellerName?: string;
+  SellerTaxID?: string;
+  BuyerName?: string;
+  BuyerTaxID?: string;
+  BuyerBranch?: string;
+  BuyerAddress?: string;
+  File?: string;
+}
+
+export interface TaxInvoiceBuyer {
+  buyer_name: string;
+  buyer_tax_id: string;
+  buyer_branch?: string; // ไม่ระบุ = สำนักงานใหญ่
+  buyer_address: string;
+}
@@ -0,0 +1,24 @@
+// สถานะการทำงานของ job แต่ละรอบ
+export type JobRunStatus = "running" | "succeeded" | "failed";
+
+export interface JobRunInterface {
+  ID: number;
+  Job: string;
+  Trigger: "schedule" | "manual";
+  TriggeredBy?: number | null;
+  Status: JobRunStatus;
+  StartedAt: string;
+  EndedAt?: string | null;
+  DurationMs: number;
+  Items: number; // จำนวนรายการที่ job จัดการ
+  Error: string;
+}
+
+export interface JobInterface {
+  name: string;
+  description: string;
+  schedule: string; // cron เช่น "0 7 * * *" หรือ "@every 5m"
+  running: boolean;
+  next_run: string | null;
+  last_run: JobRunInterface | null;
+}
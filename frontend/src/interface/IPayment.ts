@@ -42,6 +42,7 @@ export interface PaymentCreateInterface {
   reference_number: string;
   picture?: File | null;
   ev_cabinet_id?: number;
+  coupon_code?: string;
 }
 
 export interface PaymentInterface {
@@ -50,6 +51,19 @@ export interface PaymentInterface {
   amount: number;
   user_id: number;
   method_id: number;
+  CouponCode?: string;
+  Discount?: number;
+  Status?: PaymentStatus;
+  StatusReason?: string;
   CreatedAt?: string;
   UpdatedAt?: string;
-}
\ No newline at end of file
+}
+
+// สถานะการตรวจสลิปของ Payment / PaymentCoin
+export type PaymentStatus =
+  | "pending"
+  | "auto-verified"
+  | "manual-review"
+  | "approved"
+  | "rejected"
+  | "refunded";
\ No newline at end of file
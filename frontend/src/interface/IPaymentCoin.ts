@@ -1,4 +1,5 @@
 import {UsersInterface} from "./IUser"
+import { PaymentStatus } from "./IPayment"
 
 export interface PaymentCoinInterface {
   ID?: number;
@@ -8,5 +9,9 @@ export interface PaymentCoinInterface {
   Picture: string;
   User?: UsersInterface;
   UserID?: number;
+  CouponCode?: string;
+  BonusCoin?: number;
+  Status?: PaymentStatus;
+  StatusReason?: string;
   CreatedAt?:string
 }
@@ -0,0 +1,61 @@
+import { EVCabinetInterface } from "./IBooking";
+import { PaymentInterface } from "./IPayment";
+
+// QR ของหัวชาร์จ (format=json)
+export interface ConnectorQR {
+  url: string;
+  charger_id: string;
+  connector_no: number;
+  image: string; // data:image/png;base64,...
+}
+
+// ผลการสแกน QR — ตู้ / หัวชาร์จ / สถานะสด / ราคา
+export interface QrStartInfo {
+  connector_id: number;
+  charger_id: string;
+  connector_no: number;
+  status: string; // Available, Preparing, Charging, ...
+  error_code: string;
+  status_updated_at: string | null;
+  online: boolean;
+  available: boolean; // เริ่มชาร์จได้ทันที
+  cabinet: EVCabinetInterface | null;
+  pay_and_start: string;
+  evcharging?: { ID: number; Name: string; Price: number };
+  tariff?: {
+    id: number;
+    name: string;
+    band: string;
+    per_kwh: number;
+    per_minute: number;
+    session_fee: number;
+  };
+}
+
+// ค่าจากลิงก์ที่สแกน (?cp=&c=&sig=)
+export interface QrStartLink {
+  cp: string;
+  c: string;
+  sig: string;
+}
+
+export interface PayAndStartInput extends QrStartLink {
+  user_id: number;
+  method_id: number;
+  amount: number;
+  coupon_code?: string;
+  picture?: File | null;
+}
+
+export interface PayAndStartResult {
+  message?: string;
+  error?: string;
+  remote_start_error?: string; // จ่ายแล้วแต่สั่งเริ่มไม่สำเร็จ (202)
+  payment?: PaymentInterface;
+  session_id?: number;
+  charging_token?: string;
+  expires_at?: string;
+  charger_id?: string;
+  connector_id?: number;
+  status?: string;
+}
@@ -0,0 +1,30 @@
+import { UsersInterface } from "./IUser"
+
+export type RefundMethod = "wallet" | "bank_transfer";
+export type RefundStatus = "requested" | "completed" | "rejected";
+
+export interface RefundInterface {
+  ID?: number;
+  Amount: number;
+  Reason: string;
+  Method: RefundMethod;
+  Status: RefundStatus;
+  PaymentID?: number | null;
+  PaymentCoinID?: number | null;
+  UserID?: number | null;
+  User?: UsersInterface;
+  ApprovedByID?: number | null;
+  ApprovedBy?: UsersInterface;
+  DecidedAt?: string | null;
+  DecisionNote?: string;
+  BankReference?: string;
+  WalletTransactionID?: number | null;
+  CreatedAt?: string;
+}
+
+export interface RefundSummary {
+  paid: number;
+  refunded: number;
+  pending: number;
+  refundable: number;
+}
@@ -0,0 +1,57 @@
+export type RevenueGroupBy = "day" | "week" | "month" | "cabinet" | "method" | "tariff";
+
+export interface RevenueRow {
+  key: string;
+  label: string;
+  payments: number;
+  gross: number;    // ยอดก่อนส่วนลด
+  discount: number;
+  refunds: number;
+  net: number;      // gross - discount - refunds
+  kwh: number;
+  // มีเฉพาะ day / week / month
+  topups?: number;
+  topup_refunds?: number;
+  bonus_coin?: number;
+}
+
+export interface RevenueSummary {
+  payments: number;
+  gross: number;
+  discount: number;
+  refunds: number;
+  net: number;
+  net_qr: number;
+  net_coin: number;
+  kwh: number;
+  topups: number;
+  topup_refunds: number;
+  bonus_coin: number;
+  wallet_liability: number; // Coin คงค้าง ณ สิ้นช่วง
+  liability_as_of: string;
+  unverified_payments: number;
+  unverified_payment_amount: number;
+  unverified_topups: number;
+  unverified_topup_amount: number;
+}
+
+export interface UnverifiedSlip {
+  type: "payment" | "payment_coin";
+  id: number;
+  user_id?: number | null;
+  amount: number;
+  reference_number: string;
+  status: string;
+  status_reason: string;
+  created_at: string;
+}
+
+export interface RevenueReport {
+  group_by: RevenueGroupBy;
+  from: string;
+  to: string;
+  generated_at: string;
+  rows: RevenueRow[];
+  summary: RevenueSummary;
+  unverified: UnverifiedSlip[];
+}
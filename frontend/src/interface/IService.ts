@@ -4,6 +4,9 @@ export interface ServiceInterface {
   Phone: string;
   Location: string;
   MapURL: string;
+  CompanyName?: string;
+  TaxID?: string;
+  Branch?: string;
   EmployeeID?: number;
   CreatedAt?: string;
   UpdatedAt?: string;
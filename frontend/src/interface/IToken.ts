@@ -1,12 +1,105 @@
 import { PaymentInterface } from "./IPayment";
 
+// สถานะของ session การชาร์จ
+export type ChargingSessionStatus =
+  | "created"
+  | "authorized"
+  | "charging"
+  | "suspended"
+  | "completed"
+  | "expired"
+  | "cancelled";
+
 export interface ChargingSessionInterface {
   ID: number;
   UserID: number;
   Token: string;
   ExpiresAt: string;
   CreatedAt: string;
-  Status: boolean;
+  Status: ChargingSessionStatus;
+  StatusReason?: string;
+  StatusChangedAt?: string | null;
+  AuthorizedAt?: string | null;
+  StartedAt?: string | null;
+  EndedAt?: string | null;
   PaymentID: number;
   Payment?: PaymentInterface; // ถ้า preload Payment
-}
\ No newline at end of file
+}
+
+// ประวัติการเปลี่ยนสถานะของ session
+export interface ChargingSessionEventInterface {
+  ID: number;
+  ChargingSessionID: number;
+  FromStatus: ChargingSessionStatus | "";
+  ToStatus: ChargingSessionStatus;
+  Reason: string;
+  Source: "ocpp" | "api" | "system";
+  CreatedAt: string;
+}
+
+// รูปแบบ token การชาร์จ — signed ตรวจ offline ได้ด้วย public key
+export type ChargingTokenFormat = "uuid" | "signed";
+
+// ข้อมูลใน token แบบลงชื่อ
+export interface ChargingTokenClaims {
+  jti: string;
+  sid: number; // ChargingSession.ID
+  sub: number; // UserID
+  pid: number; // PaymentID
+  cab?: number; // EVCabinetID
+  max_kwh?: number;
+  max_amount?: number;
+  iat: number; // unix seconds
+  exp: number;
+}
+
+// public key (JWK, Ed25519)
+export interface ChargingTokenKey {
+  kty: "OKP";
+  crv: "Ed25519";
+  x: string;
+  kid: string;
+  use: "sig";
+  alg: "EdDSA";
+}
+
+export interface RevokedChargingToken {
+  jti: string;
+  exp: number;
+  revoked_at: string;
+}
+
+// ความคืบหน้าของ session (ค่าที่ server แปลงจาก OCPP แล้ว)
+export interface ChargingProgress {
+  session_id: number;
+  status: ChargingSessionStatus;
+  status_reason: string;
+  started_at: string | null;
+  ended_at: string | null;
+  charger_id?: string;
+  connector_id?: number;
+  transaction_id?: number;
+  online: boolean;
+  elapsed_seconds: number;
+  energy_kwh: number;
+  power_kw: number | null;
+  soc: number | null; // %
+  meter_at: string | null;
+  cost_so_far: number;
+  final: boolean; // true = คิดเงินแล้ว เป็นยอดจริง
+  limit_kwh: number;
+  limit_amount: number;
+  remaining_kwh: number;
+  estimated_finish: string | null;
+  finish_by?: "energy_limit" | "full_soc";
+}
+
+// ended = ค่าสุดท้าย server จะปิดการเชื่อมต่อหลังส่ง
+export type ChargingProgressEventType = "snapshot" | "progress" | "status" | "ended";
+
+export interface ChargingProgressEvent {
+  type: ChargingProgressEventType;
+  seq: number;
+  at: string;
+  data: ChargingProgress;
+}
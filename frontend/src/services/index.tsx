@@ -16,13 +16,39 @@ import { MethodInterface } from "../interface/IMethod";
 import { InverterStatus } from "../interface/IInverterStatus"
 import { BankInterface } from "../interface/IBank"
 import { PaymentCoinInterface } from "../interface/IPaymentCoin";
+import { RefundInterface, RefundMethod, RefundSummary } from "../interface/IRefund";
+import { InvoiceInterface, InvoiceKind, TaxInvoiceBuyer } from "../interface/IInvoice";
+import {
+  CouponInput,
+  CouponInterface,
+  CouponQuote,
+  CouponRedemptionInterface,
+  CouponRedemptionSummary,
+} from "../interface/ICoupon";
+import { RevenueGroupBy, RevenueReport } from "../interface/IRevenue";
+import { JobInterface, JobRunInterface } from "../interface/IJob";
+import {
+  ConnectorQR,
+  QrStartInfo,
+  QrStartLink,
+  PayAndStartInput,
+  PayAndStartResult,
+} from "../interface/IQrStart";
 import { CarsInterface } from "../interface/ICar";
 import { ServiceInterface } from "../interface/IService";
 import { SendEmailInterface } from "../interface/ISendEmail";
 import { BookingInterface,EVCabinetInterface } from "../interface/IBooking";
 import { ModalInterface } from "../interface/ICarCatalog";
 import { BrandInterface } from "../interface/IBrand";
-import { ChargingSessionInterface } from "../interface/IToken";
+import {
+  ChargingSessionInterface,
+  ChargingSessionEventInterface,
+  ChargingProgress,
+  ChargingProgressEvent,
+  ChargingTokenFormat,
+  ChargingTokenKey,
+  RevokedChargingToken,
+} from "../interface/IToken";
 
 //const apiUrl = "http://10.0.14.228:8000";
 //export const apiUrlPicture = "http://10.0.14.228:8000/";
@@ -344,32 +370,6 @@ export const UpdateEmployeeProfile = async (
   }
 };
 
-export interface UpdateCoinInput {
-  user_id: number;
-  coin: number;
-}
-
-export const UpdateCoin = async (data: UpdateCoinInput): Promise<boolean> => {
-  try {
-    const response = await axios.put(`${apiUrl}/users/update-coin`, data, {
-      headers: {
-        "Content-Type": "application/json",
-        ...getAuthHeader(),
-      },
-    });
-
-    if (response.status === 200) {
-      return true;
-    } else {
-      console.error("Unexpected status:", response.status);
-      return false;
-    }
-  } catch (error) {
-    console.error("Error updating coin:", error);
-    return false;
-  }
-};
-
 export const ListReviews = async (): Promise<ReviewInterface[] | null> => {
   try {
     const response = await axios.get(`${apiUrl}/reviews`, {
@@ -1508,6 +1508,11 @@ export const CreatePayment = async (
       formData.append("ev_cabinet_id", paymentData.ev_cabinet_id.toString());
     }
 
+    // คูปองส่วนลด (amount = ยอดก่อนหักส่วนลด)
+    if (paymentData.coupon_code) {
+      formData.append("coupon_code", paymentData.coupon_code);
+    }
+
     // แนบรูปถ้ามี
     if (paymentData.picture instanceof File) {
       formData.append("picture", paymentData.picture);
@@ -1519,7 +1524,8 @@ export const CreatePayment = async (
       },
     });
 
-    if (response.status === 200 || response.status === 201) {
+    // 202 = ได้รับสลิปแล้วแต่รอผู้ดูแลตรวจสอบ (ดู Status)
+    if (response.status === 200 || response.status === 201 || response.status === 202) {
       return response.data.data as PaymentInterface;
     } else {
       console.error("Unexpected status:", response.status);
@@ -1532,6 +1538,35 @@ export const CreatePayment = async (
 };
 
 
+// ฟังก์ชันขอ PromptPay QR ตามยอดเงินจาก Backend
+export interface PromptPayQR {
+  payload: string;
+  amount: number;
+  image: string; // data:image/png;base64,...
+}
+
+export const GetPromptPayQR = async (amount: number, ref?: string): Promise<PromptPayQR | null> => {
+  try {
+    const response = await axios.get(`${apiUrl}/promptpay/qr`, {
+      params: { amount, ref, format: "json" },
+      headers: {
+        "Content-Type": "application/json",
+        ...getAuthHeader(),
+      },
+    });
+
+    if (response.status === 200) {
+      return response.data;
+    } else {
+      console.error("Unexpected status:", response.status);
+      return null;
+    }
+  } catch (error) {
+    console.error("Error fetching PromptPay QR:", error);
+    return null;
+  }
+};
+
 // ฟังก์ชันดึงรายการธนาคาร
 export const ListBank = async (): Promise<BankInterface[] | null> => {
   try {
@@ -1608,6 +1643,9 @@ export const CreatePaymentCoin = async (data: any) => {
     formData.append("ReferenceNumber", data.ReferenceNumber);
     formData.append("UserID", data.UserID);
     formData.append("Picture", data.Picture); // <<< File
+    if (data.CouponCode) {
+      formData.append("CouponCode", data.CouponCode); // คูปองโบนัส Coin
+    }
 
     const response = await axios.post(
       `${apiUrl}/create-payment-coins`,
@@ -1619,7 +1657,8 @@ export const CreatePaymentCoin = async (data: any) => {
         },
       }
     );
-    if (response.status === 201) {
+    // 202 = รอผู้ดูแลตรวจสอบ ยังไม่เติม Coin
+    if (response.status === 201 || response.status === 202) {
       return response.data;
     }
     return null;
@@ -2232,14 +2271,18 @@ export const DeleteModalByID = async (id: number): Promise<boolean> => {
 // services/token.ts
 export const CreateChargingToken = async (
   userID: number,
-  paymentID: number
+  paymentID: number,
+  tokenFormat?: ChargingTokenFormat // ไม่ระบุ = ตามค่าระบบ
 ): Promise<string | null> => {
   try {
     const res = await axios.post(
       `${apiUrl}/token/payment-success`,
-      { user_id: userID, payment_id: paymentID },
+      { user_id: userID, payment_id: paymentID, token_format: tokenFormat },
       {
-        headers: { "Content-Type": "application/json" },
+        headers: {
+          "Content-Type": "application/json",
+          ...getAuthHeader(),
+        },
       }
     );
 
@@ -2480,6 +2523,82 @@ export const UpdateSessionStatusByPaymentID = async (
   }
 };
 
+// ประวัติการเปลี่ยนสถานะของ session
+export const ListChargingSessionEvents = async (
+  sessionID: number
+): Promise<ChargingSessionEventInterface[] | null> => {
+  try {
+    const res = await axios.get(
+      `${apiUrl}/charging-sessions/${sessionID}/events`,
+      {
+        headers: {
+          ...getAuthHeader(),
+        },
+      }
+    );
+    return res.status === 200 ? res.data.data : null;
+  } catch (error: any) {
+    console.error(
+      "❌ Error fetching session events:",
+      error.response?.data || error.message
+    );
+    return null;
+  }
+};
+
+// ความคืบหน้าครั้งเดียว (ใช้แทน stream เมื่อเชื่อม WebSocket ไม่ได้)
+export const GetChargingProgress = async (
+  sessionID: number,
+  token: string
+): Promise<ChargingProgress | null> => {
+  try {
+    const res = await axios.get(
+      `${apiUrl}/charging-sessions/${sessionID}/progress`,
+      { params: { token } }
+    );
+    return res.status === 200 ? res.data : null;
+  } catch (error: any) {
+    console.error(
+      "❌ Error fetching charging progress:",
+      error.response?.data || error.message
+    );
+    return null;
+  }
+};
+
+// stream ความคืบหน้าของ session — ใช้ token การชาร์จของผู้ใช้เอง (server ปิดการเชื่อมต่อหลังส่ง ended)
+export const connectChargingProgressSocket = (
+  sessionID: number,
+  token: string,
+  onEvent: (event: ChargingProgressEvent) => void
+) => {
+  const ws = new WebSocket(
+    `${apiUrl}/charging-sessions/${sessionID}/stream?token=${encodeURIComponent(token)}`
+  );
+
+  ws.onopen = () => {
+    console.log("✅ Connected to charging progress stream");
+  };
+
+  ws.onmessage = (event) => {
+    try {
+      onEvent(JSON.parse(event.data));
+    } catch {
+      console.log("📩 Raw Message:", event.data);
+    }
+  };
+
+  ws.onclose = () => {
+    console.log("⚠️ Charging progress stream closed");
+  };
+
+  ws.onerror = (err) => {
+    console.error("❌ Charging progress stream error:", err);
+  };
+
+  return ws;
+};
+
 export const GetChargingSessionByStatusTrue = async (): Promise<any | null> => {
   try {
     const res = await axios.get(`${apiUrl}/charging-session/status/true`, {
@@ -2527,4 +2646,527 @@ export const GetChargingSessionByStatusAndUserID = async (
     );
     return null;
   }
-};
\ No newline at end of file
+};
+
+// ===========================
+// 🟩 คิวตรวจสลิป (Admin)
+// ===========================
+export interface PaymentReviewQueue {
+  payments: PaymentInterface[];
+  payment_coins: PaymentCoinInterface[];
+}
+
+export const ListPaymentReviews = async (
+  status?: string
+): Promise<PaymentReviewQueue | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/payment-reviews`, {
+      params: status ? { status } : undefined,
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error) {
+    console.error("Error fetching payment reviews:", error);
+    return null;
+  }
+};
+
+// ผู้ตรวจมาจาก JWT ของ admin ที่ login อยู่ (getAuthHeader)
+export interface PaymentReviewInput {
+  reason?: string;
+  reference_number?: string;
+  amount?: number; // เฉพาะ PaymentCoin
+}
+
+// kind: "payments" | "payment-coins", action: "approve" | "reject"
+export const ReviewPayment = async (
+  kind: "payments" | "payment-coins",
+  id: number,
+  action: "approve" | "reject",
+  data: PaymentReviewInput
+): Promise<any | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/${kind}/${id}/${action}`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error: any) {
+    console.error("Error reviewing payment:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+// ============================================================================
+// 🔹 คืนเงิน (Refund)
+// ============================================================================
+
+export const ListRefunds = async (params?: {
+  status?: string;
+  payment_id?: number;
+  payment_coin_id?: number;
+  user_id?: number;
+}): Promise<RefundInterface[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/refunds`, {
+      params,
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error) {
+    console.error("Error fetching refunds:", error);
+    return null;
+  }
+};
+
+export interface RefundRequestInput {
+  amount?: number; // ไม่ระบุ = คืนยอดที่เหลือทั้งหมด
+  reason: string;
+  method?: RefundMethod;
+}
+
+// kind: "payments" | "payment-coins"
+export const RequestRefund = async (
+  kind: "payments" | "payment-coins",
+  id: number,
+  data: RefundRequestInput
+): Promise<{ data: RefundInterface; summary: RefundSummary } | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/${kind}/${id}/refunds`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 201 ? res.data : null;
+  } catch (error: any) {
+    console.error("Error requesting refund:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+export interface RefundDecisionInput {
+  reason?: string;
+  bank_reference?: string; // จำเป็นเมื่อโอนคืนผ่านธนาคาร
+}
+
+// action: "approve" | "reject"
+export const DecideRefund = async (
+  id: number,
+  action: "approve" | "reject",
+  data: RefundDecisionInput
+): Promise<any | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/refunds/${id}/${action}`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error: any) {
+    console.error("Error deciding refund:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+// ============================================================================
+// 🔹 ใบเสร็จ / ใบกำกับภาษี
+// ============================================================================
+
+export const ListInvoicesByPayment = async (
+  paymentId: number
+): Promise<InvoiceInterface[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/payments/${paymentId}/invoices`, {
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error) {
+    console.error("Error fetching invoices:", error);
+    return null;
+  }
+};
+
+export const IssueReceipt = async (
+  paymentId: number
+): Promise<InvoiceInterface | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/payments/${paymentId}/receipt`, null, {
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error: any) {
+    console.error("Error issuing receipt:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+export const IssueTaxInvoice = async (
+  paymentId: number,
+  buyer: TaxInvoiceBuyer
+): Promise<InvoiceInterface | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/payments/${paymentId}/tax-invoice`, buyer, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error: any) {
+    console.error("Error issuing tax invoice:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+// ดาวน์โหลด PDF (ใบเสร็จจะถูกออกให้อัตโนมัติถ้ายังไม่มี)
+export const DownloadInvoice = async (
+  paymentId: number,
+  kind: InvoiceKind
+): Promise<boolean> => {
+  try {
+    const res = await axios.get(`${apiUrl}/payments/${paymentId}/${kind}`, {
+      headers: { ...getAuthHeader() },
+      responseType: "blob",
+    });
+    const disposition: string = res.headers["content-disposition"] || "";
+    const match = disposition.match(/filename="?([^"]+)"?/);
+    const url = window.URL.createObjectURL(res.data);
+    const link = document.createElement("a");
+    link.href = url;
+    link.download = match ? match[1] : `${kind}-${paymentId}.pdf`;
+    document.body.appendChild(link);
+    link.click();
+    link.remove();
+    window.URL.revokeObjectURL(url);
+    return true;
+  } catch (error) {
+    console.error("Error downloading invoice:", error);
+    return false;
+  }
+};
+
+// ============================================================================
+// 🔹 คูปอง / โปรโมชัน
+// ============================================================================
+
+export const ListCoupons = async (): Promise<CouponInterface[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/coupons`, {
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error) {
+    console.error("Error fetching coupons:", error);
+    return null;
+  }
+};
+
+export const CreateCoupon = async (data: CouponInput): Promise<CouponInterface | null> => {
+  try {
+    const res = await axios.post(`${apiUrl}/coupons`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 201 ? res.data.data : null;
+  } catch (error: any) {
+    console.error("Error creating coupon:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+export const UpdateCoupon = async (
+  id: number,
+  data: Partial<CouponInput>
+): Promise<CouponInterface | null> => {
+  try {
+    const res = await axios.patch(`${apiUrl}/coupons/${id}`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error: any) {
+    console.error("Error updating coupon:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+// ปิดใช้งานคูปอง (ประวัติการใช้ยังอยู่ในรายงาน)
+export const DeleteCoupon = async (id: number): Promise<boolean> => {
+  try {
+    const res = await axios.delete(`${apiUrl}/coupons/${id}`, {
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200;
+  } catch (error) {
+    console.error("Error deleting coupon:", error);
+    return false;
+  }
+};
+
+// ตรวจคูปองก่อนชำระ — คืน { error, reason } ถ้าใช้ไม่ได้
+export const ValidateCoupon = async (data: {
+  code: string;
+  user_id: number;
+  applies_to?: "charging" | "topup";
+  amount: number;
+  ev_cabinet_id?: number;
+}): Promise<{ data?: CouponQuote; error?: string; reason?: string }> => {
+  try {
+    const res = await axios.post(`${apiUrl}/coupons/validate`, data, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return { data: res.data.data };
+  } catch (error: any) {
+    return {
+      error: error.response?.data?.error || error.message,
+      reason: error.response?.data?.reason,
+    };
+  }
+};
+
+export const ListCouponRedemptions = async (params?: {
+  coupon_id?: number;
+  user_id?: number;
+  status?: string;
+  from?: string; // YYYY-MM-DD
+  to?: string;
+}): Promise<{ data: CouponRedemptionInterface[]; summary: CouponRedemptionSummary[] } | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/coupon-redemptions`, {
+      params,
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error) {
+    console.error("Error fetching coupon redemptions:", error);
+    return null;
+  }
+};
+
+// ============================================================================
+// 🔹 รายงานรายได้ / กระทบยอด
+// ============================================================================
+
+export interface RevenueReportParams {
+  group_by?: RevenueGroupBy;
+  from?: string; // YYYY-MM-DD (ค่าเริ่มต้น: ต้นเดือน)
+  to?: string;   // YYYY-MM-DD นับรวมวันนี้ (ค่าเริ่มต้น: วันนี้)
+}
+
+export const GetRevenueReport = async (
+  params?: RevenueReportParams
+): Promise<RevenueReport | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/reports/revenue`, {
+      params,
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error) {
+    console.error("Error fetching revenue report:", error);
+    return null;
+  }
+};
+
+// ดาวน์โหลดรายงานเป็น CSV / XLSX
+export const DownloadRevenueReport = async (
+  format: "csv" | "xlsx",
+  params?: RevenueReportParams
+): Promise<boolean> => {
+  try {
+    const res = await axios.get(`${apiUrl}/reports/revenue`, {
+      params: { ...params, format },
+      headers: { ...getAuthHeader() },
+      responseType: "blob",
+    });
+    const disposition: string = res.headers["content-disposition"] || "";
+    const match = disposition.match(/filename="?([^"]+)"?/);
+    const url = window.URL.createObjectURL(res.data);
+    const link = document.createElement("a");
+    link.href = url;
+    link.download = match ? match[1] : `revenue.${format}`;
+    document.body.appendChild(link);
+    link.click();
+    link.remove();
+    window.URL.revokeObjectURL(url);
+    return true;
+  } catch (error) {
+    console.error("Error downloading revenue report:", error);
+    return false;
+  }
+};
+
+// ============================================================================
+// 🔹 Background jobs (admin)
+// ============================================================================
+
+export const ListJobs = async (): Promise<JobInterface[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/jobs`, {
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error) {
+    console.error("Error fetching jobs:", error);
+    return null;
+  }
+};
+
+export const ListJobRuns = async (
+  name: string,
+  limit = 50
+): Promise<JobRunInterface[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/jobs/${name}/runs`, {
+      params: { limit },
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error) {
+    console.error("Error fetching job runs:", error);
+    return null;
+  }
+};
+
+// สั่งรัน job ทันที — คืนผลของรอบนั้น (รวมกรณี failed)
+export const TriggerJob = async (
+  name: string
+): Promise<{ data?: JobRunInterface; error?: string }> => {
+  try {
+    const res = await axios.post(
+      `${apiUrl}/jobs/${name}/run`,
+      {},
+      { headers: { "Content-Type": "application/json", ...getAuthHeader() } }
+    );
+    return res.data;
+  } catch (error: any) {
+    console.error("Error triggering job:", error.response?.data || error.message);
+    return error.response?.data || { error: error.message };
+  }
+};
+
+// ============================================================================
+// 🔹 Token การชาร์จแบบลงชื่อ (key set / denylist)
+// ============================================================================
+
+export const GetChargingTokenKeys = async (): Promise<ChargingTokenKey[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/charging-tokens/keys`);
+    return res.status === 200 ? res.data.keys : null;
+  } catch (error) {
+    console.error("Error fetching charging token keys:", error);
+    return null;
+  }
+};
+
+export const RotateChargingTokenKeys = async (): Promise<string | null> => {
+  try {
+    const res = await axios.post(
+      `${apiUrl}/charging-tokens/keys/rotate`,
+      {},
+      { headers: { ...getAuthHeader() } }
+    );
+    return res.status === 200 ? res.data.kid : null;
+  } catch (error) {
+    console.error("Error rotating charging token keys:", error);
+    return null;
+  }
+};
+
+export const ListRevokedChargingTokens = async (
+  since?: string // RFC3339
+): Promise<RevokedChargingToken[] | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/charging-tokens/revoked`, {
+      params: since ? { since } : undefined,
+    });
+    return res.status === 200 ? res.data.data : null;
+  } catch (error) {
+    console.error("Error fetching revoked charging tokens:", error);
+    return null;
+  }
+};
+
+// ยกเลิก token (ระบุ token หรือ sessionID) — session ที่ยังไม่จบจะถูก cancel ด้วย
+export const RevokeChargingToken = async (params: {
+  token?: string;
+  session_id?: number;
+  reason?: string;
+}): Promise<boolean> => {
+  try {
+    const res = await axios.post(`${apiUrl}/charging-tokens/revoke`, params, {
+      headers: { "Content-Type": "application/json", ...getAuthHeader() },
+    });
+    return res.status === 200;
+  } catch (error: any) {
+    console.error("Error revoking charging token:", error.response?.data || error.message);
+    return false;
+  }
+};
+
+// ============================================================================
+// 🔹 QR-to-start ของหัวชาร์จ
+// ============================================================================
+
+// URL รูป QR (PNG) สำหรับพิมพ์ติดหัวชาร์จ
+export const ConnectorQRImageUrl = (connectorID: number, scale = 8): string =>
+  `${apiUrl}/connectors/${connectorID}/qr?scale=${scale}`;
+
+export const GetConnectorQR = async (connectorID: number): Promise<ConnectorQR | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/connectors/${connectorID}/qr`, {
+      params: { format: "json" },
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error) {
+    console.error("Error fetching connector QR:", error);
+    return null;
+  }
+};
+
+// อ่านค่าจาก query ของ deep link (/user/qr-start?cp=&c=&sig=)
+export const ParseQrStartLink = (search: string): QrStartLink | null => {
+  const q = new URLSearchParams(search);
+  const cp = q.get("cp");
+  const c = q.get("c");
+  const sig = q.get("sig");
+  return cp && c && sig ? { cp, c, sig } : null;
+};
+
+export const ResolveQrStart = async (link: QrStartLink): Promise<QrStartInfo | null> => {
+  try {
+    const res = await axios.get(`${apiUrl}/qr-start/resolve`, {
+      params: link,
+      headers: { ...getAuthHeader() },
+    });
+    return res.status === 200 ? res.data : null;
+  } catch (error: any) {
+    console.error("Error resolving QR:", error.response?.data || error.message);
+    return null;
+  }
+};
+
+// ชำระเงิน + ออก token + สั่งเริ่มชาร์จในครั้งเดียว
+// 201 = เริ่มชาร์จแล้ว, 202 = สลิปรอตรวจ หรือจ่ายแล้วแต่สั่งเริ่มไม่สำเร็จ (มี remote_start_error — ลองใหม่ด้วย remote-start)
+export const PayAndStart = async (
+  input: PayAndStartInput,
+  idempotencyKey?: string
+): Promise<PayAndStartResult> => {
+  const formData = new FormData();
+  formData.append("cp", input.cp);
+  formData.append("c", input.c);
+  formData.append("sig", input.sig);
+  formData.append("user_id", input.user_id.toString());
+  formData.append("method_id", input.method_id.toString());
+  formData.append("amount", input.amount.toString());
+  if (input.coupon_code) {
+    formData.append("coupon_code", input.coupon_code);
+  }
+  if (input.picture instanceof File) {
+    formData.append("picture", input.picture);
+  }
+
+  try {
+    const res = await axios.post(`${apiUrl}/qr-start/pay-and-start`, formData, {
+      headers: {
+        ...getAuthHeader(),
+        ...(idempotencyKey ? { "Idempotency-Key": idempotencyKey } : {}),
+      },
+    });
+    return res.data;
+  } catch (error: any) {
+    console.error("Error in pay-and-start:", error.response?.data || error.message);
+    return error.response?.data || { error: error.message };
+  }
+};